@@ -0,0 +1,366 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"github.com/kercylan98/minotaur/configuration"
+	"github.com/kercylan98/minotaur/utils/log"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	adminConsoleCommandTimeout = time.Second * 10
+)
+
+// AdminCommandHandle 管理控制台指令处理函数
+//   - args 为指令参数，不包含指令名称
+//   - 写入 output 的内容将作为指令的执行结果返回给调用方
+type AdminCommandHandle func(srv *Server, args []string, output io.Writer) error
+
+// adminCommand 管理控制台指令
+type adminCommand struct {
+	usage  string
+	handle AdminCommandHandle
+}
+
+// adminConsole 管理控制台
+type adminConsole struct {
+	network    string
+	addr       string
+	token      string
+	listener   net.Listener
+	httpServer *http.Server
+	commands   map[string]*adminCommand
+	rw         sync.RWMutex
+}
+
+// newAdminConsole 创建管理控制台并注册内置指令
+func newAdminConsole(network, addr, token string) *adminConsole {
+	console := &adminConsole{
+		network:  network,
+		addr:     addr,
+		token:    token,
+		commands: map[string]*adminCommand{},
+	}
+	console.commands["help"] = &adminCommand{usage: "help", handle: adminCommandHelp}
+	console.commands["conns"] = &adminCommand{usage: "conns", handle: adminCommandConns}
+	console.commands["kick"] = &adminCommand{usage: "kick <connId> [connId...]", handle: adminCommandKick}
+	console.commands["shunts"] = &adminCommand{usage: "shunts", handle: adminCommandShunts}
+	console.commands["tickers"] = &adminCommand{usage: "tickers", handle: adminCommandTickers}
+	console.commands["loglevel"] = &adminCommand{usage: "loglevel [debug|info|warn|error]", handle: adminCommandLogLevel}
+	console.commands["config"] = &adminCommand{usage: "config <load|refresh|reload>", handle: adminCommandConfig}
+	console.commands["shutdown"] = &adminCommand{usage: "shutdown", handle: adminCommandShutdown}
+	return console
+}
+
+// RegAdminCommand 注册管理控制台指令，注册同名指令将会覆盖已有的指令（包括内置指令）
+//   - 需要通过 WithAdminConsole 开启管理控制台，否则将会 panic
+//   - usage 为指令的用法说明，将在 help 指令中展示
+//   - 指令将在服务器的系统消息中执行，因此可以安全的访问服务器状态
+func (slf *Server) RegAdminCommand(command, usage string, handle AdminCommandHandle) {
+	if slf.adminConsole == nil {
+		panic(ErrAdminConsoleNotEnabled)
+	}
+	slf.adminConsole.rw.Lock()
+	slf.adminConsole.commands[command] = &adminCommand{usage: usage, handle: handle}
+	slf.adminConsole.rw.Unlock()
+	log.Info("Server", log.String("RegAdminCommand", command), log.String("usage", usage))
+}
+
+// ExecAdminCommand 执行管理控制台指令，返回指令的输出
+//   - 当指令未通过 RegAdminCommand 注册时，将尝试执行通过 RegConsoleCommandEvent 注册的指令
+func (slf *Server) ExecAdminCommand(ctx context.Context, command string, args ...string) (string, error) {
+	if slf.adminConsole == nil {
+		return "", ErrAdminConsoleNotEnabled
+	}
+	slf.adminConsole.rw.RLock()
+	cmd, exist := slf.adminConsole.commands[command]
+	slf.adminConsole.rw.RUnlock()
+
+	var handle AdminCommandHandle
+	if exist {
+		handle = cmd.handle
+	} else {
+		handle = func(srv *Server, args []string, output io.Writer) error {
+			handles, exist := srv.consoleCommandEventHandles[command]
+			if !exist {
+				return ErrAdminCommandNotFound
+			}
+			for _, handle := range handles {
+				handle(srv)
+			}
+			return nil
+		}
+	}
+
+	// 指令的输出仅在执行结束后交付，超时返回后指令仍在执行时不会与调用方共享输出
+	var done = make(chan adminCommandResult, 1)
+	var exec = func() {
+		var output bytes.Buffer
+		defer func() {
+			if err := recover(); err != nil {
+				done <- adminCommandResult{output: output.String(), err: fmt.Errorf("%v", err)}
+			}
+		}()
+		err := handle(slf, args, &output)
+		done <- adminCommandResult{output: output.String(), err: err}
+	}
+	if !slf.isRunning.Load() {
+		exec()
+	} else {
+		PushSystemMessage(slf, exec, "AdminCommand", command)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, adminConsoleCommandTimeout)
+	defer cancel()
+	select {
+	case result := <-done:
+		return result.output, result.err
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
+
+// adminCommandResult 管理控制台指令的执行结果
+type adminCommandResult struct {
+	output string
+	err    error
+}
+
+// serveAdminConsole 启动管理控制台
+//   - 将在 Server.Run 中启动，侦听失败时 Server.Run 将返回该错误
+//   - 未设置鉴权令牌时仅允许侦听 unix 套接字或回环地址
+func (slf *Server) serveAdminConsole() error {
+	console := slf.adminConsole
+	if console == nil {
+		return nil
+	}
+	if len(console.token) == 0 && console.network != "unix" && !isLoopbackAddr(console.addr) {
+		return ErrAdminConsoleToken
+	}
+	if console.network == "unix" {
+		_ = os.Remove(console.addr)
+	}
+	listener, err := net.Listen(console.network, console.addr)
+	if err != nil {
+		return err
+	}
+	console.listener = listener
+
+	var handler = http.NewServeMux()
+	handler.HandleFunc("/commands", slf.adminConsoleAuth(func(writer http.ResponseWriter, request *http.Request) {
+		console.rw.RLock()
+		_ = adminCommandHelp(slf, nil, writer)
+		console.rw.RUnlock()
+	}))
+	handler.HandleFunc("/exec", slf.adminConsoleAuth(func(writer http.ResponseWriter, request *http.Request) {
+		var line = request.URL.Query().Get("cmd")
+		if len(line) == 0 && request.Body != nil {
+			body, _ := io.ReadAll(io.LimitReader(request.Body, 1<<20))
+			line = string(body)
+		}
+		var fields = strings.Fields(line)
+		if len(fields) == 0 {
+			http.Error(writer, "empty command", http.StatusBadRequest)
+			return
+		}
+		output, err := slf.ExecAdminCommand(request.Context(), fields[0], fields[1:]...)
+		log.Info("AdminConsole", log.String("remote", request.RemoteAddr), log.String("command", line), log.Err(err))
+		if err != nil {
+			var status = http.StatusInternalServerError
+			if err == ErrAdminCommandNotFound {
+				status = http.StatusNotFound
+			}
+			http.Error(writer, strings.TrimSpace(output+"\n"+err.Error()), status)
+			return
+		}
+		_, _ = io.WriteString(writer, output)
+	}))
+
+	console.httpServer = &http.Server{Handler: handler}
+	go func() {
+		if err := console.httpServer.Serve(listener); err != nil && err != http.ErrServerClosed {
+			log.Error("AdminConsole", log.String("network", console.network), log.String("listen", console.addr), log.Err(err))
+		}
+	}()
+	log.Info("AdminConsole", log.String("network", console.network), log.String("listen", console.addr))
+	return nil
+}
+
+// isLoopbackAddr 地址是否为回环地址
+func isLoopbackAddr(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// adminConsoleAuth 管理控制台鉴权
+//   - 支持 Authorization: Bearer <token> 请求头或 token 查询参数
+func (slf *Server) adminConsoleAuth(handler http.HandlerFunc) http.HandlerFunc {
+	return func(writer http.ResponseWriter, request *http.Request) {
+		if token := slf.adminConsole.token; len(token) > 0 {
+			var provided = strings.TrimPrefix(request.Header.Get("Authorization"), "Bearer ")
+			if len(provided) == 0 {
+				provided = request.URL.Query().Get("token")
+			}
+			if subtle.ConstantTimeCompare([]byte(provided), []byte(token)) != 1 {
+				http.Error(writer, "unauthorized", http.StatusUnauthorized)
+				return
+			}
+		}
+		handler(writer, request)
+	}
+}
+
+// releaseAdminConsole 关闭管理控制台
+func (slf *Server) releaseAdminConsole() {
+	if slf.adminConsole == nil || slf.adminConsole.httpServer == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	_ = slf.adminConsole.httpServer.Shutdown(ctx)
+	if slf.adminConsole.network == "unix" {
+		_ = os.Remove(slf.adminConsole.addr)
+	}
+}
+
+func adminCommandHelp(srv *Server, args []string, output io.Writer) error {
+	var commands = make([]string, 0, len(srv.adminConsole.commands))
+	for command := range srv.adminConsole.commands {
+		commands = append(commands, command)
+	}
+	sort.Strings(commands)
+	for _, command := range commands {
+		_, _ = fmt.Fprintln(output, srv.adminConsole.commands[command].usage)
+	}
+	commands = commands[:0]
+	for command := range srv.consoleCommandEventHandles {
+		if _, exist := srv.adminConsole.commands[command]; !exist {
+			commands = append(commands, command)
+		}
+	}
+	sort.Strings(commands)
+	for _, command := range commands {
+		_, _ = fmt.Fprintln(output, command)
+	}
+	return nil
+}
+
+func adminCommandConns(srv *Server, args []string, output io.Writer) error {
+	var conns = srv.GetOnlineAll()
+	var ids = make([]string, 0, len(conns))
+	for id := range conns {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	_, _ = fmt.Fprintf(output, "online: %d\n", len(ids))
+	for _, id := range ids {
+		conn := conns[id]
+		metrics := conn.GetWriteQueueMetrics()
+		_, _ = fmt.Fprintf(output, "%s\tip=%s\twebsocket=%v\tactive=%s\tqueued=%d\tsent=%d\n",
+			id, conn.GetIP(), conn.IsWebsocket(), conn.GetLastActiveTime().Format(time.DateTime), metrics.QueuedBytes, metrics.SentBytes,
+		)
+	}
+	return nil
+}
+
+func adminCommandKick(srv *Server, args []string, output io.Writer) error {
+	if len(args) == 0 {
+		return ErrAdminCommandArgs
+	}
+	for _, id := range args {
+		if !srv.IsOnline(id) {
+			_, _ = fmt.Fprintf(output, "%s\tnot online\n", id)
+			continue
+		}
+		srv.CloseConn(id)
+		_, _ = fmt.Fprintf(output, "%s\tkicked\n", id)
+	}
+	return nil
+}
+
+func adminCommandShunts(srv *Server, args []string, output io.Writer) error {
+	if srv.shunts == nil {
+		_, _ = fmt.Fprintln(output, "shunt disabled")
+		return nil
+	}
+	var stats = srv.GetShuntStats()
+	var guids = make([]int64, 0, len(stats))
+	for guid := range stats {
+		guids = append(guids, guid)
+	}
+	sort.Slice(guids, func(i, j int) bool { return guids[i] < guids[j] })
+	_, _ = fmt.Fprintf(output, "shunts: %d\n", len(guids))
+	for _, guid := range guids {
+		var stat = stats[guid]
+		_, _ = fmt.Fprintf(output, "%d\tdepth=%d\tprocessed=%d\tavg=%s\tmax=%s\n", guid, stat.Depth, stat.Processed, stat.AvgLatency, stat.MaxLatency)
+	}
+	return nil
+}
+
+func adminCommandTickers(srv *Server, args []string, output io.Writer) error {
+	if srv.ticker == nil {
+		_, _ = fmt.Fprintln(output, "ticker disabled")
+		return nil
+	}
+	var schedulers = srv.ticker.GetSchedulers()
+	sort.Strings(schedulers)
+	_, _ = fmt.Fprintf(output, "schedulers: %d\n", len(schedulers))
+	for _, name := range schedulers {
+		_, _ = fmt.Fprintln(output, name)
+	}
+	return nil
+}
+
+func adminCommandLogLevel(srv *Server, args []string, output io.Writer) error {
+	if len(args) > 0 {
+		var level log.Level
+		if err := level.UnmarshalText([]byte(args[0])); err != nil {
+			return err
+		}
+		log.SetLevel(level)
+	}
+	_, _ = fmt.Fprintln(output, log.GetLevel().String())
+	return nil
+}
+
+func adminCommandConfig(srv *Server, args []string, output io.Writer) error {
+	if len(args) == 0 {
+		return ErrAdminCommandArgs
+	}
+	switch args[0] {
+	case "load":
+		configuration.Load()
+	case "refresh":
+		configuration.Refresh()
+	case "reload":
+		configuration.Load()
+		configuration.Refresh()
+	default:
+		return ErrAdminCommandArgs
+	}
+	_, _ = fmt.Fprintf(output, "config %s finished\n", args[0])
+	return nil
+}
+
+func adminCommandShutdown(srv *Server, args []string, output io.Writer) error {
+	_, _ = fmt.Fprintln(output, "shutting down")
+	srv.shutdownFromMessage(nil)
+	return nil
+}
@@ -0,0 +1,69 @@
+package server_test
+
+import (
+	"context"
+	"github.com/kercylan98/minotaur/server"
+	"github.com/kercylan98/minotaur/utils/log"
+	. "github.com/smartystreets/goconvey/convey"
+	"io"
+	"net"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestServer_ExecAdminCommand(t *testing.T) {
+	Convey("TestServer_ExecAdminCommand", t, func() {
+		var sock = filepath.Join(t.TempDir(), "admin.sock")
+		srv := server.New(server.NetworkNone, server.WithAdminConsole("unix", sock, "secret"))
+		srv.RegAdminCommand("echo", "echo <text...>", func(srv *server.Server, args []string, output io.Writer) error {
+			_, err := io.WriteString(output, strings.Join(args, " "))
+			return err
+		})
+		stop := runServer(srv)
+		defer stop()
+
+		output, err := srv.ExecAdminCommand(context.Background(), "loglevel", "warn")
+		So(err, ShouldBeNil)
+		So(output, ShouldEqual, "warn\n")
+		So(log.GetLevel(), ShouldEqual, log.WarnLevel)
+		log.SetLevel(log.DebugLevel)
+
+		_, err = srv.ExecAdminCommand(context.Background(), "unknown")
+		So(err, ShouldEqual, server.ErrAdminCommandNotFound)
+
+		client := &http.Client{Transport: &http.Transport{DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return net.Dial("unix", sock)
+		}}}
+		resp, err := client.Post("http://admin/exec", "text/plain", strings.NewReader("echo hello minotaur"))
+		So(err, ShouldBeNil)
+		So(resp.StatusCode, ShouldEqual, http.StatusUnauthorized)
+		_ = resp.Body.Close()
+
+		request, _ := http.NewRequest(http.MethodPost, "http://admin/exec", strings.NewReader("echo hello minotaur"))
+		request.Header.Set("Authorization", "Bearer secret")
+		resp, err = client.Do(request)
+		So(err, ShouldBeNil)
+		body, _ := io.ReadAll(resp.Body)
+		_ = resp.Body.Close()
+		So(resp.StatusCode, ShouldEqual, http.StatusOK)
+		So(string(body), ShouldEqual, "hello minotaur")
+	})
+}
+
+func TestWithAdminConsole_Token(t *testing.T) {
+	Convey("TestWithAdminConsole_Token", t, func() {
+		srv := server.New(server.NetworkNone, server.WithAdminConsole("tcp", ":0", ""))
+		So(srv.RunNone(), ShouldEqual, server.ErrAdminConsoleToken)
+
+		var addr = freeAddr()
+		srv = server.New(server.NetworkNone, server.WithAdminConsole("tcp", addr, ""))
+		stop := runServer(srv)
+		defer stop()
+		resp, err := http.Get("http://" + addr + "/commands")
+		So(err, ShouldBeNil)
+		_ = resp.Body.Close()
+		So(resp.StatusCode, ShouldEqual, http.StatusOK)
+	})
+}
@@ -1,33 +1,43 @@
 package client
 
 import (
+	"context"
 	"github.com/gorilla/websocket"
 	"github.com/kercylan98/minotaur/server"
 	"github.com/kercylan98/minotaur/utils/concurrent"
 	"sync"
-	"time"
 )
 
 // NewWebsocket 创建 websocket 客户端
-func NewWebsocket(addr string) *Websocket {
+func NewWebsocket(addr string, options ...WebsocketOption) *Websocket {
 	client := &Websocket{
 		websocketEvents: new(websocketEvents),
 		addr:            addr,
 		data:            map[string]any{},
 	}
+	for _, option := range options {
+		option(client)
+	}
 	return client
 }
 
 // Websocket websocket 客户端
+//   - 连接事件将在独立的事件协程中按顺序执行，请求/响应模式下的响应帧将在读取协程中直接交付，因此允许在事件处理函数中调用 Call
 type Websocket struct {
 	*websocketEvents
 	conn *websocket.Conn
 	addr string
 	data map[string]any
 
-	mutex      sync.Mutex
-	packetPool *concurrent.Pool[*websocketPacket]
-	packets    []*websocketPacket
+	mutex       sync.Mutex
+	packetPool  *concurrent.Pool[*websocketPacket]
+	packets     []*websocketPacket
+	writeSignal chan struct{} // 写入信号
+	events      []func()      // 等待执行的连接事件，nil 表示事件协程需要退出
+	eventSignal chan struct{} // 连接事件信号
+
+	rpcMaxInflight int               // 请求/响应模式下同时等待响应的请求数量上限，为 0 时表示未开启
+	rpc            *server.RPCCaller // 请求/响应调用器
 }
 
 // Run 启动
@@ -37,40 +47,126 @@ func (slf *Websocket) Run() error {
 		return err
 	}
 	slf.conn = ws
-	var wait = new(sync.WaitGroup)
-	wait.Add(1)
-	go slf.writeLoop(wait)
-	wait.Wait()
+	if slf.rpcMaxInflight > 0 {
+		slf.rpc = server.NewRPCCaller(slf.rpcMaxInflight)
+	}
+	slf.mutex.Lock()
+	slf.packetPool = concurrent.NewPool[*websocketPacket](10*1024,
+		func() *websocketPacket {
+			return &websocketPacket{}
+		}, func(data *websocketPacket) {
+			data.packet = nil
+			data.websocketMessageType = 0
+			data.callback = nil
+		},
+	)
+	slf.writeSignal = make(chan struct{}, 1)
+	slf.eventSignal = make(chan struct{}, 1)
+	slf.mutex.Unlock()
+	go slf.writeLoop()
+	go slf.eventLoop()
+	slf.event(func() {
+		slf.OnConnectionOpenedEvent(slf)
+	})
 	go func() {
 		defer func() {
-			if err := recover(); err != nil {
-				slf.Close()
+			// 读取出错或在两次读取之间通过 Close 关闭时，均需要触发连接关闭事件并结束事件协程
+			err := recover()
+			slf.Close()
+			slf.event(func() {
 				slf.OnConnectionClosedEvent(slf, err)
-			}
+			})
+			slf.event(nil)
 		}()
-		slf.OnConnectionOpenedEvent(slf)
-		for slf.packetPool != nil {
+		for slf.IsConnected() {
 			messageType, packet, readErr := ws.ReadMessage()
 			if readErr != nil {
 				panic(readErr)
 			}
-			slf.OnConnectionReceivePacketEvent(slf, server.NewWSPacket(messageType, packet))
+			slf.receive(messageType, packet)
 		}
 	}()
 	return nil
 }
 
+// receive 处理接收到的数据包
+func (slf *Websocket) receive(messageType int, packet []byte) {
+	if slf.rpc == nil {
+		slf.event(func() {
+			slf.OnConnectionReceivePacketEvent(slf, server.NewWSPacket(messageType, packet))
+		})
+		return
+	}
+	kind, seq, data, err := server.UnpackRPCFrame(packet)
+	if err != nil {
+		panic(err)
+	}
+	switch kind {
+	case server.RPCFrameResponse:
+		slf.rpc.Resolve(seq, server.NewWSPacket(messageType, data))
+	case server.RPCFrameRequest:
+		slf.event(func() {
+			slf.OnConnectionReceiveRequestEvent(slf, server.NewWSPacket(messageType, data), func(packet server.Packet) {
+				slf.write(packet.WebsocketType, server.PackRPCFrame(server.RPCFrameResponse, seq, packet.Data))
+			})
+		})
+	default:
+		slf.event(func() {
+			slf.OnConnectionReceivePacketEvent(slf, server.NewWSPacket(messageType, data))
+		})
+	}
+}
+
+// event 将连接事件加入事件队列，event 为 nil 时事件协程将在执行完此前的事件后退出
+func (slf *Websocket) event(event func()) {
+	slf.mutex.Lock()
+	slf.events = append(slf.events, event)
+	slf.mutex.Unlock()
+	select {
+	case slf.eventSignal <- struct{}{}:
+	default:
+	}
+}
+
+// eventLoop 按顺序执行连接事件
+func (slf *Websocket) eventLoop() {
+	for range slf.eventSignal {
+		slf.mutex.Lock()
+		events := slf.events
+		slf.events = nil
+		slf.mutex.Unlock()
+		for _, event := range events {
+			if event == nil {
+				return
+			}
+			event()
+		}
+	}
+}
+
 // Close 关闭
 func (slf *Websocket) Close() {
-	if slf.packetPool != nil {
-		slf.packetPool.Close()
-		slf.packetPool = nil
+	if slf.rpc != nil {
+		slf.rpc.Close(server.ErrConnClosed)
 	}
+	slf.mutex.Lock()
+	var pool = slf.packetPool
+	slf.packetPool = nil
 	slf.packets = nil
+	slf.mutex.Unlock()
+	if pool != nil {
+		pool.Close()
+	}
+	if slf.conn != nil {
+		_ = slf.conn.Close()
+	}
+	slf.notifyWrite()
 }
 
 // IsConnected 是否已连接
 func (slf *Websocket) IsConnected() bool {
+	slf.mutex.Lock()
+	defer slf.mutex.Unlock()
 	return slf.packetPool != nil
 }
 
@@ -87,52 +183,78 @@ func (slf *Websocket) SetData(key string, value any) {
 // Write 向连接中写入数据
 //   - messageType: websocket模式中指定消息类型
 func (slf *Websocket) Write(packet server.Packet) {
+	if slf.rpc != nil {
+		packet.Data = server.PackRPCFrame(server.RPCFramePush, 0, packet.Data)
+	}
+	slf.write(packet.WebsocketType, packet.Data)
+}
+
+// Call 向服务器发起请求并等待响应
+//   - 需要通过 WithWebsocketRPC 开启请求/响应模式，否则将返回 server.ErrRPCNotEnabled
+//   - 可通过 ctx 控制请求的超时时间及取消，当连接关闭时将返回 server.ErrConnClosed
+func (slf *Websocket) Call(ctx context.Context, packet server.Packet) (server.Packet, error) {
+	if slf.rpc == nil {
+		return server.Packet{}, server.ErrRPCNotEnabled
+	}
+	return slf.rpc.Call(ctx, func(seq uint32) error {
+		if !slf.write(packet.WebsocketType, server.PackRPCFrame(server.RPCFrameRequest, seq, packet.Data)) {
+			return server.ErrConnClosed
+		}
+		return nil
+	})
+}
+
+// write 将数据加入写入队列，连接已关闭时返回 false
+func (slf *Websocket) write(websocketType int, data []byte) bool {
+	slf.mutex.Lock()
 	if slf.packetPool == nil {
-		return
+		slf.mutex.Unlock()
+		return false
 	}
 	cp := slf.packetPool.Get()
-	cp.websocketMessageType = packet.WebsocketType
-	cp.packet = packet.Data
-	slf.mutex.Lock()
+	cp.websocketMessageType = websocketType
+	cp.packet = data
 	slf.packets = append(slf.packets, cp)
 	slf.mutex.Unlock()
+	slf.notifyWrite()
+	return true
+}
+
+// notifyWrite 通知写循环有新的数据包需要写入
+func (slf *Websocket) notifyWrite() {
+	select {
+	case slf.writeSignal <- struct{}{}:
+	default:
+	}
 }
 
 // writeLoop 写循环
-func (slf *Websocket) writeLoop(wait *sync.WaitGroup) {
-	slf.packetPool = concurrent.NewPool[*websocketPacket](10*1024,
-		func() *websocketPacket {
-			return &websocketPacket{}
-		}, func(data *websocketPacket) {
-			data.packet = nil
-			data.websocketMessageType = 0
-			data.callback = nil
-		},
-	)
+//   - 在接收到写入信号后立即将队列中的数据包写入
+func (slf *Websocket) writeLoop() {
 	defer func() {
 		if err := recover(); err != nil {
 			slf.Close()
 		}
 	}()
-	wait.Done()
-	for {
+	for range slf.writeSignal {
 		slf.mutex.Lock()
-		if slf.packetPool == nil {
-			return
-		}
-		if len(slf.packets) == 0 {
+		var pool = slf.packetPool
+		if pool == nil {
 			slf.mutex.Unlock()
-			time.Sleep(50 * time.Millisecond)
-			continue
+			return
 		}
-		packets := slf.packets[0:]
-		slf.packets = slf.packets[0:0]
+		packets := slf.packets
+		slf.packets = nil
 		slf.mutex.Unlock()
 		for i := 0; i < len(packets); i++ {
 			data := packets[i]
 			var err = slf.conn.WriteMessage(data.websocketMessageType, data.packet)
 			callback := data.callback
-			slf.packetPool.Release(data)
+			slf.mutex.Lock()
+			if slf.packetPool == pool {
+				pool.Release(data)
+			}
+			slf.mutex.Unlock()
 			if callback != nil {
 				callback(err)
 			}
@@ -3,15 +3,17 @@ package client
 import "github.com/kercylan98/minotaur/server"
 
 type (
-	ConnectionClosedEventHandle        func(conn *Websocket, err any)
-	ConnectionOpenedEventHandle        func(conn *Websocket)
-	ConnectionReceivePacketEventHandle func(conn *Websocket, packet server.Packet)
+	ConnectionClosedEventHandle         func(conn *Websocket, err any)
+	ConnectionOpenedEventHandle         func(conn *Websocket)
+	ConnectionReceivePacketEventHandle  func(conn *Websocket, packet server.Packet)
+	ConnectionReceiveRequestEventHandle func(conn *Websocket, packet server.Packet, reply func(packet server.Packet))
 )
 
 type websocketEvents struct {
-	connectionClosedEventHandles        []ConnectionClosedEventHandle
-	connectionOpenedEventHandles        []ConnectionOpenedEventHandle
-	connectionReceivePacketEventHandles []ConnectionReceivePacketEventHandle
+	connectionClosedEventHandles         []ConnectionClosedEventHandle
+	connectionOpenedEventHandles         []ConnectionOpenedEventHandle
+	connectionReceivePacketEventHandles  []ConnectionReceivePacketEventHandle
+	connectionReceiveRequestEventHandles []ConnectionReceiveRequestEventHandle
 }
 
 // RegConnectionClosedEvent 注册连接关闭事件
@@ -46,3 +48,15 @@ func (slf *websocketEvents) OnConnectionReceivePacketEvent(conn *Websocket, pack
 		handle(conn, packet)
 	}
 }
+
+// RegConnectionReceiveRequestEvent 注册连接接收请求帧事件
+//   - 仅在通过 WithWebsocketRPC 开启请求/响应模式时生效
+func (slf *websocketEvents) RegConnectionReceiveRequestEvent(handle ConnectionReceiveRequestEventHandle) {
+	slf.connectionReceiveRequestEventHandles = append(slf.connectionReceiveRequestEventHandles, handle)
+}
+
+func (slf *websocketEvents) OnConnectionReceiveRequestEvent(conn *Websocket, packet server.Packet, reply func(packet server.Packet)) {
+	for _, handle := range slf.connectionReceiveRequestEventHandles {
+		handle(conn, packet, reply)
+	}
+}
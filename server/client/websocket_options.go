@@ -0,0 +1,18 @@
+package client
+
+import "github.com/kercylan98/minotaur/server"
+
+// WebsocketOption websocket 客户端选项
+type WebsocketOption func(websocket *Websocket)
+
+// WithWebsocketRPC 通过请求/响应模式创建 websocket 客户端，需要与开启了 server.WithRPC 的服务器配合使用
+//   - 开启后可通过 Websocket.Call 向服务器发起请求并等待响应
+//   - maxInflight：同时等待响应的请求数量上限，<= 0 时默认为 server.DefaultRPCMaxInflight
+func WithWebsocketRPC(maxInflight int) WebsocketOption {
+	return func(websocket *Websocket) {
+		if maxInflight <= 0 {
+			maxInflight = server.DefaultRPCMaxInflight
+		}
+		websocket.rpcMaxInflight = maxInflight
+	}
+}
@@ -1,13 +1,18 @@
 package server
 
 import (
+	"bytes"
+	"context"
 	"github.com/gorilla/websocket"
 	"github.com/kercylan98/minotaur/utils/concurrent"
+	"github.com/kercylan98/minotaur/utils/log"
 	"github.com/panjf2000/gnet"
 	"github.com/xtaci/kcp-go/v5"
 	"net"
+	"net/http"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -18,11 +23,15 @@ func newKcpConn(server *Server, session *kcp.UDPSession) *Conn {
 		remoteAddr: session.RemoteAddr(),
 		ip:         session.RemoteAddr().String(),
 		kcp:        session,
+		limiter:    newConnRateLimiter(server),
+		token:      newSessionToken(server),
+		rpc:        newConnRPC(server),
 		data:       map[any]any{},
 	}
 	if index := strings.LastIndex(c.ip, ":"); index != -1 {
 		c.ip = c.ip[0:index]
 	}
+	c.touch()
 	var wait = new(sync.WaitGroup)
 	wait.Add(1)
 	go c.writeLoop(wait)
@@ -37,11 +46,15 @@ func newGNetConn(server *Server, conn gnet.Conn) *Conn {
 		remoteAddr: conn.RemoteAddr(),
 		ip:         conn.RemoteAddr().String(),
 		gn:         conn,
+		limiter:    newConnRateLimiter(server),
+		token:      newSessionToken(server),
+		rpc:        newConnRPC(server),
 		data:       map[any]any{},
 	}
 	if index := strings.LastIndex(c.ip, ":"); index != -1 {
 		c.ip = c.ip[0:index]
 	}
+	c.touch()
 	var wait = new(sync.WaitGroup)
 	wait.Add(1)
 	go c.writeLoop(wait)
@@ -56,8 +69,12 @@ func newWebsocketConn(server *Server, ws *websocket.Conn, ip string) *Conn {
 		remoteAddr: ws.RemoteAddr(),
 		ip:         ip,
 		ws:         ws,
+		limiter:    newConnRateLimiter(server),
+		token:      newSessionToken(server),
+		rpc:        newConnRPC(server),
 		data:       map[any]any{},
 	}
+	c.touch()
 	var wait = new(sync.WaitGroup)
 	wait.Add(1)
 	go c.writeLoop(wait)
@@ -72,7 +89,9 @@ func NewEmptyConn(server *Server) *Conn {
 		remoteAddr: &net.TCPAddr{},
 		ip:         "0.0.0.0:0",
 		data:       map[any]any{},
+		rpc:        newConnRPC(server),
 	}
+	c.touch()
 	var wait = new(sync.WaitGroup)
 	wait.Add(1)
 	go c.writeLoop(wait)
@@ -83,6 +102,7 @@ func NewEmptyConn(server *Server) *Conn {
 // Conn 服务器连接
 type Conn struct {
 	server     *Server
+	addrMutex  sync.RWMutex // 会话恢复时远程地址及 IP 将被替换
 	remoteAddr net.Addr
 	ip         string
 	ws         *websocket.Conn
@@ -92,64 +112,172 @@ type Conn struct {
 	mutex      sync.Mutex
 	packetPool *concurrent.Pool[*connPacket]
 	packets    []*connPacket
+
+	writeSignal     chan struct{} // 写入信号
+	writable        chan struct{} // 写入队列可写信号，队列中的数据被发送或连接关闭后将被关闭并重新创建
+	queuedPackets   int           // 写入队列中的数据包数量
+	queuedBytes     int           // 写入队列中的字节数
+	peakQueuedBytes int           // 写入队列字节数峰值
+	sentPackets     int64         // 已发送的数据包数量
+	sentBytes       int64         // 已发送的字节数
+	droppedPackets  int64         // 因写入队列已满被丢弃的数据包数量
+	flushes         int64         // 批量写入次数
+
+	unpacked  []byte               // 尚未组成完整数据包的数据
+	rpc       *RPCCaller           // 请求/响应调用器
+	token     string               // 会话令牌
+	closed    atomic.Bool          // 是否已被主动关闭，主动关闭的连接将不会保留会话
+	detached  bool                 // 传输层是否已断开且处于会话保持期间
+	expire    *time.Timer          // 会话过期定时器
+	carrier   atomic.Pointer[Conn] // 会话恢复后，当前传输层的原始连接
+	resumedTo atomic.Pointer[Conn] // 会话恢复后，接管该连接传输层的连接
+
+	limiter     *connRateLimiter // 速率限制器
+	acquiredIP  string           // 占用连接数量的 IP
+	closeReason error            // 连接关闭原因
+	active      atomic.Int64     // 最后一次接收到数据的时间
+	pinged      atomic.Int64     // 最后一次发送心跳包的时间
+	header      http.Header      // WebSocket 握手时的请求头
+
+	virtual       func(packet Packet) error // 虚拟连接的写入函数
+	virtualCloser func()                    // 虚拟连接的关闭函数
+}
+
+// newConnRPC 当服务器开启请求/响应模式时为连接创建调用器
+func newConnRPC(server *Server) *RPCCaller {
+	if server.rpcMaxInflight <= 0 {
+		return nil
+	}
+	return NewRPCCaller(server.rpcMaxInflight)
 }
 
 // IsEmpty 是否是空连接
 func (slf *Conn) IsEmpty() bool {
-	return slf.ws == nil && slf.gn == nil && slf.kcp == nil
+	return slf.ws == nil && slf.gn == nil && slf.kcp == nil && slf.virtual == nil
 }
 
 // Reuse 重用连接
 //   - 重用连接时，会将当前连接的数据复制到新连接中
 //   - 通常在于连接断开后，重新连接时使用
 func (slf *Conn) Reuse(conn *Conn) {
+	slf.Close()
 	slf.mutex.Lock()
 	conn.mutex.Lock()
 	defer func() {
 		slf.mutex.Unlock()
 		conn.mutex.Unlock()
 	}()
-	slf.Close()
-	slf.remoteAddr = conn.remoteAddr
-	slf.ip = conn.ip
+	slf.setAddr(conn.RemoteAddr(), conn.GetIP())
 	slf.ws = conn.ws
 	slf.gn = conn.gn
 	slf.kcp = conn.kcp
 	slf.data = conn.data
 	slf.packetPool = conn.packetPool
 	slf.packets = conn.packets
+	slf.rpc = conn.rpc
+	slf.header = conn.header
+	slf.virtual = conn.virtual
+	slf.virtualCloser = conn.virtualCloser
 }
 
 // RemoteAddr 获取远程地址
 func (slf *Conn) RemoteAddr() net.Addr {
+	slf.addrMutex.RLock()
+	defer slf.addrMutex.RUnlock()
 	return slf.remoteAddr
 }
 
 // GetID 获取连接ID
 //   - 为远程地址的字符串形式
+//   - 会话恢复后将变更为新的传输层的远程地址
 func (slf *Conn) GetID() string {
-	return slf.remoteAddr.String()
+	return slf.RemoteAddr().String()
 }
 
 // GetIP 获取连接IP
 func (slf *Conn) GetIP() string {
+	slf.addrMutex.RLock()
+	defer slf.addrMutex.RUnlock()
 	return slf.ip
 }
 
+// setAddr 替换连接的远程地址及 IP
+func (slf *Conn) setAddr(addr net.Addr, ip string) {
+	slf.addrMutex.Lock()
+	slf.remoteAddr, slf.ip = addr, ip
+	slf.addrMutex.Unlock()
+}
+
+// GetServer 获取连接所属的服务器
+func (slf *Conn) GetServer() *Server {
+	return slf.server
+}
+
+// GetHeader 获取 WebSocket 握手时请求头中特定键的值
+//   - 非 WebSocket 连接将总是返回空字符串
+func (slf *Conn) GetHeader(key string) string {
+	if slf.header == nil {
+		return ""
+	}
+	return slf.header.Get(key)
+}
+
 // Close 关闭连接
+//   - 主动关闭的连接将不会保留会话
+//   - 因写入队列已满而等待的写入方将被立即唤醒
 func (slf *Conn) Close() {
+	slf.closed.Store(true)
+	slf.closeTransport()
+	slf.mutex.Lock()
+	var pool = slf.packetPool
+	slf.packetPool = nil
+	slf.packets = nil
+	slf.queuedPackets, slf.queuedBytes = 0, 0
+	if slf.writable != nil {
+		slf.notifyWritable()
+	}
+	slf.mutex.Unlock()
+	if pool != nil {
+		pool.Close()
+	}
+	if slf.rpc != nil {
+		slf.rpc.Close(ErrConnClosed)
+	}
+	slf.notifyWrite()
+}
+
+// closeWithReason 以特定原因关闭连接，ConnectionClosedEvent 将收到该原因
+func (slf *Conn) closeWithReason(reason error) {
+	slf.mutex.Lock()
+	if slf.closeReason == nil {
+		slf.closeReason = reason
+	}
+	slf.mutex.Unlock()
+	slf.Close()
+}
+
+// closeTransportWithReason 以特定原因关闭连接的传输层，ConnectionClosedEvent 将收到该原因
+//   - 与传输层读取错误相同，开启会话保持时连接的会话将被保留
+func (slf *Conn) closeTransportWithReason(reason error) {
+	slf.mutex.Lock()
+	if slf.closeReason == nil {
+		slf.closeReason = reason
+	}
+	slf.mutex.Unlock()
+	slf.closeTransport()
+}
+
+// closeTransport 关闭连接的传输层
+func (slf *Conn) closeTransport() {
 	if slf.ws != nil {
 		_ = slf.ws.Close()
 	} else if slf.gn != nil {
 		_ = slf.gn.Close()
 	} else if slf.kcp != nil {
 		_ = slf.kcp.Close()
+	} else if slf.virtualCloser != nil {
+		slf.virtualCloser()
 	}
-	if slf.packetPool != nil {
-		slf.packetPool.Close()
-	}
-	slf.packetPool = nil
-	slf.packets = nil
 }
 
 // SetData 设置连接数据
@@ -179,35 +307,181 @@ func (slf *Conn) IsWebsocket() bool {
 // Write 向连接中写入数据
 //   - messageType: websocket模式中指定消息类型
 func (slf *Conn) Write(packet Packet) {
-	packet = slf.server.OnConnectionWritePacketBeforeEvent(slf, packet)
-	if slf.packetPool == nil {
-		return
-	}
-	cp := slf.packetPool.Get()
-	cp.websocketMessageType = packet.WebsocketType
-	cp.packet = packet.Data
-	slf.mutex.Lock()
-	slf.packets = append(slf.packets, cp)
-	slf.mutex.Unlock()
+	slf.WriteWithCallback(packet, nil)
 }
 
 // WriteWithCallback 与 Write 相同，但是会在写入完成后调用 callback
 //   - 当 callback 为 nil 时，与 Write 相同
 func (slf *Conn) WriteWithCallback(packet Packet, callback func(err error)) {
 	packet = slf.server.OnConnectionWritePacketBeforeEvent(slf, packet)
+	if slf.rpc != nil {
+		packet.Data = PackRPCFrame(RPCFramePush, 0, packet.Data)
+	}
+	slf.write(packet, callback)
+}
+
+// Call 向连接发起请求并等待响应
+//   - 需要通过 WithRPC 开启请求/响应模式，否则将返回 ErrRPCNotEnabled
+//   - 可通过 ctx 控制请求的超时时间及取消，当连接关闭时将返回 ErrConnClosed
+//   - 该函数将阻塞直到收到响应，请勿在服务器消息中同步调用，否则等待期间将阻塞整个消息分发，可通过 PushAsyncMessage 进行调用
+func (slf *Conn) Call(ctx context.Context, packet Packet) (Packet, error) {
+	if slf.rpc == nil {
+		return Packet{}, ErrRPCNotEnabled
+	}
+	packet = slf.server.OnConnectionWritePacketBeforeEvent(slf, packet)
+	return slf.rpc.Call(ctx, func(seq uint32) error {
+		if slf.closed.Load() {
+			return ErrConnClosed
+		}
+		slf.write(Packet{WebsocketType: packet.WebsocketType, Data: PackRPCFrame(RPCFrameRequest, seq, packet.Data)}, nil)
+		return nil
+	})
+}
+
+// reply 对特定序列号的请求进行响应
+func (slf *Conn) reply(seq uint32, packet Packet) {
+	packet = slf.server.OnConnectionWritePacketBeforeEvent(slf, packet)
+	packet.Data = PackRPCFrame(RPCFrameResponse, seq, packet.Data)
+	slf.write(packet, nil)
+}
+
+// write 将数据包加入写入队列
+//   - 当写入队列超出 WithWriteQueueLimit 设置的上限时，将根据 WriteQueueFullPolicy 进行处理
+//   - 当连接已通过会话恢复转交至其他连接时，数据包将写入接管该连接传输层的连接
+func (slf *Conn) write(packet Packet, callback func(err error)) {
+	slf.mutex.Lock()
 	if slf.packetPool == nil {
+		var resumed = slf.resumedTo.Load()
+		slf.mutex.Unlock()
+		if resumed != nil {
+			resumed.write(packet, callback)
+		}
 		return
 	}
+	if limit := slf.server.writeQueueLimit; limit > 0 {
+		var deadline <-chan time.Time
+		for slf.queuedPackets > 0 && slf.queuedBytes+len(packet.Data) > limit {
+			if slf.server.writeQueuePolicy == WriteQueueFullBlock && !slf.detached {
+				if deadline == nil {
+					timer := time.NewTimer(DefaultWriteQueueBlockTimeout)
+					defer timer.Stop()
+					deadline = timer.C
+				}
+				var writable = slf.writable
+				slf.mutex.Unlock()
+				var timeout bool
+				select {
+				case <-writable:
+				case <-deadline:
+					timeout = true
+				}
+				slf.mutex.Lock()
+				if slf.packetPool == nil {
+					var resumed = slf.resumedTo.Load()
+					slf.mutex.Unlock()
+					if resumed != nil {
+						resumed.write(packet, callback)
+					}
+					return
+				}
+				if !timeout {
+					continue
+				}
+			}
+			slf.droppedPackets++
+			slf.mutex.Unlock()
+			if callback != nil {
+				callback(ErrConnWriteQueueFull)
+			}
+			if slf.server.writeQueuePolicy == WriteQueueFullDisconnect {
+				log.Warn("Server", log.String("conn", slf.GetID()), log.String("action", "disconnect"), log.Err(ErrConnWriteQueueFull))
+				slf.Close()
+			}
+			return
+		}
+	}
 	cp := slf.packetPool.Get()
 	cp.websocketMessageType = packet.WebsocketType
 	cp.packet = packet.Data
 	cp.callback = callback
-	slf.mutex.Lock()
 	slf.packets = append(slf.packets, cp)
+	slf.queuedPackets++
+	slf.queuedBytes += len(cp.packet)
+	if slf.queuedBytes > slf.peakQueuedBytes {
+		slf.peakQueuedBytes = slf.queuedBytes
+	}
 	slf.mutex.Unlock()
+	slf.notifyWrite()
+}
+
+// notifyWritable 唤醒所有因写入队列已满而等待的写入方，调用方需持有 mutex
+func (slf *Conn) notifyWritable() {
+	close(slf.writable)
+	slf.writable = make(chan struct{})
+}
+
+// notifyWrite 通知写循环有新的数据包需要写入
+func (slf *Conn) notifyWrite() {
+	select {
+	case slf.writeSignal <- struct{}{}:
+	default:
+	}
+}
+
+// receive 接收来自传输层的数据并推送至服务器
+//   - 当服务器设置了 PacketCodec 时，将会对数据进行拆包处理，仅推送完整的数据包
+func (slf *Conn) receive(data []byte) error {
+	codec := slf.server.packetCodec
+	if codec == nil {
+		slf.push(bytes.Clone(data), 0)
+		return nil
+	}
+	slf.unpacked = append(slf.unpacked, data...)
+	var offset int
+	for offset < len(slf.unpacked) {
+		packet, n, err := codec.Decode(slf.unpacked[offset:])
+		if err != nil {
+			slf.unpacked = nil
+			return err
+		}
+		if n == 0 {
+			break
+		}
+		offset += n
+		slf.push(packet, 0)
+	}
+	slf.unpacked = append(slf.unpacked[:0], slf.unpacked[offset:]...)
+	return nil
+}
+
+// push 将完整的数据包推送至服务器
+//   - 在请求/响应模式下，响应帧将直接交付给等待中的请求，不会进入服务器消息队列
+//   - 仅作为代理链路的连接不进行速率限制，速率限制将作用于其承载的每个虚拟连接
+func (slf *Conn) push(packet []byte, websocketType int) {
+	if resumed := slf.resumedTo.Load(); resumed != nil {
+		resumed.push(packet, websocketType)
+		return
+	}
+	slf.touch()
+	slf.server.metrics.recordBytesIn(len(packet))
+	if !slf.server.isLink(slf) && !slf.limit(len(packet)) {
+		return
+	}
+	if matcher := slf.server.heartbeatMatcher; matcher != nil && matcher(slf, packet) {
+		return
+	}
+	if slf.rpc != nil {
+		kind, seq, data, err := UnpackRPCFrame(packet)
+		if err == nil && kind == RPCFrameResponse {
+			slf.rpc.Resolve(seq, Packet{WebsocketType: websocketType, Data: data})
+			return
+		}
+	}
+	PushPacketMessage(slf.server, slf, append(packet, byte(websocketType)))
 }
 
 // writeLoop 写循环
+//   - 在接收到写入信号后立即将队列中的数据包批量写入，在传输层支持的情况下将合并为一次写入
 func (slf *Conn) writeLoop(wait *sync.WaitGroup) {
 	slf.packetPool = concurrent.NewPool[*connPacket](10*1024,
 		func() *connPacket {
@@ -218,51 +492,151 @@ func (slf *Conn) writeLoop(wait *sync.WaitGroup) {
 			data.callback = nil
 		},
 	)
+	slf.writeSignal = make(chan struct{}, 1)
+	slf.writable = make(chan struct{})
 	defer func() {
 		if err := recover(); err != nil {
 			slf.Close()
 		}
 	}()
 	wait.Done()
-	for {
+	for range slf.writeSignal {
 		slf.mutex.Lock()
 		if slf.packetPool == nil {
+			slf.notifyWritable()
+			slf.mutex.Unlock()
 			return
 		}
-		if len(slf.packets) == 0 {
+		if len(slf.packets) == 0 || slf.detached {
 			slf.mutex.Unlock()
-			time.Sleep(50 * time.Millisecond)
 			continue
 		}
-		packets := slf.packets[0:]
-		slf.packets = slf.packets[0:0]
+		packets, pool := slf.packets, slf.packetPool
+		slf.packets = nil
 		slf.mutex.Unlock()
-		for i := 0; i < len(packets); i++ {
-			data := packets[i]
+		slf.flush(pool, packets)
+	}
+}
+
+// flush 将数据包写入传输层
+//   - 写入期间传输层被会话恢复替换时，写入失败的数据包将保留至新的传输层发送，不会影响新的传输层
+//   - pool 为取出数据包时的缓冲池，写入期间连接被关闭时 packetPool 将被置空，因此不会再次读取该字段
+func (slf *Conn) flush(pool *concurrent.Pool[*connPacket], packets []*connPacket) {
+	slf.mutex.Lock()
+	var ws, gn, kcp, carrier = slf.ws, slf.gn, slf.kcp, slf.carrier.Load()
+	slf.mutex.Unlock()
+	var count, size = len(packets), 0
+	var buffers = make([][]byte, 0, len(packets))
+	for i := 0; i < len(packets); i++ {
+		data := packets[i]
+		size += len(data.packet)
+		var buffer = data.packet
+		if codec := slf.server.packetCodec; codec != nil && slf.virtual == nil {
 			var err error
-			if slf.IsWebsocket() {
-				err = slf.ws.WriteMessage(data.websocketMessageType, data.packet)
-			} else {
-				if slf.gn != nil {
-					switch slf.server.network {
-					case NetworkUdp, NetworkUdp4, NetworkUdp6:
-						err = slf.gn.SendTo(data.packet)
-					default:
-						err = slf.gn.AsyncWrite(data.packet)
-					}
+			if buffer, err = codec.Encode(data.packet); err != nil {
+				callback := data.callback
+				pool.Release(data)
+				if callback != nil {
+					callback(err)
+				}
+				packets = append(packets[:i], packets[i+1:]...)
+				i--
+				continue
+			}
+		}
+		buffers = append(buffers, buffer)
+	}
 
-				} else if slf.kcp != nil {
-					_, err = slf.kcp.Write(data.packet)
+	var written int
+	var err error
+	switch {
+	case ws != nil:
+		for ; written < len(packets); written++ {
+			data := packets[written]
+			if err = ws.WriteMessage(data.websocketMessageType, data.packet); err != nil {
+				break
+			}
+		}
+	case gn != nil:
+		switch slf.server.network {
+		case NetworkUdp, NetworkUdp4, NetworkUdp6:
+			for ; written < len(buffers); written++ {
+				if err = gn.SendTo(buffers[written]); err != nil {
+					break
 				}
 			}
-			callback := data.callback
-			slf.packetPool.Release(data)
-			if callback != nil {
-				callback(err)
+		default:
+			if err = gn.AsyncWritev(buffers); err == nil {
+				written = len(buffers)
 			}
-			if err != nil {
-				panic(err)
+		}
+	case kcp != nil:
+		if _, err = kcp.WriteBuffers(buffers); err == nil {
+			written = len(buffers)
+		}
+	case slf.virtual != nil:
+		for ; written < len(packets); written++ {
+			data := packets[written]
+			if err = slf.virtual(Packet{WebsocketType: data.websocketMessageType, Data: data.packet}); err != nil {
+				break
 			}
 		}
+	default:
+		written = len(packets)
+	}
+
+	var sentBytes int
+	for i := 0; i < written; i++ {
+		data := packets[i]
+		sentBytes += len(buffers[i])
+		callback := data.callback
+		pool.Release(data)
+		if callback != nil {
+			callback(nil)
+		}
+	}
+	slf.mutex.Lock()
+	slf.queuedPackets -= count
+	slf.queuedBytes -= size
+	slf.sentPackets += int64(written)
+	slf.sentBytes += int64(sentBytes)
+	slf.flushes++
+	if err == nil {
+		slf.notifyWritable()
+	}
+	slf.mutex.Unlock()
+	slf.server.metrics.recordBytesOut(sentBytes)
+	if err == nil {
+		return
+	}
+
+	// 写入失败的数据包及其之后的数据包均未送达，处于会话保持期间时将按原有顺序保留，待会话恢复后重新发送
+	var unsent = packets[written:]
+	if slf.token != "" && !slf.closed.Load() {
+		slf.mutex.Lock()
+		var replaced = slf.carrier.Load() != carrier
+		if !replaced {
+			slf.detached = true
+		}
+		for _, data := range unsent {
+			slf.queuedPackets++
+			slf.queuedBytes += len(data.packet)
+		}
+		slf.packets = append(unsent, slf.packets...)
+		slf.mutex.Unlock()
+		if replaced {
+			slf.notifyWrite()
+		} else {
+			slf.closeTransport()
+		}
+		return
+	}
+	for _, data := range unsent {
+		callback := data.callback
+		pool.Release(data)
+		if callback != nil {
+			callback(err)
+		}
 	}
+	panic(err)
 }
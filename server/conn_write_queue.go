@@ -0,0 +1,40 @@
+package server
+
+const (
+	// WriteQueueFullDrop 写入队列已满时丢弃新写入的数据包，数据包的回调函数将收到 ErrConnWriteQueueFull
+	WriteQueueFullDrop WriteQueueFullPolicy = iota
+	// WriteQueueFullBlock 写入队列已满时阻塞写入方，直到队列中的数据被发送或连接关闭
+	//   - 阻塞时间超过 DefaultWriteQueueBlockTimeout 或连接处于会话保持期间时将丢弃数据包，避免消息处理协程被永久阻塞
+	WriteQueueFullBlock
+	// WriteQueueFullDisconnect 写入队列已满时视为慢速消费者，主动断开连接
+	WriteQueueFullDisconnect
+)
+
+// WriteQueueFullPolicy 连接写入队列已满时的处理策略
+type WriteQueueFullPolicy byte
+
+// ConnWriteQueueMetrics 连接写入队列指标
+type ConnWriteQueueMetrics struct {
+	QueuedPackets   int   // 当前写入队列中的数据包数量
+	QueuedBytes     int   // 当前写入队列中的字节数
+	PeakQueuedBytes int   // 写入队列字节数峰值
+	SentPackets     int64 // 已发送的数据包数量
+	SentBytes       int64 // 已发送的字节数（包含封包产生的额外字节）
+	DroppedPackets  int64 // 因写入队列已满被丢弃的数据包数量
+	Flushes         int64 // 批量写入次数
+}
+
+// GetWriteQueueMetrics 获取连接写入队列指标
+func (slf *Conn) GetWriteQueueMetrics() ConnWriteQueueMetrics {
+	slf.mutex.Lock()
+	defer slf.mutex.Unlock()
+	return ConnWriteQueueMetrics{
+		QueuedPackets:   slf.queuedPackets,
+		QueuedBytes:     slf.queuedBytes,
+		PeakQueuedBytes: slf.peakQueuedBytes,
+		SentPackets:     slf.sentPackets,
+		SentBytes:       slf.sentBytes,
+		DroppedPackets:  slf.droppedPackets,
+		Flushes:         slf.flushes,
+	}
+}
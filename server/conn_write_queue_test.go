@@ -0,0 +1,80 @@
+package server_test
+
+import (
+	"errors"
+	"github.com/kercylan98/minotaur/server"
+	. "github.com/smartystreets/goconvey/convey"
+	"testing"
+	"time"
+)
+
+func TestWithWriteQueueLimit_Block(t *testing.T) {
+	Convey("TestWithWriteQueueLimit_Block", t, func() {
+		srv := server.New(server.NetworkNone, server.WithWriteQueueLimit(4, server.WriteQueueFullBlock))
+		var release = make(chan struct{})
+		var written = make(chan string, 4)
+		var results = make(chan error, 4)
+		srv.RegConnectionReceivePacketEvent(func(srv *server.Server, conn *server.Conn, packet server.Packet) {
+			for _, data := range []string{"aaaa", "bbbb"} {
+				conn.WriteWithCallback(server.Packet{Data: []byte(data)}, func(err error) {
+					results <- err
+				})
+			}
+		})
+		stop := runServer(srv)
+		defer stop()
+
+		conn := server.NewVirtualConn(srv, "c1", "127.0.0.1", func(packet server.Packet) error {
+			<-release
+			written <- string(packet.Data)
+			return nil
+		}, nil)
+		srv.OnConnectionOpenedEvent(conn)
+		conn.ReceiveVirtualPacket(server.Packet{Data: []byte("write")})
+
+		time.Sleep(100 * time.Millisecond)
+		So(conn.GetWriteQueueMetrics().QueuedPackets, ShouldEqual, 1)
+		close(release)
+
+		for _, expect := range []string{"aaaa", "bbbb"} {
+			select {
+			case data := <-written:
+				So(data, ShouldEqual, expect)
+			case <-time.After(time.Second):
+				t.Fatal("packet was not written")
+			}
+			So(<-results, ShouldBeNil)
+		}
+		So(conn.GetWriteQueueMetrics().DroppedPackets, ShouldEqual, 0)
+	})
+}
+
+func TestWithWriteQueueLimit_BlockClose(t *testing.T) {
+	Convey("TestWithWriteQueueLimit_BlockClose", t, func() {
+		srv := server.New(server.NetworkNone, server.WithWriteQueueLimit(4, server.WriteQueueFullBlock))
+		var release = make(chan struct{})
+		var done = make(chan struct{})
+		srv.RegConnectionReceivePacketEvent(func(srv *server.Server, conn *server.Conn, packet server.Packet) {
+			conn.Write(server.Packet{Data: []byte("aaaa")})
+			conn.Write(server.Packet{Data: []byte("bbbb")})
+			close(done)
+		})
+		stop := runServer(srv)
+		defer stop()
+
+		conn := server.NewVirtualConn(srv, "c1", "127.0.0.1", func(packet server.Packet) error {
+			<-release
+			return errors.New("broken")
+		}, nil)
+		srv.OnConnectionOpenedEvent(conn)
+		conn.ReceiveVirtualPacket(server.Packet{Data: []byte("write")})
+
+		time.Sleep(100 * time.Millisecond)
+		close(release)
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatal("blocked writer was not woken after the write loop failed")
+		}
+	})
+}
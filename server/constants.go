@@ -21,8 +21,18 @@ const (
 )
 
 const (
-	DefaultMessageBufferSize     = 1024
-	DefaultMessageChannelSize    = 1024 * 64
-	DefaultAsyncPoolSize         = 256
-	DefaultWebsocketReadDeadline = 30 * time.Second
+	DefaultMessageBufferSize      = 1024
+	DefaultMessageChannelSize     = 1024 * 64
+	DefaultAsyncPoolSize          = 256
+	DefaultWebsocketReadDeadline  = 30 * time.Second
+	DefaultPacketCodecMaxSize     = 4 * 1024 * 1024
+	DefaultRPCMaxInflight         = 1024
+	DefaultCrossRetryAttempts     = 5
+	DefaultCrossRetryBackoff      = time.Second
+	DefaultCrossRetryMaxBackoff   = 10 * time.Second
+	DefaultShuntIdleTimeout       = time.Minute
+	DefaultShuntChannelSize       = 1024
+	DefaultCrashReportWindow      = time.Minute
+	DefaultHotUpgradeTimeout      = 30 * time.Second
+	DefaultWriteQueueBlockTimeout = 5 * time.Second
 )
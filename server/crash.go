@@ -0,0 +1,269 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kercylan98/minotaur/notify"
+	"github.com/kercylan98/minotaur/utils/log"
+)
+
+const crashPacketPreviewLimit = 1024 // 崩溃报告中数据包预览的最大长度
+
+// CrashReport 处理消息时发生崩溃的报告
+//   - 相同调用栈的崩溃将被视为同一崩溃，仅在 WithCrashReport 设置的时间窗口内上报一次，并通过 Count 进行计数
+//   - 实现了 notify.Notify 接口，可直接通过 notify.Manager 进行推送
+type CrashReport struct {
+	Signature    string      `json:"signature"`           // 调用栈签名
+	Count        int64       `json:"count"`               // 累计发生次数
+	Suppressed   int64       `json:"suppressed"`          // 自上次上报以来被去重的次数
+	FirstAt      time.Time   `json:"first_at"`            // 首次发生时间
+	LastAt       time.Time   `json:"last_at"`             // 最近一次发生时间
+	MessageType  MessageType `json:"message_type"`        // 消息类型
+	MessageName  string      `json:"message_name"`        // 消息类型名称
+	ConnID       string      `json:"conn_id,omitempty"`   // 连接 ID，仅数据包消息有效
+	ConnDataKeys []string    `json:"conn_data_keys"`      // 连接数据中的所有键，仅数据包消息有效
+	Packet       string      `json:"packet,omitempty"`    // 通过 SetMessagePacketVisualizer 可视化后的数据包预览，仅数据包消息有效
+	Panic        string      `json:"panic"`               // panic 的值
+	Stack        string      `json:"stack"`               // 调用栈
+	ServerID     int64       `json:"server_id,omitempty"` // 跨服消息来源服务器 ID，仅跨服消息有效
+	Mark         string      `json:"mark,omitempty"`      // 定时器名称或消息标记
+}
+
+// Format 格式化崩溃报告
+func (slf CrashReport) Format() (string, error) {
+	data, err := json.Marshal(slf)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// CrashSink 崩溃报告的接收器
+type CrashSink interface {
+	// Report 接收崩溃报告，该函数可能在不同的协程中被调用
+	Report(report CrashReport)
+}
+
+// CrashSinkFunc 通过函数接收崩溃报告
+type CrashSinkFunc func(report CrashReport)
+
+// Report 接收崩溃报告
+func (slf CrashSinkFunc) Report(report CrashReport) {
+	slf(report)
+}
+
+// NewCrashFileSink 创建一个将崩溃报告以 JSON Lines 格式追加写入到文件中的接收器
+func NewCrashFileSink(path string) CrashSink {
+	return &crashFileSink{path: path}
+}
+
+// crashFileSink 将崩溃报告写入文件的接收器
+type crashFileSink struct {
+	path  string
+	mutex sync.Mutex
+}
+
+func (slf *crashFileSink) Report(report CrashReport) {
+	data, err := json.Marshal(report)
+	if err != nil {
+		log.Error("Server", log.String("action", "crash-report"), log.Err(err))
+		return
+	}
+	slf.mutex.Lock()
+	defer slf.mutex.Unlock()
+	file, err := os.OpenFile(slf.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		log.Error("Server", log.String("action", "crash-report"), log.Err(err))
+		return
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+	if _, err = file.Write(append(data, '\n')); err != nil {
+		log.Error("Server", log.String("action", "crash-report"), log.Err(err))
+	}
+}
+
+// NewCrashNotifySink 创建一个通过 notify.Manager 推送崩溃报告的接收器
+//   - converter：将崩溃报告转换为特定渠道的通知，例如 notifies.NewFeiShu，为 nil 时将直接推送 CrashReport
+func NewCrashNotifySink(manager *notify.Manager, converter func(report CrashReport) notify.Notify) CrashSink {
+	return CrashSinkFunc(func(report CrashReport) {
+		if converter == nil {
+			manager.PushNotify(report)
+			return
+		}
+		manager.PushNotify(converter(report))
+	})
+}
+
+// newCrashReporter 创建崩溃报告器
+func newCrashReporter(window time.Duration, sinks []CrashSink) *crashReporter {
+	return &crashReporter{
+		window:   window,
+		sinks:    sinks,
+		reports:  map[string]*CrashReport{},
+		reported: map[string]time.Time{},
+	}
+}
+
+// crashReporter 崩溃报告器
+type crashReporter struct {
+	mutex    sync.Mutex
+	window   time.Duration           // 相同签名的崩溃上报的时间窗口
+	sinks    []CrashSink             // 接收器
+	reports  map[string]*CrashReport // 签名对应的崩溃报告
+	reported map[string]time.Time    // 签名最近一次上报的时间
+}
+
+// report 记录崩溃报告，当该签名在时间窗口内未上报时将交由所有接收器处理
+func (slf *crashReporter) report(report CrashReport) {
+	slf.mutex.Lock()
+	exist, ok := slf.reports[report.Signature]
+	if !ok {
+		report.FirstAt = report.LastAt
+		exist = &report
+		slf.reports[report.Signature] = exist
+	} else {
+		var count, suppressed, firstAt = exist.Count, exist.Suppressed, exist.FirstAt
+		*exist = report
+		exist.Count, exist.Suppressed, exist.FirstAt = count, suppressed, firstAt
+	}
+	exist.Count++
+	if at, reported := slf.reported[report.Signature]; reported && exist.LastAt.Sub(at) < slf.window {
+		exist.Suppressed++
+		slf.mutex.Unlock()
+		return
+	}
+	slf.reported[report.Signature] = exist.LastAt
+	report = *exist
+	exist.Suppressed = 0
+	slf.mutex.Unlock()
+
+	for _, sink := range slf.sinks {
+		sink.Report(report)
+	}
+}
+
+// snapshot 获取所有崩溃报告
+func (slf *crashReporter) snapshot() []CrashReport {
+	slf.mutex.Lock()
+	defer slf.mutex.Unlock()
+	var reports = make([]CrashReport, 0, len(slf.reports))
+	for _, report := range slf.reports {
+		reports = append(reports, *report)
+	}
+	sort.Slice(reports, func(i, j int) bool {
+		return reports[i].LastAt.After(reports[j].LastAt)
+	})
+	return reports
+}
+
+// GetCrashReports 获取服务器运行以来所有的崩溃报告，按照最近一次发生时间倒序排列
+//   - 未通过 WithCrashReport 开启崩溃报告时将返回 nil
+func (slf *Server) GetCrashReports() []CrashReport {
+	if slf.crashReporter == nil {
+		return nil
+	}
+	return slf.crashReporter.snapshot()
+}
+
+// recoverMessage 处理消息处理过程中捕获到的 panic
+//   - 非 error 类型的 panic 将被包装为 ErrMessagePanic 后触发 MessageErrorEvent
+func (slf *Server) recoverMessage(msg *Message, attrs []any, r any, stack string) {
+	log.Error("Server", log.String("MessageType", messageNames[msg.t]), log.Any("MessageAttrs", attrs), log.Any("error", r), log.String("stack", stack))
+	if slf.crashReporter != nil {
+		slf.crashReporter.report(slf.newCrashReport(msg.t, attrs, r, stack))
+	}
+	err, ok := r.(error)
+	if !ok {
+		err = fmt.Errorf("%w: %v", ErrMessagePanic, r)
+	}
+	slf.OnMessageErrorEvent(msg, err)
+}
+
+// newCrashReport 根据消息创建崩溃报告
+func (slf *Server) newCrashReport(t MessageType, attrs []any, r any, stack string) CrashReport {
+	var report = CrashReport{
+		Signature:   crashSignature(stack),
+		LastAt:      time.Now(),
+		MessageType: t,
+		MessageName: t.String(),
+		Panic:       fmt.Sprint(r),
+		Stack:       stack,
+	}
+	switch t {
+	case MessageTypePacket:
+		if conn, ok := attrs[0].(*Conn); ok {
+			report.ConnID = conn.GetID()
+			report.ConnDataKeys = make([]string, 0, len(conn.data))
+			for key := range conn.data {
+				report.ConnDataKeys = append(report.ConnDataKeys, fmt.Sprint(key))
+			}
+			sort.Strings(report.ConnDataKeys)
+		}
+		if packet, ok := attrs[1].([]byte); ok {
+			report.Packet = messagePacketVisualization(packet)
+			if len(report.Packet) > crashPacketPreviewLimit {
+				report.Packet = report.Packet[:crashPacketPreviewLimit] + "...(" + strconv.Itoa(len(report.Packet)) + ")"
+			}
+		}
+	case MessageTypeCross:
+		report.ServerID, _ = attrs[0].(int64)
+	case MessageTypeTicker, MessageTypeSystem:
+		if len(attrs) > 1 {
+			report.Mark = fmt.Sprint(attrs[1:]...)
+		}
+	case MessageTypeAsync:
+		if len(attrs) > 2 {
+			report.Mark = fmt.Sprint(attrs[2:]...)
+		}
+	}
+	return report
+}
+
+// crashSignature 计算调用栈签名
+//   - 将忽略协程 ID、函数参数及指令偏移，使得相同代码位置产生的崩溃具有相同的签名
+//   - 将跳过 panic 之前的 recover 相关调用栈，仅从发生 panic 的位置开始计算
+//   - 仅计算至 dispatchMessage 为止的调用栈，避免系统通道与分流通道中相同的崩溃产生不同的签名
+func crashSignature(stack string) string {
+	var lines = strings.Split(stack, "\n")
+	var start int
+	for i, line := range lines {
+		if strings.HasPrefix(line, "panic(") {
+			start = i + 2
+			break
+		}
+	}
+	if start > len(lines) {
+		start = len(lines)
+	}
+	var hash = fnv.New64a()
+	for _, line := range lines[start:] {
+		if strings.HasPrefix(line, "goroutine ") {
+			continue
+		}
+		var dispatch bool
+		if strings.HasPrefix(line, "\t") {
+			if index := strings.LastIndex(line, " +0x"); index != -1 {
+				line = line[:index]
+			}
+		} else if index := strings.LastIndex(line, "("); index != -1 {
+			dispatch = strings.Contains(line, "(*Server).dispatchMessage(")
+			line = line[:index]
+		}
+		_, _ = hash.Write([]byte(line))
+		_, _ = hash.Write([]byte{'\n'})
+		if dispatch {
+			break
+		}
+	}
+	return strconv.FormatUint(hash.Sum64(), 16)
+}
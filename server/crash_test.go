@@ -0,0 +1,122 @@
+package server_test
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"github.com/kercylan98/minotaur/server"
+	. "github.com/smartystreets/goconvey/convey"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWithCrashReport(t *testing.T) {
+	Convey("TestWithCrashReport", t, func() {
+		var path = filepath.Join(t.TempDir(), "crash.log")
+		var reports = make(chan server.CrashReport, 8)
+		srv := server.New(server.NetworkNone, server.WithCrashReport(time.Hour, server.NewCrashFileSink(path), server.CrashSinkFunc(func(report server.CrashReport) {
+			reports <- report
+		})))
+		var errs = make(chan error, 8)
+		srv.RegMessageErrorEvent(func(srv *server.Server, message *server.Message, err error) {
+			errs <- err
+		})
+		srv.RegConnectionReceivePacketEvent(func(srv *server.Server, conn *server.Conn, packet server.Packet) {
+			panic(string(packet.Data))
+		})
+		stop := runServer(srv)
+
+		conn := server.NewVirtualConn(srv, "c1", "127.0.0.1", func(packet server.Packet) error {
+			return nil
+		}, nil)
+		conn.SetData("uid", 1)
+		srv.OnConnectionOpenedEvent(conn)
+		for i := 0; i < 3; i++ {
+			conn.ReceiveVirtualPacket(server.Packet{Data: []byte("boom")})
+		}
+		for i := 0; i < 3; i++ {
+			select {
+			case err := <-errs:
+				So(errors.Is(err, server.ErrMessagePanic), ShouldBeTrue)
+			case <-time.After(time.Second):
+				t.Fatal("message error event was not triggered")
+			}
+		}
+		stop()
+
+		So(len(reports), ShouldEqual, 1)
+		report := <-reports
+		So(report.Count, ShouldEqual, 1)
+		So(report.MessageType, ShouldEqual, server.MessageTypePacket)
+		So(report.ConnID, ShouldEqual, "c1")
+		So(report.ConnDataKeys, ShouldResemble, []string{"uid"})
+		So(report.Packet, ShouldContainSubstring, "boom")
+		So(report.Panic, ShouldEqual, "boom")
+		So(report.Stack, ShouldNotBeEmpty)
+
+		crashes := srv.GetCrashReports()
+		So(len(crashes), ShouldEqual, 1)
+		So(crashes[0].Signature, ShouldEqual, report.Signature)
+		So(crashes[0].Count, ShouldEqual, 3)
+		So(crashes[0].Suppressed, ShouldEqual, 2)
+
+		file, err := os.Open(path)
+		So(err, ShouldBeNil)
+		defer file.Close()
+		var lines int
+		var scanner = bufio.NewScanner(file)
+		scanner.Buffer(nil, 1024*1024)
+		for scanner.Scan() {
+			var r server.CrashReport
+			So(json.Unmarshal(scanner.Bytes(), &r), ShouldBeNil)
+			So(r.Signature, ShouldEqual, report.Signature)
+			lines++
+		}
+		So(lines, ShouldEqual, 1)
+	})
+}
+
+func TestWithCrashReport_Signature(t *testing.T) {
+	Convey("TestWithCrashReport_Signature", t, func() {
+		var reports = make(chan server.CrashReport, 8)
+		srv := server.New(server.NetworkNone, server.WithCrashReport(time.Hour, server.CrashSinkFunc(func(report server.CrashReport) {
+			reports <- report
+		})))
+		srv.RegConnectionReceivePacketEvent(func(srv *server.Server, conn *server.Conn, packet server.Packet) {
+			if string(packet.Data) == "a" {
+				crashSiteA()
+			}
+			crashSiteB()
+		})
+		stop := runServer(srv)
+		defer stop()
+
+		conn := server.NewVirtualConn(srv, "c1", "127.0.0.1", func(packet server.Packet) error {
+			return nil
+		}, nil)
+		srv.OnConnectionOpenedEvent(conn)
+		conn.ReceiveVirtualPacket(server.Packet{Data: []byte("a")})
+		conn.ReceiveVirtualPacket(server.Packet{Data: []byte("b")})
+
+		var signatures []string
+		for i := 0; i < 2; i++ {
+			select {
+			case report := <-reports:
+				signatures = append(signatures, report.Signature)
+			case <-time.After(time.Second):
+				t.Fatal("crash report was not triggered")
+			}
+		}
+		So(signatures[0], ShouldNotEqual, signatures[1])
+	})
+}
+
+func crashSiteA() {
+	panic("a")
+}
+
+func crashSiteB() {
+	panic("b")
+}
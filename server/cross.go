@@ -1,5 +1,13 @@
 package server
 
+import (
+	"context"
+	"fmt"
+	"github.com/kercylan98/minotaur/utils/log"
+	"reflect"
+	"time"
+)
+
 // Cross 跨服接口
 type Cross interface {
 	// Init 初始化跨服
@@ -13,3 +21,117 @@ type Cross interface {
 	// Release 释放资源
 	Release()
 }
+
+// CrossAdvanced 支持广播、分组推送及请求/响应的跨服接口
+type CrossAdvanced interface {
+	Cross
+	// SetRequestHandle 设置跨服请求的处理函数，将在 Init 之前被调用
+	//  - requestHandle.serverId: 发起请求的服务器id
+	//  - requestHandle.reply: 对请求进行响应
+	SetRequestHandle(requestHandle func(serverId int64, packet []byte, reply func(packet []byte)))
+	// Broadcast 向所有服务器广播跨服消息，包括本服
+	Broadcast(packet []byte) error
+	// PushGroup 向特定分组中的所有服务器推送跨服消息
+	PushGroup(group string, packet []byte) error
+	// Request 向特定服务器发起跨服请求并等待响应
+	Request(ctx context.Context, serverId int64, packet []byte) ([]byte, error)
+}
+
+// initCross 按添加顺序初始化所有跨服中间件，任一跨服中间件初始化失败时将释放已初始化的跨服中间件
+func (slf *Server) initCross() error {
+	for i, name := range slf.crossNames {
+		if err := slf.initCrossWithRetry(name, slf.cross[name]); err != nil {
+			for _, initialized := range slf.crossNames[:i] {
+				slf.cross[initialized].Release()
+			}
+			slf.cross = nil
+			return err
+		}
+	}
+	return nil
+}
+
+// initCrossWithRetry 根据重试策略初始化特定的跨服中间件
+func (slf *Server) initCrossWithRetry(crossName string, cross Cross) error {
+	if advanced, ok := cross.(CrossAdvanced); ok {
+		advanced.SetRequestHandle(func(serverId int64, packet []byte, reply func(packet []byte)) {
+			slf.pushCrossMessage(serverId, packet, reply)
+		})
+	}
+	var backoff = slf.crossRetryBackoff
+	for attempt := 1; ; attempt++ {
+		err := cross.Init(slf, func(serverId int64, packet []byte) {
+			slf.pushCrossMessage(serverId, packet)
+		})
+		if err == nil {
+			log.Info("Cross", log.Int64("ServerID", slf.id), log.String("Name", crossName), log.String("Cross", reflect.TypeOf(cross).String()))
+			return nil
+		}
+		if slf.crossRetryAttempts > 0 && attempt >= slf.crossRetryAttempts {
+			return fmt.Errorf("%w: %s after %d attempts: %w", ErrCrossInit, crossName, attempt, err)
+		}
+		log.Warn("Cross", log.Int64("ServerID", slf.id), log.String("Name", crossName), log.Int("Attempt", attempt), log.String("Backoff", backoff.String()), log.Err(err))
+		time.Sleep(backoff)
+		if backoff *= 2; backoff > slf.crossRetryMaxBackoff {
+			backoff = slf.crossRetryMaxBackoff
+		}
+	}
+}
+
+// pushCrossMessage 将跨服中间件接收到的消息推送至消息队列
+//   - 跨服中间件在消息队列创建后才会被初始化，因此不会丢失消息
+func (slf *Server) pushCrossMessage(serverId int64, packet []byte, reply ...func(packet []byte)) {
+	msg := slf.messagePool.Get()
+	msg.t = MessageTypeCross
+	if len(reply) > 0 {
+		msg.attrs = []any{serverId, packet, reply[0]}
+	} else {
+		msg.attrs = []any{serverId, packet}
+	}
+	slf.pushMessage(msg)
+}
+
+// getCrossAdvanced 获取特定名称的 CrossAdvanced 跨服中间件
+func (slf *Server) getCrossAdvanced(crossName string) (CrossAdvanced, error) {
+	cross, exist := slf.cross[crossName]
+	if !exist {
+		return nil, ErrNoSupportCross
+	}
+	advanced, ok := cross.(CrossAdvanced)
+	if !ok {
+		return nil, ErrCrossNotAdvanced
+	}
+	return advanced, nil
+}
+
+// PushCrossBroadcast 通过特定名称的跨服中间件向所有服务器广播跨服消息，包括本服
+//   - 跨服中间件需要实现 CrossAdvanced 接口
+func PushCrossBroadcast(srv *Server, crossName string, packet []byte) error {
+	cross, err := srv.getCrossAdvanced(crossName)
+	if err != nil {
+		return err
+	}
+	return cross.Broadcast(packet)
+}
+
+// PushCrossGroup 通过特定名称的跨服中间件向特定分组中的所有服务器推送跨服消息
+//   - 跨服中间件需要实现 CrossAdvanced 接口，分组的加入方式由跨服中间件决定
+func PushCrossGroup(srv *Server, crossName string, group string, packet []byte) error {
+	cross, err := srv.getCrossAdvanced(crossName)
+	if err != nil {
+		return err
+	}
+	return cross.PushGroup(group, packet)
+}
+
+// CrossRequest 通过特定名称的跨服中间件向特定服务器发起跨服请求并等待响应
+//   - 跨服中间件需要实现 CrossAdvanced 接口
+//   - 目标服务器将通过 ReceiveCrossRequestEvent 对请求进行处理并响应
+//   - 可通过 ctx 控制请求的超时时间，该函数将阻塞直到收到响应，请勿在服务器消息中同步调用，可通过 PushAsyncMessage 进行调用
+func CrossRequest(ctx context.Context, srv *Server, crossName string, serverId int64, packet []byte) ([]byte, error) {
+	cross, err := srv.getCrossAdvanced(crossName)
+	if err != nil {
+		return nil, err
+	}
+	return cross.Request(ctx, serverId, packet)
+}
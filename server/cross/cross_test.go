@@ -0,0 +1,155 @@
+package cross_test
+
+import (
+	"context"
+	"errors"
+	"github.com/kercylan98/minotaur/server"
+	"github.com/kercylan98/minotaur/server/cross"
+	. "github.com/smartystreets/goconvey/convey"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+type crossRecorder struct {
+	mutex    sync.Mutex
+	received map[int64][]string
+}
+
+func (slf *crossRecorder) run(id int64, c server.Cross) *server.Server {
+	srv := server.New(server.NetworkNone, server.WithCross("cross", id, c))
+	srv.RegReceiveCrossPacketEvent(func(srv *server.Server, senderServerId int64, packet []byte) {
+		slf.mutex.Lock()
+		defer slf.mutex.Unlock()
+		slf.received[srv.GetID()] = append(slf.received[srv.GetID()], string(packet))
+	})
+	srv.RegReceiveCrossRequestEvent(func(srv *server.Server, senderServerId int64, packet []byte, reply func(packet []byte)) {
+		reply(append(packet, '!'))
+	})
+	var started = make(chan struct{})
+	srv.RegStartFinishEvent(func(srv *server.Server) {
+		close(started)
+	})
+	go func() {
+		_ = srv.RunNone()
+	}()
+	<-started
+	return srv
+}
+
+func (slf *crossRecorder) get(id int64) []string {
+	slf.mutex.Lock()
+	defer slf.mutex.Unlock()
+	var result = append([]string(nil), slf.received[id]...)
+	sort.Strings(result)
+	return result
+}
+
+func testCross(t *testing.T, name string, generator func(groups ...string) server.Cross) {
+	Convey(name, t, func() {
+		var recorder = &crossRecorder{received: map[int64][]string{}}
+		a := recorder.run(1, generator("zone.asia.*"))
+		b := recorder.run(2, generator())
+		defer a.Shutdown()
+		defer b.Shutdown()
+
+		server.PushCrossMessage(b, "cross", 1, []byte("push"))
+		So(server.PushCrossBroadcast(b, "cross", []byte("broadcast")), ShouldBeNil)
+		So(server.PushCrossGroup(b, "cross", "zone.asia.1", []byte("group")), ShouldBeNil)
+		So(server.PushCrossGroup(b, "cross", "zone.europe.1", []byte("ignored")), ShouldBeNil)
+
+		response, err := server.CrossRequest(context.Background(), b, "cross", 1, []byte("request"))
+		So(err, ShouldBeNil)
+		So(string(response), ShouldEqual, "request!")
+		_, err = server.CrossRequest(context.Background(), b, "cross", 3, []byte("request"))
+		So(err, ShouldNotBeNil)
+
+		time.Sleep(100 * time.Millisecond)
+		So(recorder.get(1), ShouldResemble, []string{"broadcast", "group", "push"})
+		So(recorder.get(2), ShouldResemble, []string{"broadcast"})
+	})
+}
+
+func TestLoopback(t *testing.T) {
+	var network = cross.NewLoopbackNetwork()
+	testCross(t, "TestLoopback", func(groups ...string) server.Cross {
+		return network.NewCross(groups...)
+	})
+}
+
+func TestTCP(t *testing.T) {
+	var hub = cross.NewTCPHub("127.0.0.1:0")
+	if err := hub.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer hub.Close()
+	testCross(t, "TestTCP", func(groups ...string) server.Cross {
+		return cross.NewTCP(hub.Addr().String(), cross.WithTCPGroups(groups...))
+	})
+}
+
+func TestCrossRetry(t *testing.T) {
+	Convey("TestCrossRetry", t, func() {
+		var network = cross.NewLoopbackNetwork()
+		var recorder = &crossRecorder{received: map[int64][]string{}}
+		first := recorder.run(1, network.NewCross())
+		defer first.Shutdown()
+
+		srv := server.New(server.NetworkNone,
+			server.WithCrossRetry(2, time.Millisecond, time.Millisecond),
+			server.WithCross("cross", 1, network.NewCross()),
+		)
+		err := srv.RunNone()
+		So(errors.Is(err, server.ErrCrossInit), ShouldBeTrue)
+		So(errors.Is(err, cross.ErrServerIdExist), ShouldBeTrue)
+	})
+}
+
+// eagerCross 在初始化期间即推送跨服消息的跨服中间件
+type eagerCross struct{}
+
+func (eagerCross) Init(srv *server.Server, packetHandle func(serverId int64, packet []byte)) error {
+	packetHandle(2, []byte("init"))
+	return nil
+}
+
+func (eagerCross) PushMessage(serverId int64, packet []byte) error {
+	return nil
+}
+
+func (eagerCross) Release() {}
+
+func TestCrossInitInRun(t *testing.T) {
+	Convey("TestCrossInitInRun", t, func() {
+		var recorder = &crossRecorder{received: map[int64][]string{}}
+		srv := recorder.run(1, eagerCross{})
+		defer srv.Shutdown()
+
+		var deadline = time.Now().Add(time.Second)
+		for time.Now().Before(deadline) && len(recorder.get(1)) == 0 {
+			time.Sleep(10 * time.Millisecond)
+		}
+		So(recorder.get(1), ShouldResemble, []string{"init"})
+	})
+}
+
+func TestEnvelopeCodec(t *testing.T) {
+	for name, codec := range map[string]cross.EnvelopeCodec{"JSON": cross.JSONEnvelopeCodec, "Binary": cross.BinaryEnvelopeCodec} {
+		Convey(name, t, func() {
+			envelope := cross.NewEnvelope(1, "match", []byte("payload"), cross.WithEnvelopeTTL(time.Second), cross.WithEnvelopeReplyTo("match.result"))
+			So(envelope.TraceID, ShouldNotBeEmpty)
+			data, err := codec.Marshal(envelope)
+			So(err, ShouldBeNil)
+
+			var result = new(cross.Envelope)
+			So(codec.Unmarshal(data, result), ShouldBeNil)
+			So(result, ShouldResemble, envelope)
+			So(result.IsExpired(time.Now()), ShouldBeFalse)
+			So(result.IsExpired(time.Now().Add(2*time.Second)), ShouldBeTrue)
+		})
+	}
+	Convey("IllegalBinary", t, func() {
+		So(cross.BinaryEnvelopeCodec.Unmarshal([]byte{1, 2, 3}, new(cross.Envelope)), ShouldEqual, cross.ErrIllegalEnvelope)
+	})
+}
@@ -0,0 +1,77 @@
+package cross
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+)
+
+// Envelope 跨服消息信封，为跨服数据包附加路由元数据
+//   - 通过 EnvelopeCodec 编码后作为跨服数据包进行传输，与具体的跨服中间件无关
+type Envelope struct {
+	Topic     string        `json:"topic"`              // 消息主题，用于区分消息类型
+	TraceID   string        `json:"trace_id,omitempty"` // 追踪 ID，同一调用链中的消息应当保持一致
+	From      int64         `json:"from"`               // 发送者服务器 id
+	Timestamp int64         `json:"timestamp"`          // 发送时间（毫秒时间戳）
+	TTL       time.Duration `json:"ttl,omitempty"`      // 存活时间，为 0 时表示永不过期
+	ReplyTo   string        `json:"reply_to,omitempty"` // 期望接收方响应的主题，为空时表示无需响应
+	Payload   []byte        `json:"payload,omitempty"`  // 消息体
+}
+
+// EnvelopeOption 跨服消息信封选项
+type EnvelopeOption func(envelope *Envelope)
+
+// WithEnvelopeTraceID 设置跨服消息的追踪 ID，默认将随机生成
+func WithEnvelopeTraceID(traceID string) EnvelopeOption {
+	return func(envelope *Envelope) {
+		envelope.TraceID = traceID
+	}
+}
+
+// WithEnvelopeTTL 设置跨服消息的存活时间，超过存活时间的消息将在接收时被丢弃
+func WithEnvelopeTTL(ttl time.Duration) EnvelopeOption {
+	return func(envelope *Envelope) {
+		envelope.TTL = ttl
+	}
+}
+
+// WithEnvelopeReplyTo 设置期望接收方响应的主题
+func WithEnvelopeReplyTo(topic string) EnvelopeOption {
+	return func(envelope *Envelope) {
+		envelope.ReplyTo = topic
+	}
+}
+
+// NewEnvelope 创建一个跨服消息信封，发送时间为当前时间
+func NewEnvelope(from int64, topic string, payload []byte, options ...EnvelopeOption) *Envelope {
+	envelope := &Envelope{
+		Topic:     topic,
+		From:      from,
+		Timestamp: time.Now().UnixMilli(),
+		Payload:   payload,
+	}
+	for _, option := range options {
+		option(envelope)
+	}
+	if envelope.TraceID == "" {
+		envelope.TraceID = NewTraceID()
+	}
+	return envelope
+}
+
+// NewTraceID 生成一个随机的追踪 ID
+func NewTraceID() string {
+	var id [16]byte
+	_, _ = rand.Read(id[:])
+	return hex.EncodeToString(id[:])
+}
+
+// GetTime 获取跨服消息的发送时间
+func (slf *Envelope) GetTime() time.Time {
+	return time.UnixMilli(slf.Timestamp)
+}
+
+// IsExpired 判断跨服消息在特定时间是否已经过期
+func (slf *Envelope) IsExpired(now time.Time) bool {
+	return slf.TTL > 0 && now.After(slf.GetTime().Add(slf.TTL))
+}
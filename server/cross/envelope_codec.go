@@ -0,0 +1,84 @@
+package cross
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"time"
+)
+
+// EnvelopeCodec 跨服消息信封编解码器
+type EnvelopeCodec interface {
+	// Marshal 将信封编码为跨服数据包
+	Marshal(envelope *Envelope) ([]byte, error)
+	// Unmarshal 将跨服数据包解码至信封
+	Unmarshal(data []byte, envelope *Envelope) error
+}
+
+var (
+	// JSONEnvelopeCodec 基于 JSON 的跨服消息信封编解码器，便于调试及与其他语言互通
+	JSONEnvelopeCodec EnvelopeCodec = jsonEnvelopeCodec{}
+	// BinaryEnvelopeCodec 基于紧凑二进制格式的跨服消息信封编解码器
+	//   - 格式：[版本 1B][发送者 8B][发送时间 8B][存活时间毫秒 8B][主题长度 2B][主题][追踪 ID 长度 2B][追踪 ID][响应主题长度 2B][响应主题][消息体]
+	//   - 多字节字段均采用大端序
+	BinaryEnvelopeCodec EnvelopeCodec = binaryEnvelopeCodec{}
+)
+
+type jsonEnvelopeCodec struct{}
+
+func (jsonEnvelopeCodec) Marshal(envelope *Envelope) ([]byte, error) {
+	return json.Marshal(envelope)
+}
+
+func (jsonEnvelopeCodec) Unmarshal(data []byte, envelope *Envelope) error {
+	return json.Unmarshal(data, envelope)
+}
+
+const binaryEnvelopeVersion = 1
+
+type binaryEnvelopeCodec struct{}
+
+func (binaryEnvelopeCodec) Marshal(envelope *Envelope) ([]byte, error) {
+	var fields = [...]string{envelope.Topic, envelope.TraceID, envelope.ReplyTo}
+	var size = 1 + 8 + 8 + 8 + len(envelope.Payload)
+	for _, field := range fields {
+		if len(field) > 0xFFFF {
+			return nil, ErrIllegalEnvelope
+		}
+		size += 2 + len(field)
+	}
+	var data = make([]byte, 0, size)
+	data = append(data, binaryEnvelopeVersion)
+	data = binary.BigEndian.AppendUint64(data, uint64(envelope.From))
+	data = binary.BigEndian.AppendUint64(data, uint64(envelope.Timestamp))
+	data = binary.BigEndian.AppendUint64(data, uint64(envelope.TTL.Milliseconds()))
+	for _, field := range fields {
+		data = binary.BigEndian.AppendUint16(data, uint16(len(field)))
+		data = append(data, field...)
+	}
+	return append(data, envelope.Payload...), nil
+}
+
+func (binaryEnvelopeCodec) Unmarshal(data []byte, envelope *Envelope) error {
+	if len(data) < 25 || data[0] != binaryEnvelopeVersion {
+		return ErrIllegalEnvelope
+	}
+	envelope.From = int64(binary.BigEndian.Uint64(data[1:]))
+	envelope.Timestamp = int64(binary.BigEndian.Uint64(data[9:]))
+	envelope.TTL = time.Duration(binary.BigEndian.Uint64(data[17:])) * time.Millisecond
+	data = data[25:]
+	var fields [3]string
+	for i := range fields {
+		if len(data) < 2 {
+			return ErrIllegalEnvelope
+		}
+		var length = int(binary.BigEndian.Uint16(data))
+		if len(data) < 2+length {
+			return ErrIllegalEnvelope
+		}
+		fields[i] = string(data[2 : 2+length])
+		data = data[2+length:]
+	}
+	envelope.Topic, envelope.TraceID, envelope.ReplyTo = fields[0], fields[1], fields[2]
+	envelope.Payload = append([]byte(nil), data...)
+	return nil
+}
@@ -0,0 +1,13 @@
+package cross
+
+import "errors"
+
+var (
+	ErrServerIdExist   = errors.New("the server id already exists in the cross network")
+	ErrServerNotFound  = errors.New("the target server was not found in the cross network")
+	ErrNotConnected    = errors.New("the cross is not connected")
+	ErrRequestFailed   = errors.New("cross request failed")
+	ErrNoRequestHandle = errors.New("the target server does not handle cross requests")
+	ErrIllegalEnvelope = errors.New("illegal cross envelope")
+	ErrHubRegister     = errors.New("register to the cross hub failed")
+)
@@ -0,0 +1,30 @@
+package cross
+
+import "strings"
+
+// matchGroup 判断分组是否与分组规则匹配，规则语法与 nats 通配符保持一致
+//   - 分组以 "." 分隔为多个片段
+//   - "*" 匹配任意单个片段
+//   - ">" 仅可位于末尾，匹配剩余的一个或多个片段
+func matchGroup(pattern, group string) bool {
+	var patterns, groups = strings.Split(pattern, "."), strings.Split(group, ".")
+	for i, p := range patterns {
+		if p == ">" {
+			return i == len(patterns)-1 && len(groups) > i
+		}
+		if i >= len(groups) || (p != "*" && p != groups[i]) {
+			return false
+		}
+	}
+	return len(patterns) == len(groups)
+}
+
+// matchGroups 判断分组是否与任意分组规则匹配
+func matchGroups(patterns []string, group string) bool {
+	for _, pattern := range patterns {
+		if matchGroup(pattern, group) {
+			return true
+		}
+	}
+	return false
+}
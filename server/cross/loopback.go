@@ -0,0 +1,117 @@
+package cross
+
+import (
+	"bytes"
+	"context"
+	"github.com/kercylan98/minotaur/server"
+	"github.com/kercylan98/minotaur/utils/concurrent"
+)
+
+// NewLoopbackNetwork 创建一个进程内的跨服网络
+//   - 通过 LoopbackNetwork.NewCross 创建的跨服中间件之间可以直接相互通讯，无需任何外部依赖
+//   - 适用于测试或单进程内运行多个服务器的场景
+func NewLoopbackNetwork() *LoopbackNetwork {
+	return &LoopbackNetwork{
+		crosses: concurrent.NewBalanceMap[int64, *Loopback](),
+	}
+}
+
+// LoopbackNetwork 进程内的跨服网络
+type LoopbackNetwork struct {
+	crosses *concurrent.BalanceMap[int64, *Loopback]
+}
+
+// NewCross 创建一个接入该跨服网络的跨服中间件，每个服务器都应当使用独立的跨服中间件
+//   - groups：加入的跨服分组，支持与 nats 一致的通配符
+func (slf *LoopbackNetwork) NewCross(groups ...string) *Loopback {
+	return &Loopback{
+		network: slf,
+		groups:  groups,
+	}
+}
+
+// Loopback 进程内的跨服中间件
+type Loopback struct {
+	network       *LoopbackNetwork
+	serverId      int64
+	groups        []string
+	packetHandle  func(serverId int64, packet []byte)
+	requestHandle func(serverId int64, packet []byte, reply func(packet []byte))
+}
+
+func (slf *Loopback) Init(server *server.Server, packetHandle func(serverId int64, packet []byte)) (err error) {
+	slf.serverId = server.GetID()
+	slf.packetHandle = packetHandle
+	slf.network.crosses.Atom(func(m map[int64]*Loopback) {
+		if _, exist := m[slf.serverId]; exist {
+			err = ErrServerIdExist
+			return
+		}
+		m[slf.serverId] = slf
+	})
+	return err
+}
+
+// SetRequestHandle 设置跨服请求的处理函数
+func (slf *Loopback) SetRequestHandle(requestHandle func(serverId int64, packet []byte, reply func(packet []byte))) {
+	slf.requestHandle = requestHandle
+}
+
+func (slf *Loopback) PushMessage(serverId int64, packet []byte) error {
+	target, exist := slf.network.crosses.GetExist(serverId)
+	if !exist {
+		return ErrServerNotFound
+	}
+	target.packetHandle(slf.serverId, bytes.Clone(packet))
+	return nil
+}
+
+// Broadcast 向所有服务器广播跨服消息，包括本服
+func (slf *Loopback) Broadcast(packet []byte) error {
+	for _, target := range slf.network.crosses.Slice() {
+		target.packetHandle(slf.serverId, bytes.Clone(packet))
+	}
+	return nil
+}
+
+// PushGroup 向特定分组中的所有服务器推送跨服消息
+func (slf *Loopback) PushGroup(group string, packet []byte) error {
+	for _, target := range slf.network.crosses.Slice() {
+		if matchGroups(target.groups, group) {
+			target.packetHandle(slf.serverId, bytes.Clone(packet))
+		}
+	}
+	return nil
+}
+
+// Request 向特定服务器发起跨服请求并等待响应
+func (slf *Loopback) Request(ctx context.Context, serverId int64, packet []byte) ([]byte, error) {
+	target, exist := slf.network.crosses.GetExist(serverId)
+	if !exist {
+		return nil, ErrServerNotFound
+	}
+	if target.requestHandle == nil {
+		return nil, ErrNoRequestHandle
+	}
+	var response = make(chan []byte, 1)
+	target.requestHandle(slf.serverId, bytes.Clone(packet), func(packet []byte) {
+		select {
+		case response <- bytes.Clone(packet):
+		default:
+		}
+	})
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case packet = <-response:
+		return packet, nil
+	}
+}
+
+func (slf *Loopback) Release() {
+	slf.network.crosses.Atom(func(m map[int64]*Loopback) {
+		if m[slf.serverId] == slf {
+			delete(m, slf.serverId)
+		}
+	})
+}
@@ -1,6 +1,7 @@
 package cross
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"github.com/kercylan98/minotaur/server"
@@ -31,14 +32,25 @@ func NewNats(url string, options ...NatsOption) *Nats {
 	return n
 }
 
+// Nats 基于 nats 实现的跨服中间件
+//   - 点对点消息主题为：{subject}_{serverId}
+//   - 请求主题为：{subject}.request.{serverId}，基于 nats 的 request/reply 实现
+//   - 广播主题为：{subject}.broadcast
+//   - 分组主题为：{subject}.group.{group}，分组可通过 WithNatsGroups 加入，支持 nats 通配符
 type Nats struct {
-	conn        *nats.Conn
-	url         string
-	subject     string
-	options     []nats.Option
-	messagePool *concurrent.Pool[*Message]
+	conn          *nats.Conn
+	url           string
+	subject       string
+	groups        []string
+	options       []nats.Option
+	messagePool   *concurrent.Pool[*Message]
+	serverId      int64
+	requestHandle func(serverId int64, packet []byte, reply func(packet []byte))
+	subscriptions []*nats.Subscription // 当前生效的订阅
 }
 
+// Init 初始化跨服中间件
+//   - 允许重复调用，再次调用时将取消之前的订阅后重新订阅，初始化失败时已建立的订阅将被取消
 func (slf *Nats) Init(server *server.Server, packetHandle func(serverId int64, packet []byte)) (err error) {
 	if slf.conn == nil {
 		if len(slf.options) == 0 {
@@ -58,7 +70,14 @@ func (slf *Nats) Init(server *server.Server, packetHandle func(serverId int64, p
 			return err
 		}
 	}
-	_, err = slf.conn.Subscribe(fmt.Sprintf("%s_%d", slf.subject, server.GetID()), func(msg *nats.Msg) {
+	slf.serverId = server.GetID()
+	slf.unsubscribe()
+	defer func() {
+		if err != nil {
+			slf.unsubscribe()
+		}
+	}()
+	var handle = func(msg *nats.Msg) {
 		message := slf.messagePool.Get()
 		defer slf.messagePool.Release(message)
 		if err := json.Unmarshal(msg.Data, &message); err != nil {
@@ -66,10 +85,59 @@ func (slf *Nats) Init(server *server.Server, packetHandle func(serverId int64, p
 			return
 		}
 		packetHandle(message.ServerId, message.Packet)
-	})
+	}
+	if err = slf.subscribe(fmt.Sprintf("%s_%d", slf.subject, slf.serverId), handle); err != nil {
+		return err
+	}
+	if err = slf.subscribe(slf.broadcastSubject(), handle); err != nil {
+		return err
+	}
+	for _, group := range slf.groups {
+		if err = slf.subscribe(slf.groupSubject(group), handle); err != nil {
+			return err
+		}
+	}
+	if slf.requestHandle != nil {
+		err = slf.subscribe(slf.requestSubject(slf.serverId), func(msg *nats.Msg) {
+			message := slf.messagePool.Get()
+			defer slf.messagePool.Release(message)
+			if err := json.Unmarshal(msg.Data, &message); err != nil {
+				log.Error(nasMark, log.Err(err))
+				return
+			}
+			slf.requestHandle(message.ServerId, message.Packet, func(packet []byte) {
+				if err := msg.Respond(packet); err != nil {
+					log.Error(nasMark, log.String("info", "respond"), log.Err(err))
+				}
+			})
+		})
+	}
 	return err
 }
 
+// subscribe 订阅特定主题，订阅将在 Init 失败或再次 Init 时被取消
+func (slf *Nats) subscribe(subject string, handle nats.MsgHandler) error {
+	subscription, err := slf.conn.Subscribe(subject, handle)
+	if err != nil {
+		return err
+	}
+	slf.subscriptions = append(slf.subscriptions, subscription)
+	return nil
+}
+
+// unsubscribe 取消所有订阅
+func (slf *Nats) unsubscribe() {
+	for _, subscription := range slf.subscriptions {
+		_ = subscription.Unsubscribe()
+	}
+	slf.subscriptions = nil
+}
+
+// SetRequestHandle 设置跨服请求的处理函数
+func (slf *Nats) SetRequestHandle(requestHandle func(serverId int64, packet []byte, reply func(packet []byte))) {
+	slf.requestHandle = requestHandle
+}
+
 func (slf *Nats) PushMessage(serverId int64, packet []byte) error {
 	message := slf.messagePool.Get()
 	defer slf.messagePool.Release(message)
@@ -82,6 +150,59 @@ func (slf *Nats) PushMessage(serverId int64, packet []byte) error {
 	return slf.conn.Publish(fmt.Sprintf("%s_%d", slf.subject, serverId), data)
 }
 
+// Broadcast 向所有服务器广播跨服消息，包括本服
+func (slf *Nats) Broadcast(packet []byte) error {
+	data, err := slf.marshal(packet)
+	if err != nil {
+		return err
+	}
+	return slf.conn.Publish(slf.broadcastSubject(), data)
+}
+
+// PushGroup 向特定分组中的所有服务器推送跨服消息
+//   - 订阅了与 group 匹配的通配符分组的服务器同样会收到消息
+func (slf *Nats) PushGroup(group string, packet []byte) error {
+	data, err := slf.marshal(packet)
+	if err != nil {
+		return err
+	}
+	return slf.conn.Publish(slf.groupSubject(group), data)
+}
+
+// Request 向特定服务器发起跨服请求并等待响应
+func (slf *Nats) Request(ctx context.Context, serverId int64, packet []byte) ([]byte, error) {
+	data, err := slf.marshal(packet)
+	if err != nil {
+		return nil, err
+	}
+	msg, err := slf.conn.RequestWithContext(ctx, slf.requestSubject(serverId), data)
+	if err != nil {
+		return nil, err
+	}
+	return msg.Data, nil
+}
+
 func (slf *Nats) Release() {
 	slf.conn.Close()
 }
+
+// marshal 将本服发出的数据包序列化为跨服消息
+func (slf *Nats) marshal(packet []byte) ([]byte, error) {
+	message := slf.messagePool.Get()
+	defer slf.messagePool.Release(message)
+	message.ServerId = slf.serverId
+	message.Packet = packet
+	return json.Marshal(message)
+}
+
+func (slf *Nats) requestSubject(serverId int64) string {
+	return fmt.Sprintf("%s.request.%d", slf.subject, serverId)
+}
+
+func (slf *Nats) broadcastSubject() string {
+	return slf.subject + ".broadcast"
+}
+
+func (slf *Nats) groupSubject(group string) string {
+	return slf.subject + ".group." + group
+}
@@ -26,3 +26,11 @@ func WithNatsConn(conn *nats.Conn) NatsOption {
 		n.conn = conn
 	}
 }
+
+// WithNatsGroups 加入特定的跨服分组，加入后将能够接收到通过 PushGroup 推送至该分组的消息
+//   - 分组名称支持 nats 通配符，例如 "zone.asia.*" 将接收 "zone.asia.1"、"zone.asia.2" 等分组的消息
+func WithNatsGroups(groups ...string) NatsOption {
+	return func(n *Nats) {
+		n.groups = append(n.groups, groups...)
+	}
+}
@@ -0,0 +1,80 @@
+package cross_test
+
+import (
+	"github.com/kercylan98/minotaur/server"
+	"github.com/kercylan98/minotaur/server/cross"
+	natsserver "github.com/nats-io/nats-server/v2/server"
+	"github.com/nats-io/nats.go"
+	. "github.com/smartystreets/goconvey/convey"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// runNatsServer 运行内嵌的 nats 服务器
+func runNatsServer(t *testing.T) *natsserver.Server {
+	ns, err := natsserver.NewServer(&natsserver.Options{Host: "127.0.0.1", Port: -1, NoLog: true, NoSigs: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	go ns.Start()
+	if !ns.ReadyForConnections(5 * time.Second) {
+		t.Fatal("nats server start timeout")
+	}
+	return ns
+}
+
+// waitSubscriptions 等待 nats 服务器中的订阅数量达到 expected
+func waitSubscriptions(ns *natsserver.Server, expected uint32) uint32 {
+	var deadline = time.Now().Add(time.Second)
+	for time.Now().Before(deadline) && ns.NumSubscriptions() != expected {
+		time.Sleep(10 * time.Millisecond)
+	}
+	return ns.NumSubscriptions()
+}
+
+func TestNats(t *testing.T) {
+	ns := runNatsServer(t)
+	defer ns.Shutdown()
+	testCross(t, "TestNats", func(groups ...string) server.Cross {
+		return cross.NewNats(ns.ClientURL(), cross.WithNatsGroups(groups...))
+	})
+}
+
+func TestNats_Init(t *testing.T) {
+	ns := runNatsServer(t)
+	defer ns.Shutdown()
+	srv := server.New(server.NetworkNone, server.WithCross("cross", 1, nil))
+	var base = ns.NumSubscriptions()
+
+	Convey("TestNats_Init", t, func() {
+		Convey("Reinit", func() {
+			var received atomic.Int32
+			n := cross.NewNats(ns.ClientURL())
+			defer n.Release()
+			for i := 0; i < 2; i++ {
+				So(n.Init(srv, func(serverId int64, packet []byte) {
+					received.Add(1)
+				}), ShouldBeNil)
+			}
+			So(waitSubscriptions(ns, base+2), ShouldEqual, base+2)
+
+			So(n.PushMessage(1, []byte("push")), ShouldBeNil)
+			So(n.Broadcast([]byte("broadcast")), ShouldBeNil)
+			time.Sleep(100 * time.Millisecond)
+			So(received.Load(), ShouldEqual, 2)
+		})
+
+		Convey("Failure", func() {
+			conn, err := nats.Connect(ns.ClientURL())
+			So(err, ShouldBeNil)
+			defer conn.Close()
+
+			n := cross.NewNats("", cross.WithNatsConn(conn), cross.WithNatsGroups("zone.asia", "zone europe"))
+			So(n.Init(srv, func(serverId int64, packet []byte) {}), ShouldEqual, nats.ErrBadSubject)
+			So(conn.NumSubscriptions(), ShouldEqual, 0)
+			So(conn.Flush(), ShouldBeNil)
+			So(waitSubscriptions(ns, base), ShouldEqual, base)
+		})
+	})
+}
@@ -0,0 +1,202 @@
+package cross
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"github.com/kercylan98/minotaur/server"
+	"github.com/kercylan98/minotaur/utils/concurrent"
+	"github.com/kercylan98/minotaur/utils/log"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// NewTCP 创建一个接入 TCPHub 的跨服中间件
+//   - hubAddr：集线器的地址
+//   - 与集线器断开连接后将自动重连，断开期间的跨服消息将推送失败
+func NewTCP(hubAddr string, options ...TCPOption) *TCP {
+	t := &TCP{
+		hubAddr:           hubAddr,
+		reconnectInterval: DefaultTCPReconnectInterval,
+		pending:           concurrent.NewBalanceMap[uint64, chan *tcpFrame](),
+	}
+	for _, option := range options {
+		option(t)
+	}
+	return t
+}
+
+// TCP 基于 TCP 集线器的跨服中间件
+type TCP struct {
+	hubAddr           string
+	groups            []string
+	reconnectInterval time.Duration
+	serverId          int64
+	conn              net.Conn
+	mutex             sync.Mutex
+	seq               atomic.Uint64
+	pending           *concurrent.BalanceMap[uint64, chan *tcpFrame]
+	closed            atomic.Bool
+	packetHandle      func(serverId int64, packet []byte)
+	requestHandle     func(serverId int64, packet []byte, reply func(packet []byte))
+}
+
+func (slf *TCP) Init(server *server.Server, packetHandle func(serverId int64, packet []byte)) error {
+	slf.serverId = server.GetID()
+	slf.packetHandle = packetHandle
+	return slf.connect()
+}
+
+// SetRequestHandle 设置跨服请求的处理函数
+func (slf *TCP) SetRequestHandle(requestHandle func(serverId int64, packet []byte, reply func(packet []byte))) {
+	slf.requestHandle = requestHandle
+}
+
+func (slf *TCP) PushMessage(serverId int64, packet []byte) error {
+	return slf.write(&tcpFrame{Op: tcpOpPush, From: slf.serverId, To: serverId, Packet: packet})
+}
+
+// Broadcast 向所有服务器广播跨服消息，包括本服
+func (slf *TCP) Broadcast(packet []byte) error {
+	return slf.write(&tcpFrame{Op: tcpOpBroadcast, From: slf.serverId, Packet: packet})
+}
+
+// PushGroup 向特定分组中的所有服务器推送跨服消息
+func (slf *TCP) PushGroup(group string, packet []byte) error {
+	return slf.write(&tcpFrame{Op: tcpOpGroup, From: slf.serverId, Group: group, Packet: packet})
+}
+
+// Request 向特定服务器发起跨服请求并等待响应
+func (slf *TCP) Request(ctx context.Context, serverId int64, packet []byte) ([]byte, error) {
+	var seq = slf.seq.Add(1)
+	var response = make(chan *tcpFrame, 1)
+	slf.pending.Set(seq, response)
+	defer slf.pending.Delete(seq)
+	if err := slf.write(&tcpFrame{Op: tcpOpRequest, From: slf.serverId, To: serverId, Seq: seq, Packet: packet}); err != nil {
+		return nil, err
+	}
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case frame := <-response:
+		if frame.Error != "" {
+			return nil, fmt.Errorf("%w: %s", ErrRequestFailed, frame.Error)
+		}
+		return frame.Packet, nil
+	}
+}
+
+func (slf *TCP) Release() {
+	if slf.closed.Swap(true) {
+		return
+	}
+	slf.mutex.Lock()
+	defer slf.mutex.Unlock()
+	if slf.conn != nil {
+		_ = slf.conn.Close()
+		slf.conn = nil
+	}
+}
+
+func (slf *TCP) write(frame *tcpFrame) error {
+	slf.mutex.Lock()
+	defer slf.mutex.Unlock()
+	if slf.conn == nil {
+		return ErrNotConnected
+	}
+	return writeTCPFrame(slf.conn, frame)
+}
+
+// connect 连接并注册到集线器
+func (slf *TCP) connect() error {
+	conn, err := net.DialTimeout("tcp", slf.hubAddr, DefaultTCPRegisterTimeout)
+	if err != nil {
+		return err
+	}
+	var reader = bufio.NewReader(conn)
+	_ = conn.SetDeadline(time.Now().Add(DefaultTCPRegisterTimeout))
+	if err = writeTCPFrame(conn, &tcpFrame{Op: tcpOpRegister, From: slf.serverId, Groups: slf.groups}); err != nil {
+		_ = conn.Close()
+		return err
+	}
+	frame, err := readTCPFrame(reader)
+	if err != nil {
+		_ = conn.Close()
+		return err
+	}
+	if frame.Op != tcpOpRegistered || frame.Error != "" {
+		_ = conn.Close()
+		return fmt.Errorf("%w: %s", ErrHubRegister, frame.Error)
+	}
+	_ = conn.SetDeadline(time.Time{})
+
+	slf.mutex.Lock()
+	if slf.closed.Load() {
+		slf.mutex.Unlock()
+		_ = conn.Close()
+		return ErrNotConnected
+	}
+	slf.conn = conn
+	slf.mutex.Unlock()
+	go slf.read(conn, reader)
+	return nil
+}
+
+// read 读取集线器转发的跨服消息，断开连接后将自动重连
+func (slf *TCP) read(conn net.Conn, reader *bufio.Reader) {
+	for {
+		frame, err := readTCPFrame(reader)
+		if err != nil {
+			break
+		}
+		switch frame.Op {
+		case tcpOpPush, tcpOpBroadcast, tcpOpGroup:
+			slf.packetHandle(frame.From, frame.Packet)
+		case tcpOpRequest:
+			slf.handleRequest(frame)
+		case tcpOpReply:
+			if response, exist := slf.pending.DeleteGetExist(frame.Seq); exist {
+				response <- frame
+			}
+		}
+	}
+
+	slf.mutex.Lock()
+	if slf.conn == conn {
+		slf.conn = nil
+	}
+	slf.mutex.Unlock()
+	_ = conn.Close()
+	slf.pending.ClearHandle(func(seq uint64, response chan *tcpFrame) {
+		response <- &tcpFrame{Op: tcpOpReply, Seq: seq, Error: ErrNotConnected.Error()}
+	})
+
+	for !slf.closed.Load() {
+		log.Warn(tcpMark, log.Int64("ServerID", slf.serverId), log.String("State", "Reconnect"), log.String("Hub", slf.hubAddr))
+		time.Sleep(slf.reconnectInterval)
+		if err := slf.connect(); err == nil {
+			return
+		}
+	}
+}
+
+func (slf *TCP) handleRequest(frame *tcpFrame) {
+	var reply = &tcpFrame{Op: tcpOpReply, From: slf.serverId, To: frame.From, Seq: frame.Seq}
+	if slf.requestHandle == nil {
+		reply.Error = ErrNoRequestHandle.Error()
+		_ = slf.write(reply)
+		return
+	}
+	var replied atomic.Bool
+	slf.requestHandle(frame.From, frame.Packet, func(packet []byte) {
+		if replied.Swap(true) {
+			return
+		}
+		reply.Packet = packet
+		if err := slf.write(reply); err != nil {
+			log.Error(tcpMark, log.String("info", "reply"), log.Err(err))
+		}
+	})
+}
@@ -0,0 +1,76 @@
+package cross
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"github.com/kercylan98/minotaur/server"
+	"io"
+	"net"
+	"time"
+)
+
+const (
+	tcpMark         = "Cross.TCP"
+	tcpHubMark      = "Cross.TCPHub"
+	tcpMaxFrameSize = 16 * 1024 * 1024
+)
+
+const (
+	DefaultTCPRegisterTimeout   = 5 * time.Second // 注册到集线器的超时时间
+	DefaultTCPReconnectInterval = time.Second     // 与集线器断开连接后的重连间隔
+	DefaultTCPHubWriteTimeout   = 5 * time.Second // 集线器向服务器转发跨服消息的写入超时时间
+)
+
+type tcpOp byte
+
+const (
+	tcpOpRegister   tcpOp = iota + 1 // 注册到集线器
+	tcpOpRegistered                  // 注册结果
+	tcpOpPush                        // 点对点消息
+	tcpOpBroadcast                   // 广播消息
+	tcpOpGroup                       // 分组消息
+	tcpOpRequest                     // 请求
+	tcpOpReply                       // 响应
+)
+
+// tcpFrame 跨服中间件与集线器之间传输的数据帧
+//   - 传输格式：[4 字节大端序长度][json 数据]
+type tcpFrame struct {
+	Op     tcpOp    `json:"op"`
+	From   int64    `json:"from,omitempty"`
+	To     int64    `json:"to,omitempty"`
+	Seq    uint64   `json:"seq,omitempty"`
+	Group  string   `json:"group,omitempty"`
+	Groups []string `json:"groups,omitempty"`
+	Packet []byte   `json:"packet,omitempty"`
+	Error  string   `json:"error,omitempty"`
+}
+
+func writeTCPFrame(conn net.Conn, frame *tcpFrame) error {
+	data, err := json.Marshal(frame)
+	if err != nil {
+		return err
+	}
+	var buf = make([]byte, 4+len(data))
+	binary.BigEndian.PutUint32(buf, uint32(len(data)))
+	copy(buf[4:], data)
+	_, err = conn.Write(buf)
+	return err
+}
+
+func readTCPFrame(reader io.Reader) (*tcpFrame, error) {
+	var header [4]byte
+	if _, err := io.ReadFull(reader, header[:]); err != nil {
+		return nil, err
+	}
+	var length = binary.BigEndian.Uint32(header[:])
+	if length > tcpMaxFrameSize {
+		return nil, server.ErrPacketTooLarge
+	}
+	var data = make([]byte, length)
+	if _, err := io.ReadFull(reader, data); err != nil {
+		return nil, err
+	}
+	var frame = new(tcpFrame)
+	return frame, json.Unmarshal(data, frame)
+}
@@ -0,0 +1,150 @@
+package cross
+
+import (
+	"bufio"
+	"errors"
+	"github.com/kercylan98/minotaur/utils/concurrent"
+	"github.com/kercylan98/minotaur/utils/log"
+	"net"
+	"sync"
+	"time"
+)
+
+// NewTCPHub 创建一个基于 TCP 的跨服集线器，配合 NewTCP 创建的跨服中间件使用，无需任何外部消息中间件
+//   - 集线器负责维护所有接入的服务器并对跨服消息进行转发
+//   - 适用于小规模部署的场景
+func NewTCPHub(addr string) *TCPHub {
+	return &TCPHub{
+		addr:  addr,
+		peers: concurrent.NewBalanceMap[int64, *tcpHubPeer](),
+	}
+}
+
+// TCPHub 基于 TCP 的跨服集线器
+type TCPHub struct {
+	addr     string
+	listener net.Listener
+	peers    *concurrent.BalanceMap[int64, *tcpHubPeer]
+}
+
+type tcpHubPeer struct {
+	conn   net.Conn
+	groups []string
+	mutex  sync.Mutex
+}
+
+// write 向服务器写入跨服消息，超过 DefaultTCPHubWriteTimeout 仍未写入时将断开该服务器的连接，避免阻塞其他服务器的转发
+func (slf *tcpHubPeer) write(frame *tcpFrame) {
+	slf.mutex.Lock()
+	defer slf.mutex.Unlock()
+	_ = slf.conn.SetWriteDeadline(time.Now().Add(DefaultTCPHubWriteTimeout))
+	if err := writeTCPFrame(slf.conn, frame); err != nil {
+		_ = slf.conn.Close()
+	}
+}
+
+// Start 开始侦听并处理跨服中间件的连接，该函数不会阻塞
+func (slf *TCPHub) Start() error {
+	listener, err := net.Listen("tcp", slf.addr)
+	if err != nil {
+		return err
+	}
+	slf.listener = listener
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				if !errors.Is(err, net.ErrClosed) {
+					log.Error(tcpHubMark, log.Err(err))
+				}
+				return
+			}
+			go slf.serve(conn)
+		}
+	}()
+	log.Info(tcpHubMark, log.String("Addr", listener.Addr().String()))
+	return nil
+}
+
+// Addr 获取集线器的侦听地址
+func (slf *TCPHub) Addr() net.Addr {
+	return slf.listener.Addr()
+}
+
+// Close 关闭集线器及所有接入的连接
+func (slf *TCPHub) Close() {
+	if slf.listener != nil {
+		_ = slf.listener.Close()
+	}
+	slf.peers.ClearHandle(func(serverId int64, peer *tcpHubPeer) {
+		_ = peer.conn.Close()
+	})
+}
+
+func (slf *TCPHub) serve(conn net.Conn) {
+	defer func() {
+		_ = conn.Close()
+	}()
+	var reader = bufio.NewReader(conn)
+	_ = conn.SetReadDeadline(time.Now().Add(DefaultTCPRegisterTimeout))
+	frame, err := readTCPFrame(reader)
+	if err != nil || frame.Op != tcpOpRegister {
+		return
+	}
+	_ = conn.SetReadDeadline(time.Time{})
+
+	var serverId = frame.From
+	var peer = &tcpHubPeer{conn: conn, groups: frame.Groups}
+	var exist bool
+	slf.peers.Atom(func(m map[int64]*tcpHubPeer) {
+		if _, exist = m[serverId]; !exist {
+			m[serverId] = peer
+		}
+	})
+	if exist {
+		peer.write(&tcpFrame{Op: tcpOpRegistered, Error: ErrServerIdExist.Error()})
+		return
+	}
+	defer slf.peers.Atom(func(m map[int64]*tcpHubPeer) {
+		if m[serverId] == peer {
+			delete(m, serverId)
+		}
+	})
+	peer.write(&tcpFrame{Op: tcpOpRegistered})
+	log.Info(tcpHubMark, log.Int64("ServerID", serverId), log.String("State", "Registered"))
+
+	for {
+		if frame, err = readTCPFrame(reader); err != nil {
+			log.Info(tcpHubMark, log.Int64("ServerID", serverId), log.String("State", "Disconnected"), log.Err(err))
+			return
+		}
+		frame.From = serverId
+		slf.route(peer, frame)
+	}
+}
+
+// route 对跨服消息进行转发
+func (slf *TCPHub) route(sender *tcpHubPeer, frame *tcpFrame) {
+	switch frame.Op {
+	case tcpOpPush, tcpOpReply:
+		if target, exist := slf.peers.GetExist(frame.To); exist {
+			target.write(frame)
+		}
+	case tcpOpRequest:
+		if target, exist := slf.peers.GetExist(frame.To); exist {
+			target.write(frame)
+		} else {
+			sender.write(&tcpFrame{Op: tcpOpReply, To: frame.From, Seq: frame.Seq, Error: ErrServerNotFound.Error()})
+		}
+	case tcpOpBroadcast:
+		for _, target := range slf.peers.Slice() {
+			target.write(frame)
+		}
+	case tcpOpGroup:
+		for _, target := range slf.peers.Slice() {
+			if matchGroups(target.groups, frame.Group) {
+				target.write(frame)
+			}
+		}
+	}
+}
@@ -0,0 +1,23 @@
+package cross
+
+import "time"
+
+type TCPOption func(t *TCP)
+
+// WithTCPGroups 加入特定的跨服分组，加入后将能够接收到通过 PushGroup 推送至该分组的消息
+//   - 分组名称支持与 nats 一致的通配符，例如 "zone.asia.*"
+func WithTCPGroups(groups ...string) TCPOption {
+	return func(t *TCP) {
+		t.groups = append(t.groups, groups...)
+	}
+}
+
+// WithTCPReconnectInterval 设置与集线器断开连接后的重连间隔
+//   - 默认为 DefaultTCPReconnectInterval
+func WithTCPReconnectInterval(interval time.Duration) TCPOption {
+	return func(t *TCP) {
+		if interval > 0 {
+			t.reconnectInterval = interval
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package server
+
+import (
+	"github.com/kercylan98/minotaur/utils/log"
+	"sync"
+	"time"
+)
+
+// IsDraining 服务器是否正在排空连接
+//   - 排空期间服务器将不再接受新的连接
+func (slf *Server) IsDraining() bool {
+	return slf.draining.Load()
+}
+
+// accepting 服务器是否允许接受新的连接
+func (slf *Server) accepting() bool {
+	return !slf.draining.Load() && !slf.isShutdown.Load()
+}
+
+// drain 排空服务器中的所有连接
+//   - 停止接受新的连接后，对每个连接执行 ConnectionDrainEvent，等待写入队列中的数据发送完毕后以 ErrServerShutdown 关闭连接
+//   - 整个过程不会超过 WithDrain 设置的排空超时时间
+func (slf *Server) drain() {
+	if slf.drainTimeout <= 0 || !slf.draining.CompareAndSwap(false, true) {
+		return
+	}
+	var deadline = time.Now().Add(slf.drainTimeout)
+	var conns = slf.online.Map()
+	log.Info("Server", log.Any("network", slf.network), log.String("listen", slf.addr),
+		log.String("action", "drain"), log.Int("conn", len(conns)), log.String("timeout", slf.drainTimeout.String()))
+
+	for _, conn := range conns {
+		slf.OnConnectionDrainEvent(conn)
+	}
+	for _, conn := range conns {
+		for conn.GetWriteQueueMetrics().QueuedPackets > 0 && time.Now().Before(deadline) {
+			time.Sleep(10 * time.Millisecond)
+		}
+		conn.closeWithReason(ErrServerShutdown)
+	}
+	for slf.online.Size() > 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// drainMultiple 并行排空多个服务器中的连接
+func drainMultiple(servers []*Server) {
+	var wait sync.WaitGroup
+	for _, server := range servers {
+		wait.Add(1)
+		go func(server *Server) {
+			defer wait.Done()
+			server.drain()
+		}(server)
+	}
+	wait.Wait()
+}
@@ -0,0 +1,51 @@
+package server_test
+
+import (
+	"github.com/gorilla/websocket"
+	"github.com/kercylan98/minotaur/server"
+	. "github.com/smartystreets/goconvey/convey"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWithDrain(t *testing.T) {
+	Convey("TestWithDrain", t, func() {
+		var addr = freeAddr()
+		var url = "ws://" + addr + "/drain-" + strings.ReplaceAll(addr, ":", "-")
+		srv := server.New(server.NetworkWebsocket, server.WithDrain(5*time.Second))
+		var opened, drained, closed = make(chan string, 1), make(chan string, 1), make(chan any, 1)
+		var stopped = make(chan struct{})
+		srv.RegConnectionOpenedEvent(func(srv *server.Server, conn *server.Conn) {
+			opened <- conn.GetID()
+		})
+		srv.RegConnectionDrainEvent(func(srv *server.Server, conn *server.Conn) {
+			drained <- conn.GetID()
+		})
+		srv.RegConnectionClosedEvent(func(srv *server.Server, conn *server.Conn, err any) {
+			closed <- err
+		})
+		srv.RegStopEvent(func(srv *server.Server) {
+			close(stopped)
+		})
+		stop := runServer(srv, strings.TrimPrefix(url, "ws://"))
+		defer stop()
+		waitListen(addr)
+
+		ws, _, err := websocket.DefaultDialer.Dial(url, nil)
+		So(err, ShouldBeNil)
+		defer ws.Close()
+		var id = <-opened
+
+		var now = time.Now()
+		srv.OnConsoleCommandEvent("shutdown")
+		select {
+		case <-stopped:
+		case <-time.After(2 * time.Second):
+			t.Fatal("shutdown triggered by a message waited for the whole drain timeout")
+		}
+		So(time.Since(now), ShouldBeLessThan, time.Second)
+		So(<-drained, ShouldEqual, id)
+		So(<-closed, ShouldEqual, server.ErrServerShutdown)
+	})
+}
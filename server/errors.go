@@ -10,5 +10,31 @@ var (
 	ErrNetworkIncompatibleHttp     = errors.New("the current network mode is not compatible with NetworkHttp")
 	ErrWebsocketIllegalMessageType = errors.New("illegal message type")
 	ErrNoSupportCross              = errors.New("the server does not support GetID or PushCrossMessage, please use the WithCross option to create the server")
+	ErrCrossInit                   = errors.New("cross init failed")
+	ErrCrossNotAdvanced            = errors.New("the cross does not support Broadcast, PushGroup or Request, it should implement the CrossAdvanced interface")
+	ErrNoSupportRegistry           = errors.New("the server does not support Registry, please use the WithRegistry option to create the server")
+	ErrRegistryAddress             = errors.New("the service instance address can not be empty, it should be an address that other servers can connect to")
 	ErrNoSupportTicker             = errors.New("the server does not support Ticker, please use the WithTicker option to create the server")
+	ErrPacketTooLarge              = errors.New("packet too large")
+	ErrPacketCodecLengthFieldSize  = errors.New("packet codec length field size only supports 2 or 4")
+	ErrPacketCodecDelimiter        = errors.New("packet codec delimiter can not be empty")
+	ErrRPCNotEnabled               = errors.New("the server does not support Call, please use the WithRPC option to create the server")
+	ErrRPCIllegalFrame             = errors.New("illegal rpc frame")
+	ErrConnClosed                  = errors.New("connection closed")
+	ErrSessionTakenOver            = errors.New("connection transport closed due to session taken over by a new connection")
+	ErrConnWriteQueueFull          = errors.New("connection write queue is full")
+	ErrConnectionRateLimited       = errors.New("connection closed due to exceeding the rate limit")
+	ErrConnectionLimitExceeded     = errors.New("the number of connections from the ip exceeds the limit")
+	ErrConnectionIdleTimeout       = errors.New("connection closed due to idle timeout")
+	ErrServerShutdown              = errors.New("connection closed due to server shutdown")
+	ErrAdminConsoleNotEnabled      = errors.New("admin console is not enabled, please use WithAdminConsole to enable it")
+	ErrAdminConsoleToken           = errors.New("admin console token can not be empty unless it listens on a unix socket or loopback address")
+	ErrAdminCommandNotFound        = errors.New("admin command not found")
+	ErrAdminCommandArgs            = errors.New("invalid admin command arguments")
+	ErrMessageRecordIllegal        = errors.New("illegal message record")
+	ErrReplayNetwork               = errors.New("message replay only supports NetworkNone")
+	ErrMessagePanic                = errors.New("message handler panic")
+	ErrHotUpgrade                  = errors.New("hot upgrade failed")
+	ErrHotUpgradeInProgress        = errors.New("hot upgrade is in progress")
+	ErrHotUpgradeNotEnabled        = errors.New("no running server supports hot upgrade, please use the WithHotUpgrade option to create the server")
 )
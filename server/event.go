@@ -17,6 +17,7 @@ type ConnectionReceivePacketEventHandle func(srv *Server, conn *Conn, packet Pac
 type ConnectionOpenedEventHandle func(srv *Server, conn *Conn)
 type ConnectionClosedEventHandle func(srv *Server, conn *Conn, err any)
 type ReceiveCrossPacketEventHandle func(srv *Server, senderServerId int64, packet []byte)
+type ReceiveCrossRequestEventHandle func(srv *Server, senderServerId int64, packet []byte, reply func(packet []byte))
 type MessageErrorEventHandle func(srv *Server, message *Message, err error)
 type MessageLowExecEventHandle func(srv *Server, message *Message, cost time.Duration)
 type ConsoleCommandEventHandle func(srv *Server)
@@ -25,6 +26,12 @@ type ConnectionWritePacketBeforeEventHandle func(srv *Server, conn *Conn, packet
 type ShuntChannelCreatedEventHandle func(srv *Server, guid int64)
 type ShuntChannelClosedEventHandle func(srv *Server, guid int64)
 type ConnectionPacketPreprocessEventHandle func(srv *Server, conn *Conn, packet []byte, abort func(), usePacket func(newPacket []byte))
+type ConnectionReceiveRequestEventHandle func(srv *Server, conn *Conn, packet Packet, reply func(packet Packet))
+type SessionResumedEventHandle func(srv *Server, conn *Conn)
+type SessionExpiredEventHandle func(srv *Server, conn *Conn)
+type ConnectionRateLimitedEventHandle func(srv *Server, conn *Conn, action RateLimitAction)
+type ConnectionLimitExceededEventHandle func(srv *Server, ip string)
+type ConnectionDrainEventHandle func(srv *Server, conn *Conn)
 
 type event struct {
 	*Server
@@ -35,6 +42,7 @@ type event struct {
 	connectionOpenedEventHandles           []ConnectionOpenedEventHandle
 	connectionClosedEventHandles           []ConnectionClosedEventHandle
 	receiveCrossPacketEventHandles         []ReceiveCrossPacketEventHandle
+	receiveCrossRequestEventHandles        []ReceiveCrossRequestEventHandle
 	messageErrorEventHandles               []MessageErrorEventHandle
 	messageLowExecEventHandles             []MessageLowExecEventHandle
 	connectionOpenedAfterEventHandles      []ConnectionOpenedAfterEventHandle
@@ -42,6 +50,12 @@ type event struct {
 	shuntChannelCreatedEventHandles        []ShuntChannelCreatedEventHandle
 	shuntChannelClosedEventHandles         []ShuntChannelClosedEventHandle
 	connectionPacketPreprocessEventHandles []ConnectionPacketPreprocessEventHandle
+	connectionReceiveRequestEventHandles   []ConnectionReceiveRequestEventHandle
+	sessionResumedEventHandles             []SessionResumedEventHandle
+	sessionExpiredEventHandles             []SessionExpiredEventHandle
+	connectionRateLimitedEventHandles      []ConnectionRateLimitedEventHandle
+	connectionLimitExceededEventHandles    []ConnectionLimitExceededEventHandle
+	connectionDrainEventHandles            []ConnectionDrainEventHandle
 
 	consoleCommandEventHandles        map[string][]ConsoleCommandEventHandle
 	consoleCommandEventHandleInitOnce sync.Once
@@ -62,9 +76,13 @@ func (slf *event) OnStopEvent() {
 // RegConsoleCommandEvent 控制台收到指令时将立即执行被注册的事件处理函数
 //   - 默认将注册 "exit", "quit", "close", "shutdown", "EXIT", "QUIT", "CLOSE", "SHUTDOWN" 指令作为关闭服务器的指令
 //   - 可通过注册默认指令进行默认行为的覆盖
+//   - 当通过 WithAdminConsole 开启管理控制台时，将不再从标准输入读取指令，而是通过管理控制台执行
 func (slf *event) RegConsoleCommandEvent(command string, handle ConsoleCommandEventHandle) {
 	slf.consoleCommandEventHandleInitOnce.Do(func() {
 		slf.consoleCommandEventHandles = map[string][]ConsoleCommandEventHandle{}
+		if slf.Server.adminConsole != nil {
+			return
+		}
 		go func() {
 			for {
 				var input string
@@ -84,7 +102,7 @@ func (slf *event) OnConsoleCommandEvent(command string) {
 			switch command {
 			case "exit", "quit", "close", "shutdown", "EXIT", "QUIT", "CLOSE", "SHUTDOWN":
 				log.Info("Console", log.String("Receive", command), log.String("Action", "Shutdown"))
-				slf.Server.shutdown(nil)
+				slf.Server.shutdownFromMessage(nil)
 				return
 			}
 			log.Warn("Server", log.String("Command", "unregistered"))
@@ -138,12 +156,42 @@ func (slf *event) RegConnectionClosedEvent(handle ConnectionClosedEventHandle) {
 }
 
 func (slf *event) OnConnectionClosedEvent(conn *Conn, err any) {
-	PushSystemMessage(slf.Server, func() {
+	pushConnSystemMessage(slf.Server, func() {
+		if len(conn.acquiredIP) > 0 {
+			slf.Server.releaseIP(conn.acquiredIP)
+			conn.acquiredIP = ""
+		}
+		if slf.Server.isLink(conn) {
+			conn.Close()
+			slf.Server.linkClosed(conn, err)
+			return
+		}
+		conn, valid := slf.Server.resolveSession(conn)
+		if !valid {
+			return
+		}
+		if conn.closeReason != nil {
+			err = conn.closeReason
+		}
+		slf.Server.metrics.recordConnClosed()
+		if slf.Server.recorder != nil {
+			slf.Server.recorder.recordConnClosed(conn)
+		}
 		for _, handle := range slf.connectionClosedEventHandles {
 			handle(slf.Server, conn, err)
 		}
+		if conn.token != "" && !conn.closed.Load() {
+			conn.closeReason = nil
+			conn.detach()
+			slf.Server.online.Delete(conn.GetID())
+			slf.Server.expireSession(conn)
+			return
+		}
 		conn.Close()
 		slf.Server.online.Delete(conn.GetID())
+		if conn.token != "" {
+			slf.Server.sessions.Delete(conn.token)
+		}
 	}, "ConnectionClosedEvent")
 }
 
@@ -157,14 +205,35 @@ func (slf *event) RegConnectionOpenedEvent(handle ConnectionOpenedEventHandle) {
 }
 
 func (slf *event) OnConnectionOpenedEvent(conn *Conn) {
-	PushSystemMessage(slf.Server, func() {
-		slf.Server.online.Set(conn.GetID(), conn)
-		for _, handle := range slf.connectionOpenedEventHandles {
-			handle(slf.Server, conn)
-		}
+	pushConnSystemMessage(slf.Server, func() {
+		slf.onConnectionOpened(conn)
 	}, "ConnectionOpenedEvent")
 }
 
+// OnConnectionResumeOrOpenedEvent 尝试通过会话令牌恢复会话，恢复失败时将作为新的连接执行 ConnectionOpenedEvent
+func (slf *event) OnConnectionResumeOrOpenedEvent(conn *Conn, token string) {
+	pushConnSystemMessage(slf.Server, func() {
+		if _, resumed := slf.Server.ResumeSession(conn, token); !resumed {
+			slf.onConnectionOpened(conn)
+		}
+	}, "ConnectionResumeOrOpenedEvent")
+}
+
+func (slf *event) onConnectionOpened(conn *Conn) {
+	if slf.Server.isLink(conn) {
+		return
+	}
+	slf.Server.metrics.recordConnOpened()
+	if slf.Server.recorder != nil {
+		slf.Server.recorder.recordConnOpened(conn)
+	}
+	slf.Server.bindSession(conn)
+	slf.Server.online.Set(conn.GetID(), conn)
+	for _, handle := range slf.connectionOpenedEventHandles {
+		handle(slf.Server, conn)
+	}
+}
+
 // RegConnectionReceivePacketEvent 在接收到数据包时将立刻执行被注册的事件处理函数
 func (slf *event) RegConnectionReceivePacketEvent(handle ConnectionReceivePacketEventHandle) {
 	if slf.network == NetworkHttp {
@@ -192,7 +261,22 @@ func (slf *event) OnReceiveCrossPacketEvent(serverId int64, packet []byte) {
 	}
 }
 
+// RegReceiveCrossRequestEvent 在接收到跨服请求时将立即执行被注册的事件处理函数
+//   - 仅在跨服中间件实现了 CrossAdvanced 接口时生效
+//   - 通过 reply 函数对请求进行响应，允许在异步流程中调用
+func (slf *event) RegReceiveCrossRequestEvent(handle ReceiveCrossRequestEventHandle) {
+	slf.receiveCrossRequestEventHandles = append(slf.receiveCrossRequestEventHandles, handle)
+	log.Info("Server", log.String("RegEvent", runtimes.CurrentRunningFuncName()), log.String("handle", reflect.TypeOf(handle).String()))
+}
+
+func (slf *event) OnReceiveCrossRequestEvent(serverId int64, packet []byte, reply func(packet []byte)) {
+	for _, handle := range slf.receiveCrossRequestEventHandles {
+		handle(slf.Server, serverId, packet, reply)
+	}
+}
+
 // RegMessageErrorEvent 在处理消息发生错误时将立即执行被注册的事件处理函数
+//   - 处理消息时发生的非 error 类型的 panic 将被包装为 ErrMessagePanic
 func (slf *event) RegMessageErrorEvent(handle MessageErrorEventHandle) {
 	slf.messageErrorEventHandles = append(slf.messageErrorEventHandles, handle)
 	log.Info("Server", log.String("RegEvent", runtimes.CurrentRunningFuncName()), log.String("handle", reflect.TypeOf(handle).String()))
@@ -312,6 +396,108 @@ func (slf *event) OnConnectionPacketPreprocessEvent(conn *Conn, packet []byte, u
 	return abort
 }
 
+// RegConnectionReceiveRequestEvent 在接收到请求帧时将立刻执行被注册的事件处理函数
+//   - 仅在通过 WithRPC 开启请求/响应模式时生效
+//   - 通过 reply 函数对请求进行响应，响应将携带与请求相同的序列号，允许在异步流程中调用
+func (slf *event) RegConnectionReceiveRequestEvent(handle ConnectionReceiveRequestEventHandle) {
+	if slf.network == NetworkHttp {
+		panic(ErrNetworkIncompatibleHttp)
+	}
+	slf.connectionReceiveRequestEventHandles = append(slf.connectionReceiveRequestEventHandles, handle)
+	log.Info("Server", log.String("RegEvent", runtimes.CurrentRunningFuncName()), log.String("handle", reflect.TypeOf(handle).String()))
+}
+
+func (slf *event) OnConnectionReceiveRequestEvent(conn *Conn, seq uint32, packet Packet) {
+	var reply = func(packet Packet) {
+		conn.reply(seq, packet)
+	}
+	for _, handle := range slf.connectionReceiveRequestEventHandles {
+		handle(slf.Server, conn, packet, reply)
+	}
+}
+
+// RegSessionResumedEvent 在会话恢复后将立刻执行被注册的事件处理函数
+//   - 此时连接已接管新的传输层，连接数据及未发送的数据包均已保留
+func (slf *event) RegSessionResumedEvent(handle SessionResumedEventHandle) {
+	slf.sessionResumedEventHandles = append(slf.sessionResumedEventHandles, handle)
+	log.Info("Server", log.String("RegEvent", runtimes.CurrentRunningFuncName()), log.String("handle", reflect.TypeOf(handle).String()))
+}
+
+func (slf *event) OnSessionResumedEvent(conn *Conn) {
+	for _, handle := range slf.sessionResumedEventHandles {
+		handle(slf.Server, conn)
+	}
+}
+
+// RegSessionExpiredEvent 在会话保持时间结束且未恢复时将立刻执行被注册的事件处理函数
+//   - 此时连接已被彻底关闭，通常用于释放玩家数据等资源
+func (slf *event) RegSessionExpiredEvent(handle SessionExpiredEventHandle) {
+	slf.sessionExpiredEventHandles = append(slf.sessionExpiredEventHandles, handle)
+	log.Info("Server", log.String("RegEvent", runtimes.CurrentRunningFuncName()), log.String("handle", reflect.TypeOf(handle).String()))
+}
+
+func (slf *event) OnSessionExpiredEvent(conn *Conn) {
+	for _, handle := range slf.sessionExpiredEventHandles {
+		handle(slf.Server, conn)
+	}
+}
+
+// RegConnectionRateLimitedEvent 在连接接收数据包超出 WithConnectionRateLimit 设置的速率时将立刻执行被注册的事件处理函数
+//   - 连接处于受限状态期间仅会触发一次，直到有数据包再次被允许处理后重新计算
+func (slf *event) RegConnectionRateLimitedEvent(handle ConnectionRateLimitedEventHandle) {
+	slf.connectionRateLimitedEventHandles = append(slf.connectionRateLimitedEventHandles, handle)
+	log.Info("Server", log.String("RegEvent", runtimes.CurrentRunningFuncName()), log.String("handle", reflect.TypeOf(handle).String()))
+}
+
+func (slf *event) OnConnectionRateLimitedEvent(conn *Conn, action RateLimitAction) {
+	if len(slf.connectionRateLimitedEventHandles) == 0 {
+		return
+	}
+	PushSystemMessage(slf.Server, func() {
+		for _, handle := range slf.connectionRateLimitedEventHandles {
+			handle(slf.Server, conn, action)
+		}
+	}, "ConnectionRateLimitedEvent")
+}
+
+// RegConnectionLimitExceededEvent 在 IP 的连接数量超出 WithConnectionLimitPerIP 设置的上限而被拒绝时将立刻执行被注册的事件处理函数
+func (slf *event) RegConnectionLimitExceededEvent(handle ConnectionLimitExceededEventHandle) {
+	slf.connectionLimitExceededEventHandles = append(slf.connectionLimitExceededEventHandles, handle)
+	log.Info("Server", log.String("RegEvent", runtimes.CurrentRunningFuncName()), log.String("handle", reflect.TypeOf(handle).String()))
+}
+
+func (slf *event) OnConnectionLimitExceededEvent(ip string) {
+	if len(slf.connectionLimitExceededEventHandles) == 0 {
+		return
+	}
+	PushSystemMessage(slf.Server, func() {
+		for _, handle := range slf.connectionLimitExceededEventHandles {
+			handle(slf.Server, ip)
+		}
+	}, "ConnectionLimitExceededEvent")
+}
+
+// RegConnectionDrainEvent 在服务器关闭排空连接时，将在连接被关闭前立刻执行被注册的事件处理函数
+//   - 仅在通过 WithDrain 开启排空时生效
+//   - 通常用于向客户端发送告别数据包，在此期间写入的数据包将在连接关闭前尽可能发送完毕
+//   - 该事件将在关闭服务器的协程中直接执行，而非作为服务器消息执行
+func (slf *event) RegConnectionDrainEvent(handle ConnectionDrainEventHandle) {
+	slf.connectionDrainEventHandles = append(slf.connectionDrainEventHandles, handle)
+	log.Info("Server", log.String("RegEvent", runtimes.CurrentRunningFuncName()), log.String("handle", reflect.TypeOf(handle).String()))
+}
+
+func (slf *event) OnConnectionDrainEvent(conn *Conn) {
+	defer func() {
+		if err := recover(); err != nil {
+			log.Error("Server", log.String("OnConnectionDrainEvent", fmt.Sprintf("%v", err)))
+			debug.PrintStack()
+		}
+	}()
+	for _, handle := range slf.connectionDrainEventHandles {
+		handle(slf.Server, conn)
+	}
+}
+
 func (slf *event) check() {
 	switch slf.network {
 	case NetworkHttp, NetworkGRPC, NetworkNone:
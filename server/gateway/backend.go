@@ -0,0 +1,159 @@
+package gateway
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/gorilla/websocket"
+	"github.com/kercylan98/minotaur/server"
+	"github.com/kercylan98/minotaur/utils/concurrent"
+	"github.com/kercylan98/minotaur/utils/log"
+	"sync/atomic"
+)
+
+// NewBackend 创建网关后端，用于在端点服务器中将网关转发的流量拆分为每个客户端独立的虚拟连接
+//   - 端点服务器中的所有非虚拟连接都将被视为与网关之间的连接，这些连接不会触发任何连接相关的事件
+//   - 每个客户端都将拥有独立的虚拟连接，并像直连的客户端一样触发 ConnectionOpenedEvent、ConnectionReceivePacketEvent 及 ConnectionClosedEvent
+//   - 虚拟连接写入的数据将通过网关返回客户端，关闭虚拟连接时网关将断开客户端的连接
+//   - 将自动响应网关的健康探测及会话附加控制帧
+//   - 当网关使用 NewTCPTransport 或 NewKCPTransport 时，端点服务器需要通过 server.WithPacketCodec 使用 TransportCodec
+func NewBackend(srv *server.Server) *Backend {
+	backend := &Backend{
+		srv:   srv,
+		conns: concurrent.NewBalanceMap[string, *backendConn](),
+	}
+	srv.RegConnectionPacketPreprocessEvent(backend.onConnectionPacketPreprocess)
+	srv.UseVirtualConnEvents(backend.onLinkClosed)
+	return backend
+}
+
+// Backend 网关后端
+type Backend struct {
+	srv   *server.Server
+	conns *concurrent.BalanceMap[string, *backendConn] // 客户端连接 ID -> 虚拟连接
+}
+
+// backendConn 网关后端的虚拟连接
+type backendConn struct {
+	conn *server.Conn                // 虚拟连接
+	link atomic.Pointer[server.Conn] // 最近一次转发该客户端数据的网关连接
+}
+
+// GetConn 获取特定客户端连接 ID 对应的虚拟连接
+func (slf *Backend) GetConn(connID string) *server.Conn {
+	if bc, exist := slf.conns.GetExist(connID); exist {
+		return bc.conn
+	}
+	return nil
+}
+
+// bind 将客户端连接绑定至网关连接，当客户端连接不存在时将创建虚拟连接
+func (slf *Backend) bind(link *server.Conn, connID, ip string) *server.Conn {
+	var bc *backendConn
+	var created bool
+	slf.conns.Atom(func(m map[string]*backendConn) {
+		var exist bool
+		if bc, exist = m[connID]; exist {
+			return
+		}
+		created = true
+		bc = new(backendConn)
+		bc.conn = server.NewVirtualConn(slf.srv, connID, ip, func(packet server.Packet) error {
+			var link = bc.link.Load()
+			if link == nil {
+				return server.ErrConnClosed
+			}
+			return writeLink(link, Packet{ConnID: connID, WebsocketType: packet.WebsocketType, Data: packet.Data})
+		}, func() {
+			slf.unbind(connID, true, nil)
+		})
+		m[connID] = bc
+	})
+	bc.link.Store(link)
+	if created {
+		slf.srv.OnConnectionOpenedEvent(bc.conn)
+	}
+	return bc.conn
+}
+
+// unbind 解除客户端连接的绑定，并触发虚拟连接的 ConnectionClosedEvent
+//   - notify 为 true 时将通知网关断开客户端的连接
+func (slf *Backend) unbind(connID string, notify bool, reason any) {
+	bc, exist := slf.conns.DeleteGetExist(connID)
+	if !exist {
+		return
+	}
+	if link := bc.link.Swap(nil); notify && link != nil {
+		_ = writeLink(link, Packet{Kind: PacketKindDisconnect, ConnID: connID})
+	}
+	slf.srv.OnConnectionClosedEvent(bc.conn, reason)
+}
+
+// relay 获取已绑定的客户端连接，并将其转发数据所使用的网关连接更新为 link
+//   - 客户端连接不存在时返回 nil，仅会话建立及附加控制帧能够创建虚拟连接
+func (slf *Backend) relay(link *server.Conn, connID string) *server.Conn {
+	bc, exist := slf.conns.GetExist(connID)
+	if !exist {
+		return nil
+	}
+	bc.link.Store(link)
+	return bc.conn
+}
+
+// writeLink 通过网关连接写入网关数据包，无法编码的数据包将被丢弃并返回错误
+func writeLink(link *server.Conn, packet Packet) error {
+	data, err := MarshalPacket(packet)
+	if err != nil {
+		log.Warn("GatewayBackend", log.String("link", link.GetID()), log.String("conn", packet.ConnID), log.Err(err))
+		return err
+	}
+	link.Write(server.Packet{WebsocketType: websocket.BinaryMessage, Data: data})
+	return nil
+}
+
+// onConnectionPacketPreprocess 拦截来自网关的数据包并拆分至虚拟连接
+func (slf *Backend) onConnectionPacketPreprocess(srv *server.Server, conn *server.Conn, packet []byte, abort func(), usePacket func(newPacket []byte)) {
+	if conn.IsVirtual() {
+		return
+	}
+	abort()
+	p, err := UnmarshalPacket(packet[:len(packet)-1])
+	if err != nil {
+		log.Warn("GatewayBackend", log.String("link", conn.GetID()), log.Err(err))
+		return
+	}
+	switch p.Kind {
+	case PacketKindData:
+		// 客户端断开后仍在途中的数据包将被丢弃，并通知网关该客户端已不存在
+		target := slf.relay(conn, p.ConnID)
+		if target == nil {
+			_ = writeLink(conn, Packet{Kind: PacketKindDisconnect, ConnID: p.ConnID})
+			return
+		}
+		target.ReceiveVirtualPacket(server.Packet{WebsocketType: p.WebsocketType, Data: bytes.Clone(p.Data)})
+	case PacketKindConnect:
+		slf.bind(conn, p.ConnID, string(p.Data))
+	case PacketKindAttach:
+		var metadata AttachMetadata
+		_ = json.Unmarshal(p.Data, &metadata)
+		slf.bind(conn, p.ConnID, metadata.IP)
+		_ = writeLink(conn, Packet{Kind: PacketKindAttachAck, ConnID: p.ConnID})
+	case PacketKindDisconnect:
+		slf.unbind(p.ConnID, false, ErrClientDisconnected)
+	case PacketKindPing:
+		_ = writeLink(conn, Packet{Kind: PacketKindPong, Data: p.Data})
+	}
+}
+
+// onLinkClosed 网关连接断开时关闭通过该连接转发的所有虚拟连接
+func (slf *Backend) onLinkClosed(conn *server.Conn, err any) {
+	var ids []string
+	slf.conns.Range(func(id string, bc *backendConn) bool {
+		if bc.link.Load() == conn {
+			ids = append(ids, id)
+		}
+		return false
+	})
+	for _, id := range ids {
+		slf.unbind(id, false, err)
+	}
+}
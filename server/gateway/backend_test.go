@@ -0,0 +1,120 @@
+package gateway_test
+
+import (
+	"github.com/gorilla/websocket"
+	"github.com/kercylan98/minotaur/server"
+	gateway2 "github.com/kercylan98/minotaur/server/gateway"
+	"testing"
+	"time"
+)
+
+func TestNewBackend(t *testing.T) {
+	type event struct {
+		kind string
+		conn *server.Conn
+		data string
+		err  any
+	}
+	srv := server.New(server.NetworkWebsocket)
+	backend := gateway2.NewBackend(srv)
+	var events = make(chan event, 16)
+	srv.RegConnectionOpenedEvent(func(srv *server.Server, conn *server.Conn) {
+		events <- event{kind: "opened", conn: conn}
+	})
+	srv.RegConnectionReceivePacketEvent(func(srv *server.Server, conn *server.Conn, packet server.Packet) {
+		events <- event{kind: "receive", conn: conn, data: string(packet.Data)}
+		if string(packet.Data) == "close" {
+			conn.Close()
+			return
+		}
+		conn.Write(packet)
+	})
+	srv.RegConnectionClosedEvent(func(srv *server.Server, conn *server.Conn, err any) {
+		events <- event{kind: "closed", conn: conn, err: err}
+	})
+	addr, stop := runEndpointServer(t, srv, "/backend")
+	defer stop()
+
+	var received = make(chan gateway2.Packet, 16)
+	transport := gateway2.NewWebsocketTransport("ws://" + addr)
+	if err := transport.Run(func(data []byte) {
+		p, err := gateway2.UnmarshalPacket(data)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		received <- p
+	}, func(err any) {}); err != nil {
+		t.Fatal(err)
+	}
+
+	var next = func(kind, connID string) event {
+		select {
+		case e := <-events:
+			if e.kind != kind || e.conn.GetID() != connID {
+				t.Fatalf("expected %s event of %s, got %s event of %s", kind, connID, e.kind, e.conn.GetID())
+			}
+			if !e.conn.IsVirtual() {
+				t.Fatalf("%s event of %s fired on a non-virtual conn", kind, connID)
+			}
+			return e
+		case <-time.After(time.Second * 5):
+			t.Fatalf("wait %s event of %s timeout", kind, connID)
+		}
+		return event{}
+	}
+
+	transport.Write("client-a", mustMarshalPacket(gateway2.Packet{Kind: gateway2.PacketKindConnect, ConnID: "client-a", Data: []byte("10.0.0.1")}))
+	if e := next("opened", "client-a"); e.conn.GetIP() != "10.0.0.1" || backend.GetConn("client-a") != e.conn {
+		t.Fatalf("virtual conn of client-a has ip %s", e.conn.GetIP())
+	}
+
+	transport.Write("client-a", mustMarshalPacket(gateway2.Packet{ConnID: "client-a", WebsocketType: websocket.TextMessage, Data: []byte("hello")}))
+	if e := next("receive", "client-a"); e.data != "hello" {
+		t.Fatalf("client-a received %q", e.data)
+	}
+	select {
+	case p := <-received:
+		if p.Kind != gateway2.PacketKindData || p.ConnID != "client-a" || p.WebsocketType != websocket.TextMessage || string(p.Data) != "hello" {
+			t.Fatalf("gateway received %+v", p)
+		}
+	case <-time.After(time.Second * 5):
+		t.Fatal("virtual conn write timeout")
+	}
+
+	transport.Write("client-a", mustMarshalPacket(gateway2.Packet{Kind: gateway2.PacketKindDisconnect, ConnID: "client-a"}))
+	if e := next("closed", "client-a"); e.err != gateway2.ErrClientDisconnected {
+		t.Fatalf("client-a closed with %v", e.err)
+	}
+	if backend.GetConn("client-a") != nil {
+		t.Fatal("client-a should be unbound after disconnect")
+	}
+
+	// 断开后仍在途中的数据包不会重新创建虚拟连接
+	transport.Write("client-a", mustMarshalPacket(gateway2.Packet{ConnID: "client-a", WebsocketType: websocket.TextMessage, Data: []byte("late")}))
+	select {
+	case p := <-received:
+		if p.Kind != gateway2.PacketKindDisconnect || p.ConnID != "client-a" {
+			t.Fatalf("late packet of client-a, gateway received %+v", p)
+		}
+	case <-time.After(time.Second * 5):
+		t.Fatal("late packet disconnect notify timeout")
+	}
+	if backend.GetConn("client-a") != nil {
+		t.Fatal("late packet should not bind client-a again")
+	}
+
+	transport.Write("client-b", mustMarshalPacket(gateway2.Packet{Kind: gateway2.PacketKindConnect, ConnID: "client-b", Data: []byte("10.0.0.2")}))
+	next("opened", "client-b")
+	transport.Write("client-b", mustMarshalPacket(gateway2.Packet{ConnID: "client-b", WebsocketType: websocket.BinaryMessage, Data: []byte("close")}))
+	next("receive", "client-b")
+	next("closed", "client-b")
+	select {
+	case p := <-received:
+		if p.Kind != gateway2.PacketKindDisconnect || p.ConnID != "client-b" {
+			t.Fatalf("endpoint closed client-b, gateway received %+v", p)
+		}
+	case <-time.After(time.Second * 5):
+		t.Fatal("disconnect notify timeout")
+	}
+}
@@ -0,0 +1,8 @@
+package gateway
+
+import "time"
+
+const (
+	// DefaultMigrationTimeout 默认的会话迁移确认超时时间
+	DefaultMigrationTimeout = time.Second * 5
+)
@@ -0,0 +1,82 @@
+package gateway
+
+import (
+	"github.com/kercylan98/minotaur/server"
+	"github.com/kercylan98/minotaur/utils/log"
+	"strconv"
+)
+
+// EndpointGenerator 根据注册中心中的服务实例创建网关端点，返回 nil 时将忽略该服务实例
+type EndpointGenerator func(instance server.ServiceInstance) *Endpoint
+
+// DefaultEndpointGenerator 默认的网关端点生成器
+//   - 以服务器类型作为端点名称，即服务实例的类型应当与路由规则中的服务名称保持一致
+//   - 当元数据中包含 weight 时将作为端点的权重
+func DefaultEndpointGenerator(instance server.ServiceInstance) *Endpoint {
+	var options []EndpointOption
+	if weight, err := strconv.Atoi(instance.Metadata["weight"]); err == nil {
+		options = append(options, WithEndpointWeight(weight))
+	}
+	return NewEndpoint(instance.Type, instance.Address, options...)
+}
+
+// Discover 监听注册中心中特定类型的服务实例，并自动添加或移除对应的网关端点
+//   - serverTypes 为空时将监听所有类型的服务实例
+//   - generator 为 nil 时将采用 DefaultEndpointGenerator
+//   - 服务实例被移除或过期时，对应的端点将通过 RemoveEndpoint 移除，绑定在该端点上的连接将被迁移
+//   - 通过返回的 cancel 函数停止监听，已添加的端点不会被移除
+func (slf *EndpointManager) Discover(registry server.Registry, generator EndpointGenerator, serverTypes ...string) (cancel func(), err error) {
+	if generator == nil {
+		generator = DefaultEndpointGenerator
+	}
+	if len(serverTypes) == 0 {
+		serverTypes = []string{""}
+	}
+	var cancels = make([]func(), 0, len(serverTypes))
+	cancel = func() {
+		for _, c := range cancels {
+			c()
+		}
+	}
+	for _, serverType := range serverTypes {
+		c, err := registry.Watch(serverType, func(event server.RegistryEvent) {
+			slf.onRegistryEvent(event, generator)
+		})
+		if err != nil {
+			cancel()
+			return nil, err
+		}
+		cancels = append(cancels, c)
+	}
+	return cancel, nil
+}
+
+// onRegistryEvent 处理注册中心中服务实例的变化
+func (slf *EndpointManager) onRegistryEvent(event server.RegistryEvent, generator EndpointGenerator) {
+	var instance = event.Instance
+	switch event.Type {
+	case server.RegistryEventAdd:
+		if endpoint, exist := slf.discovered.GetExist(instance.ID); exist {
+			if endpoint.name == instance.Type && endpoint.address == instance.Address {
+				return
+			}
+			slf.discovered.Delete(instance.ID)
+			_ = slf.RemoveEndpoint(endpoint)
+		}
+		var endpoint = generator(instance)
+		if endpoint == nil {
+			return
+		}
+		if err := slf.AddEndpoint(endpoint); err != nil {
+			log.Warn("Gateway", log.Int64("ServerID", instance.ID), log.String("service", endpoint.name), log.String("address", endpoint.address), log.Err(err))
+			return
+		}
+		slf.discovered.Set(instance.ID, endpoint)
+		log.Info("Gateway", log.Int64("ServerID", instance.ID), log.String("service", endpoint.name), log.String("discover", endpoint.address))
+	case server.RegistryEventRemove:
+		if endpoint, exist := slf.discovered.DeleteGetExist(instance.ID); exist {
+			_ = slf.RemoveEndpoint(endpoint)
+			log.Info("Gateway", log.Int64("ServerID", instance.ID), log.String("service", endpoint.name), log.String("remove", endpoint.address))
+		}
+	}
+}
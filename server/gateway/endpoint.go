@@ -1,64 +1,315 @@
 package gateway
 
 import (
+	"encoding/binary"
 	"github.com/kercylan98/minotaur/server"
-	"github.com/kercylan98/minotaur/server/client"
+	"github.com/kercylan98/minotaur/utils/concurrent"
+	"github.com/kercylan98/minotaur/utils/log"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
 // NewEndpoint 创建网关端点
-func NewEndpoint(name, address string) *Endpoint {
+//   - 默认通过 WebSocket 与端点建立连接，可通过 WithEndpointTransport 使用其他传输层
+func NewEndpoint(name, address string, options ...EndpointOption) *Endpoint {
 	endpoint := &Endpoint{
-		client:  client.NewWebsocket(address),
-		name:    name,
-		address: address,
+		name:      name,
+		address:   address,
+		weight:    1,
+		conns:     concurrent.NewBalanceMap[string, *server.Conn](),
+		attaching: map[string]*endpointAttach{},
+	}
+	for _, option := range options {
+		option(endpoint)
+	}
+	if endpoint.transport == nil {
+		endpoint.transport = NewWebsocketTransport(address)
 	}
-	endpoint.client.RegConnectionClosedEvent(endpoint.onConnectionClosed)
-	endpoint.client.RegConnectionReceivePacketEvent(endpoint.onConnectionReceivePacket)
 	return endpoint
 }
 
 // Endpoint 网关端点
 type Endpoint struct {
-	client  *client.Websocket // 端点客户端
-	name    string            // 端点名称
-	address string            // 端点地址
-	state   float64           // 端点健康值（0为不可用，越高越优）
-	offline bool              // 离线
+	transport Transport                                    // 传输层
+	name      string                                       // 端点名称
+	address   string                                       // 端点地址
+	weight    int                                          // 端点权重
+	conns     *concurrent.BalanceMap[string, *server.Conn] // 正在使用该端点的客户端连接
+	manager   *EndpointManager                             // 所属的端点管理器
+
+	rw       sync.RWMutex
+	state    float64       // 端点健康值（0为不可用，越高越优）
+	latency  time.Duration // 最近一次探测的延迟
+	failures int           // 连续探测失败次数
+	offline  bool          // 离线
+
+	healthInterval    time.Duration // 健康探测间隔，为 0 时不进行探测
+	healthMaxFailures int           // 连续探测失败多少次后视为不可用
+	session           atomic.Uint64 // 与端点建立连接的次数，用于终止过期的探测
+	pongAt            atomic.Int64  // 最近一次收到响应的探测发出时间，仅接受比其更新的探测响应
+
+	attachMutex sync.Mutex
+	attaching   map[string]*endpointAttach // 等待端点确认会话附加的连接
+}
+
+// endpointAttach 等待端点确认的会话附加
+type endpointAttach struct {
+	packets []server.Packet // 确认前缓冲的客户端数据包
+	timer   *time.Timer     // 超时定时器
+}
+
+// GetName 获取端点名称
+func (slf *Endpoint) GetName() string {
+	return slf.name
+}
+
+// GetAddress 获取端点地址
+func (slf *Endpoint) GetAddress() string {
+	return slf.address
+}
+
+// GetWeight 获取端点权重
+func (slf *Endpoint) GetWeight() int {
+	return slf.weight
+}
+
+// GetState 获取端点健康值，0 为不可用，越高越优
+func (slf *Endpoint) GetState() float64 {
+	slf.rw.RLock()
+	defer slf.rw.RUnlock()
+	return slf.state
+}
+
+// GetLatency 获取最近一次健康探测的延迟
+func (slf *Endpoint) GetLatency() time.Duration {
+	slf.rw.RLock()
+	defer slf.rw.RUnlock()
+	return slf.latency
+}
+
+// GetConnectionCount 获取正在使用该端点的客户端连接数量
+func (slf *Endpoint) GetConnectionCount() int {
+	return slf.conns.Size()
+}
+
+// IsHealthy 端点是否可用
+func (slf *Endpoint) IsHealthy() bool {
+	slf.rw.RLock()
+	defer slf.rw.RUnlock()
+	return !slf.offline && slf.state > 0
 }
 
 // Offline 离线
 func (slf *Endpoint) Offline() {
+	slf.rw.Lock()
 	slf.offline = true
+	slf.rw.Unlock()
 }
 
 // Connect 连接端点
 func (slf *Endpoint) Connect() {
 	for {
 		var now = time.Now()
-		if err := slf.client.Run(); err == nil {
-			slf.state = 1 - (time.Since(now).Seconds() / 10)
+		if err := slf.transport.Run(slf.onReceive, slf.onClosed); err == nil {
+			slf.updateState(time.Since(now))
+			if slf.healthInterval > 0 {
+				go slf.healthCheck(slf.session.Add(1))
+			}
 			break
 		}
 		time.Sleep(100 * time.Millisecond)
 	}
 }
 
-// Write 写入数据
+// Write 写入通过 PackGatewayPacket 或 PackGatewayControlPacket 打包的数据
 func (slf *Endpoint) Write(packet server.Packet) {
-	slf.client.Write(packet)
+	var key string
+	if p, err := UnmarshalPacket(packet.Data); err == nil {
+		key = p.ConnID
+	}
+	slf.transport.Write(key, packet.Data)
+}
+
+// write 写入网关数据包，无法编码的数据包将被丢弃
+func (slf *Endpoint) write(packet Packet) {
+	data, err := MarshalPacket(packet)
+	if err != nil {
+		log.Warn("Gateway", log.String("endpoint", slf.address), log.String("conn", packet.ConnID), log.Err(err))
+		return
+	}
+	slf.transport.Write(packet.ConnID, data)
+}
+
+// Forward 将客户端数据包转发至端点
+//   - 当连接正在迁移至该端点且尚未收到确认时，数据包将被缓冲，待确认后按顺序发送
+func (slf *Endpoint) Forward(conn *server.Conn, packet server.Packet) {
+	slf.attachMutex.Lock()
+	if pending, exist := slf.attaching[conn.GetID()]; exist {
+		pending.packets = append(pending.packets, packet)
+		slf.attachMutex.Unlock()
+		return
+	}
+	slf.attachMutex.Unlock()
+	slf.write(Packet{ConnID: conn.GetID(), WebsocketType: packet.WebsocketType, Data: packet.Data})
+}
+
+// attach 将连接附加至该端点，并向端点发送会话附加控制帧
+//   - 超过 timeout 仍未收到确认时，客户端连接将通过系统消息被关闭
+func (slf *Endpoint) attach(conn *server.Conn, metadata []byte, timeout time.Duration) {
+	var id = conn.GetID()
+	slf.conns.Set(id, conn)
+	slf.attachMutex.Lock()
+	slf.attaching[id] = &endpointAttach{timer: time.AfterFunc(timeout, func() {
+		slf.attachMutex.Lock()
+		_, exist := slf.attaching[id]
+		delete(slf.attaching, id)
+		slf.attachMutex.Unlock()
+		if exist {
+			log.Warn("Gateway", log.String("endpoint", slf.address), log.String("conn", id), log.Err(ErrAttachTimeout))
+			closeConn(conn)
+		}
+	})}
+	slf.attachMutex.Unlock()
+	slf.write(Packet{Kind: PacketKindAttach, ConnID: id, Data: metadata})
 }
 
-// onConnectionClosed 与端点连接断开事件
-func (slf *Endpoint) onConnectionClosed(conn *client.Websocket, err any) {
-	if !slf.offline {
-		go slf.Connect()
+// onAttachAck 收到端点的会话附加确认，发送缓冲的数据包
+func (slf *Endpoint) onAttachAck(id string) {
+	slf.attachMutex.Lock()
+	defer slf.attachMutex.Unlock()
+	pending, exist := slf.attaching[id]
+	if !exist {
+		return
+	}
+	delete(slf.attaching, id)
+	pending.timer.Stop()
+	for _, packet := range pending.packets {
+		slf.write(Packet{ConnID: id, WebsocketType: packet.WebsocketType, Data: packet.Data})
+	}
+}
+
+// release 解除连接与该端点的绑定
+func (slf *Endpoint) release(id string) {
+	slf.conns.Delete(id)
+	slf.attachMutex.Lock()
+	if pending, exist := slf.attaching[id]; exist {
+		pending.timer.Stop()
+		delete(slf.attaching, id)
+	}
+	slf.attachMutex.Unlock()
+}
+
+// updateState 根据延迟更新端点健康值
+func (slf *Endpoint) updateState(latency time.Duration) {
+	var state = 1 - latency.Seconds()/10
+	if state <= 0 {
+		state = 0.01
+	}
+	slf.rw.Lock()
+	slf.state = state
+	slf.latency = latency
+	slf.failures = 0
+	slf.rw.Unlock()
+}
+
+// healthCheck 周期性的向端点发送探测控制帧，连续多个探测间隔内未收到任何响应达到上限后将端点视为不可用
+//   - 响应不要求在下一次探测前到达，延迟超过探测间隔的端点只要持续响应便不会被视为不可用
+func (slf *Endpoint) healthCheck(session uint64) {
+	var ticker = time.NewTicker(slf.healthInterval)
+	defer ticker.Stop()
+	var lastPong = time.Now().UnixNano()
+	var pinged bool
+	slf.pongAt.Store(lastPong)
+	for range ticker.C {
+		if slf.session.Load() != session || !slf.transport.IsConnected() {
+			return
+		}
+		var pong = slf.pongAt.Load()
+		if pinged && pong == lastPong {
+			slf.rw.Lock()
+			slf.failures++
+			var unhealthy = slf.failures >= slf.healthMaxFailures && slf.state > 0
+			if unhealthy {
+				slf.state = 0
+				log.Warn("Gateway", log.String("endpoint", slf.address), log.String("health", "unhealthy"), log.Int("failures", slf.failures))
+			}
+			slf.rw.Unlock()
+			if unhealthy && slf.manager != nil {
+				slf.manager.Migrate(slf)
+			}
+		}
+		lastPong, pinged = pong, true
+		var data = make([]byte, 8)
+		binary.BigEndian.PutUint64(data, uint64(time.Now().UnixNano()))
+		slf.write(Packet{Kind: PacketKindPing, Data: data})
+	}
+}
+
+// onPong 收到端点的探测响应
+//   - 仅接受比最近一次响应更新的探测响应，延迟以该探测的发出时间计算，乱序到达的过期响应将被忽略
+func (slf *Endpoint) onPong(data []byte) {
+	if len(data) < 8 {
+		return
+	}
+	var sent, now = int64(binary.BigEndian.Uint64(data)), time.Now().UnixNano()
+	if sent > now {
+		return
+	}
+	for {
+		var last = slf.pongAt.Load()
+		if sent <= last {
+			return
+		}
+		if slf.pongAt.CompareAndSwap(last, sent) {
+			break
+		}
+	}
+	slf.updateState(time.Duration(now - sent))
+}
+
+// onClosed 与端点连接断开事件
+func (slf *Endpoint) onClosed(err any) {
+	slf.rw.Lock()
+	slf.state = 0
+	var offline = slf.offline
+	slf.rw.Unlock()
+	if offline {
+		return
+	}
+	if slf.manager != nil {
+		slf.manager.Migrate(slf)
+	}
+	go slf.Connect()
+}
+
+// onReceive 接收到来自端点的数据包事件
+func (slf *Endpoint) onReceive(data []byte) {
+	p, err := UnmarshalPacket(data)
+	if err != nil {
+		log.Warn("Gateway", log.String("endpoint", slf.address), log.Err(err))
+		return
+	}
+	switch p.Kind {
+	case PacketKindData:
+		target, exist := slf.conns.GetExist(p.ConnID)
+		if !exist {
+			return
+		}
+		target.Write(server.Packet{WebsocketType: p.WebsocketType, Data: p.Data})
+	case PacketKindPong:
+		slf.onPong(p.Data)
+	case PacketKindAttachAck:
+		slf.onAttachAck(p.ConnID)
+	case PacketKindDisconnect:
+		if target, exist := slf.conns.GetExist(p.ConnID); exist {
+			closeConn(target)
+		}
 	}
 }
 
-// onConnectionReceivePacket 解说到来自端点的数据包事件
-func (slf *Endpoint) onConnectionReceivePacket(conn *client.Websocket, packet server.Packet) {
-	p := UnpackGatewayPacket(packet)
-	packet.Data = p.Data
-	conn.GetData(p.ConnID).(*server.Conn).Write(packet)
+// closeConn 通过系统消息关闭客户端连接
+//   - 定时器及传输层的回调不在服务器的消息处理协程中执行，直接关闭连接将与消息处理过程中对连接的写入产生竞争
+func closeConn(conn *server.Conn) {
+	server.PushSystemMessage(conn.GetServer(), conn.Close)
 }
@@ -3,17 +3,20 @@ package gateway
 import (
 	"github.com/kercylan98/minotaur/server"
 	"github.com/kercylan98/minotaur/utils/concurrent"
-	"github.com/kercylan98/minotaur/utils/random"
+	"github.com/kercylan98/minotaur/utils/log"
+	"github.com/kercylan98/minotaur/utils/super"
+	"time"
 )
 
 // NewEndpointManager 创建网关端点管理器
 func NewEndpointManager() *EndpointManager {
 	em := &EndpointManager{
-		endpoints: concurrent.NewBalanceMap[string, []*Endpoint](),
-		memory:    concurrent.NewBalanceMap[string, *Endpoint](),
-		selector: func(endpoints []*Endpoint) *Endpoint {
-			return endpoints[random.Int(0, len(endpoints)-1)]
-		},
+		endpoints:        concurrent.NewBalanceMap[string, []*Endpoint](),
+		memory:           concurrent.NewBalanceMap[string, map[string]*Endpoint](),
+		discovered:       concurrent.NewBalanceMap[int64, *Endpoint](),
+		selector:         RandomSelector(),
+		selectors:        map[string]EndpointSelector{},
+		migrationTimeout: DefaultMigrationTimeout,
 	}
 	return em
 }
@@ -21,16 +24,50 @@ func NewEndpointManager() *EndpointManager {
 // EndpointManager 网关端点管理器
 type EndpointManager struct {
 	endpoints *concurrent.BalanceMap[string, []*Endpoint]
-	memory    *concurrent.BalanceMap[string, *Endpoint]
-	selector  func([]*Endpoint) *Endpoint
+	memory    *concurrent.BalanceMap[string, map[string]*Endpoint] // 连接 ID -> 服务名称 -> 端点
+	selector  EndpointSelector
+	selectors map[string]EndpointSelector // 服务名称 -> 端点选择器，将覆盖该服务的默认端点选择器
+
+	discovered *concurrent.BalanceMap[int64, *Endpoint] // 通过注册中心发现的端点，服务器 ID -> 端点
+
+	migrationTimeout time.Duration // 会话迁移时等待端点确认的超时时间
 }
 
-// GetEndpoint 获取端点
+// GetEndpoint 获取连接在特定服务下的端点
+//   - 连接首次访问该服务时将通过选择器选择端点，此后将总是返回相同的端点
+//   - 并发访问时仅有一次选择生效，并仅由该次选择向端点发送 PacketKindConnect 控制帧
 func (slf *EndpointManager) GetEndpoint(name string, conn *server.Conn) (*Endpoint, error) {
-	endpoint, exist := slf.memory.GetExist(conn.GetID())
-	if exist {
-		return endpoint, nil
+	var endpoint *Endpoint
+	var selected bool
+	slf.memory.Atom(func(m map[string]map[string]*Endpoint) {
+		services, exist := m[conn.GetID()]
+		if endpoint = services[name]; endpoint != nil {
+			return
+		}
+		if endpoint = slf.selectEndpoint(name, conn, nil); endpoint == nil {
+			return
+		}
+		if !exist {
+			services = map[string]*Endpoint{}
+			m[conn.GetID()] = services
+		}
+		services[name] = endpoint
+		endpoint.conns.Set(conn.GetID(), conn)
+		selected = true
+	})
+	if endpoint == nil {
+		return nil, ErrEndpointNotExists
 	}
+	if selected {
+		endpoint.write(Packet{Kind: PacketKindConnect, ConnID: conn.GetID(), Data: []byte(conn.GetIP())})
+	}
+	return endpoint, nil
+}
+
+// selectEndpoint 通过选择器从特定服务的健康端点中选择一个端点
+//   - exclude 不为空时将排除该端点
+func (slf *EndpointManager) selectEndpoint(name string, conn *server.Conn, exclude *Endpoint) *Endpoint {
+	var endpoint *Endpoint
 	slf.endpoints.Atom(func(m map[string][]*Endpoint) {
 		endpoints, exist := m[name]
 		if !exist {
@@ -41,25 +78,112 @@ func (slf *EndpointManager) GetEndpoint(name string, conn *server.Conn) (*Endpoi
 		}
 		var available = make([]*Endpoint, 0, len(endpoints))
 		for _, e := range endpoints {
-			if !e.offline && e.state > 0 {
+			if e != exclude && e.IsHealthy() {
 				available = append(available, e)
 			}
 		}
 		if len(available) == 0 {
 			return
 		}
-		endpoint = slf.selector(available)
+		var selector = slf.selector
+		if s, exist := slf.selectors[name]; exist {
+			selector = s
+		}
+		endpoint = selector(conn, available)
 	})
-	if endpoint == nil {
-		return nil, ErrEndpointNotExists
+	return endpoint
+}
+
+// Migrate 将绑定在特定端点上的所有连接迁移至同一服务下的其他健康端点
+//   - 迁移时将向新的端点发送 PacketKindAttach 控制帧，在端点返回 PacketKindAttachAck 前，客户端的数据包将被缓冲
+//   - 当没有可用的端点时，连接将解除与该服务的绑定，后续数据包将重新选择端点
+//   - 原端点的传输层仍处于连接状态时，将向其发送 PacketKindDisconnect 控制帧，避免同一客户端同时存在于多个端点
+//   - 迁移期间连接已解除绑定或已迁移至其他端点时，将向已附加的新端点发送 PacketKindDisconnect 控制帧撤销附加
+//   - 通过 RemoveEndpoint 移除端点或端点断开、不健康时将自动进行迁移
+func (slf *EndpointManager) Migrate(endpoint *Endpoint) {
+	for id, conn := range endpoint.conns.Map() {
+		var target = slf.selectEndpoint(endpoint.name, conn, endpoint)
+		if target != nil {
+			target.attach(conn, super.MarshalJSON(&AttachMetadata{
+				IP:      conn.GetIP(),
+				Service: endpoint.name,
+				From:    endpoint.address,
+			}), slf.migrationTimeout)
+		}
+		var migrated bool
+		slf.memory.Atom(func(m map[string]map[string]*Endpoint) {
+			services, exist := m[id]
+			if !exist || services[endpoint.name] != endpoint {
+				return
+			}
+			migrated = true
+			if target == nil {
+				delete(services, endpoint.name)
+			} else {
+				services[endpoint.name] = target
+			}
+		})
+		endpoint.release(id)
+		if migrated && endpoint.transport.IsConnected() {
+			endpoint.write(Packet{Kind: PacketKindDisconnect, ConnID: id})
+		}
+		if !migrated {
+			if target != nil {
+				target.release(id)
+				if target.transport.IsConnected() {
+					target.write(Packet{Kind: PacketKindDisconnect, ConnID: id})
+				}
+			}
+			continue
+		}
+		if target == nil {
+			log.Warn("Gateway", log.String("conn", id), log.String("service", endpoint.name), log.String("migrate", endpoint.address), log.Err(ErrEndpointNotExists))
+			continue
+		}
+		log.Info("Gateway", log.String("conn", id), log.String("service", endpoint.name), log.String("migrate", endpoint.address), log.String("to", target.address))
 	}
-	slf.memory.Set(conn.GetID(), endpoint)
-	return endpoint, nil
+}
+
+// GetEndpoints 获取特定服务下的所有端点
+func (slf *EndpointManager) GetEndpoints(name string) []*Endpoint {
+	var endpoints []*Endpoint
+	slf.endpoints.Atom(func(m map[string][]*Endpoint) {
+		endpoints = append(endpoints, m[name]...)
+	})
+	return endpoints
+}
+
+// GetConnEndpoints 获取连接在各个服务下正在使用的端点
+func (slf *EndpointManager) GetConnEndpoints(conn *server.Conn) map[string]*Endpoint {
+	var endpoints map[string]*Endpoint
+	slf.memory.Atom(func(m map[string]map[string]*Endpoint) {
+		if services, exist := m[conn.GetID()]; exist {
+			endpoints = make(map[string]*Endpoint, len(services))
+			for service, endpoint := range services {
+				endpoints[service] = endpoint
+			}
+		}
+	})
+	return endpoints
+}
+
+// ReleaseConn 释放连接在各个服务下绑定的端点，返回被释放的端点
+//   - 通常在连接断开时调用，释放后连接再次访问服务时将重新选择端点
+func (slf *EndpointManager) ReleaseConn(conn *server.Conn) map[string]*Endpoint {
+	var endpoints map[string]*Endpoint
+	slf.memory.Atom(func(m map[string]map[string]*Endpoint) {
+		endpoints = m[conn.GetID()]
+		delete(m, conn.GetID())
+	})
+	for _, endpoint := range endpoints {
+		endpoint.release(conn.GetID())
+	}
+	return endpoints
 }
 
 // AddEndpoint 添加端点
 func (slf *EndpointManager) AddEndpoint(endpoint *Endpoint) error {
-	if endpoint.client.IsConnected() {
+	if endpoint.transport.IsConnected() {
 		return ErrCannotAddRunningEndpoint
 	}
 	for _, e := range slf.endpoints.Get(endpoint.name) {
@@ -67,6 +191,7 @@ func (slf *EndpointManager) AddEndpoint(endpoint *Endpoint) error {
 			return ErrEndpointAlreadyExists
 		}
 	}
+	endpoint.manager = slf
 	go endpoint.Connect()
 	slf.endpoints.Atom(func(m map[string][]*Endpoint) {
 		m[endpoint.name] = append(m[endpoint.name], endpoint)
@@ -75,6 +200,7 @@ func (slf *EndpointManager) AddEndpoint(endpoint *Endpoint) error {
 }
 
 // RemoveEndpoint 移除端点
+//   - 移除后端点将离线并断开连接，绑定在该端点上的客户端连接将被迁移至同一服务下的其他端点
 func (slf *EndpointManager) RemoveEndpoint(endpoint *Endpoint) error {
 	slf.endpoints.Atom(func(m map[string][]*Endpoint) {
 		var endpoints []*Endpoint
@@ -90,5 +216,8 @@ func (slf *EndpointManager) RemoveEndpoint(endpoint *Endpoint) error {
 		}
 		m[endpoint.name] = endpoints
 	})
+	endpoint.Offline()
+	slf.Migrate(endpoint)
+	endpoint.transport.Close()
 	return nil
 }
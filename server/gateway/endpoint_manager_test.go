@@ -0,0 +1,188 @@
+package gateway_test
+
+import (
+	"encoding/json"
+	"github.com/gorilla/websocket"
+	"github.com/kercylan98/minotaur/server"
+	gateway2 "github.com/kercylan98/minotaur/server/gateway"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// memoryTransport 记录网关写入的数据包的传输层，可通过 push 模拟端点发送数据包
+type memoryTransport struct {
+	mutex     sync.Mutex
+	receive   func(data []byte)
+	connected atomic.Bool
+	packets   chan gateway2.Packet
+}
+
+func newMemoryTransport() *memoryTransport {
+	return &memoryTransport{packets: make(chan gateway2.Packet, 64)}
+}
+
+func (slf *memoryTransport) Run(receive func(data []byte), closed func(err any)) error {
+	slf.mutex.Lock()
+	slf.receive = receive
+	slf.mutex.Unlock()
+	slf.connected.Store(true)
+	return nil
+}
+
+func (slf *memoryTransport) Write(key string, data []byte) {
+	if p, err := gateway2.UnmarshalPacket(data); err == nil {
+		slf.packets <- p
+	}
+}
+
+func (slf *memoryTransport) Close() {
+	slf.connected.Store(false)
+}
+
+func (slf *memoryTransport) IsConnected() bool {
+	return slf.connected.Load()
+}
+
+func (slf *memoryTransport) push(packet gateway2.Packet) {
+	slf.mutex.Lock()
+	defer slf.mutex.Unlock()
+	slf.receive(mustMarshalPacket(packet))
+}
+
+func (slf *memoryTransport) next(t *testing.T, kind gateway2.PacketKind, connID string) gateway2.Packet {
+	select {
+	case p := <-slf.packets:
+		if p.Kind != kind || p.ConnID != connID {
+			t.Fatalf("expected packet kind %d of %s, got %+v", kind, connID, p)
+		}
+		return p
+	case <-time.After(time.Second * 5):
+		t.Fatalf("wait packet kind %d of %s timeout", kind, connID)
+	}
+	return gateway2.Packet{}
+}
+
+func TestEndpointManager_Migrate(t *testing.T) {
+	srv := server.New(server.NetworkWebsocket)
+	var closed = make(chan string, 8)
+	srv.RegConnectionClosedEvent(func(srv *server.Server, conn *server.Conn, err any) {
+		closed <- conn.GetID()
+	})
+	gw := gateway2.NewGateway(srv,
+		gateway2.WithRoute(gateway2.RouteTo("lobby")),
+		gateway2.WithMigrationTimeout(200*time.Millisecond),
+		gateway2.WithEndpointSelector(func(endpoints []*gateway2.Endpoint) *gateway2.Endpoint {
+			return endpoints[0]
+		}),
+	)
+	addr, stop := runGateway(t, gw, srv, "/migrate")
+	defer stop()
+
+	var transports = []*memoryTransport{newMemoryTransport(), newMemoryTransport(), newMemoryTransport()}
+	var endpoints []*gateway2.Endpoint
+	for i, transport := range transports {
+		endpoint := gateway2.NewEndpoint("lobby", string(rune('a'+i)), gateway2.WithEndpointTransport(transport))
+		if err := gw.AddEndpoint(endpoint); err != nil {
+			t.Fatal(err)
+		}
+		for deadline := time.Now().Add(time.Second); time.Now().Before(deadline) && !endpoint.IsHealthy(); {
+			time.Sleep(10 * time.Millisecond)
+		}
+		endpoints = append(endpoints, endpoint)
+	}
+	var a, b, c = transports[0], transports[1], transports[2]
+
+	var dial = func() (*websocket.Conn, string) {
+		ws, _, err := websocket.DefaultDialer.Dial("ws://"+addr, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return ws, ws.LocalAddr().String()
+	}
+	var send = func(ws *websocket.Conn, data string) {
+		if err := ws.WriteMessage(websocket.BinaryMessage, []byte(data)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	first, firstID := dial()
+	defer first.Close()
+	send(first, "m1")
+	a.next(t, gateway2.PacketKindConnect, firstID)
+	if p := a.next(t, gateway2.PacketKindData, firstID); string(p.Data) != "m1" {
+		t.Fatalf("endpoint a received %q", p.Data)
+	}
+
+	// 迁移至 b，确认前的数据包将被缓冲
+	if err := gw.RemoveEndpoint(endpoints[0]); err != nil {
+		t.Fatal(err)
+	}
+	a.next(t, gateway2.PacketKindDisconnect, firstID)
+	var metadata gateway2.AttachMetadata
+	if err := json.Unmarshal(b.next(t, gateway2.PacketKindAttach, firstID).Data, &metadata); err != nil || metadata.From != "a" || metadata.Service != "lobby" {
+		t.Fatalf("attach metadata %+v, err %v", metadata, err)
+	}
+	send(first, "m2")
+	time.Sleep(50 * time.Millisecond)
+	if len(b.packets) != 0 {
+		t.Fatal("packets should be buffered before attach ack")
+	}
+	b.push(gateway2.Packet{Kind: gateway2.PacketKindAttachAck, ConnID: firstID})
+	if p := b.next(t, gateway2.PacketKindData, firstID); string(p.Data) != "m2" {
+		t.Fatalf("endpoint b received %q", p.Data)
+	}
+
+	// 迁移至 c，未确认的连接将在超时后被关闭
+	second, secondID := dial()
+	defer second.Close()
+	send(second, "m3")
+	b.next(t, gateway2.PacketKindConnect, secondID)
+	b.next(t, gateway2.PacketKindData, secondID)
+	if err := gw.RemoveEndpoint(endpoints[1]); err != nil {
+		t.Fatal(err)
+	}
+	var released = map[string]bool{}
+	for i := 0; i < 2; i++ {
+		select {
+		case p := <-b.packets:
+			released[p.ConnID] = p.Kind == gateway2.PacketKindDisconnect
+		case <-time.After(time.Second * 5):
+			t.Fatal("wait disconnect timeout")
+		}
+	}
+	if !released[firstID] || !released[secondID] {
+		t.Fatalf("released %v", released)
+	}
+	var attached = map[string]bool{}
+	for i := 0; i < 2; i++ {
+		select {
+		case p := <-c.packets:
+			attached[p.ConnID] = p.Kind == gateway2.PacketKindAttach
+		case <-time.After(time.Second * 5):
+			t.Fatal("wait attach timeout")
+		}
+	}
+	if !attached[firstID] || !attached[secondID] {
+		t.Fatalf("attached %v", attached)
+	}
+	c.push(gateway2.Packet{Kind: gateway2.PacketKindAttachAck, ConnID: firstID})
+	select {
+	case id := <-closed:
+		if id != secondID {
+			t.Fatalf("conn %s closed, expected %s", id, secondID)
+		}
+	case <-time.After(time.Second * 5):
+		t.Fatal("unacknowledged conn should be closed after migration timeout")
+	}
+	c.next(t, gateway2.PacketKindDisconnect, secondID)
+	_ = second.SetReadDeadline(time.Now().Add(time.Second))
+	if _, _, err := second.ReadMessage(); err == nil {
+		t.Fatal("unacknowledged client should be disconnected")
+	}
+	send(first, "m4")
+	if p := c.next(t, gateway2.PacketKindData, firstID); string(p.Data) != "m4" {
+		t.Fatalf("endpoint c received %q", p.Data)
+	}
+}
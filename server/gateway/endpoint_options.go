@@ -0,0 +1,39 @@
+package gateway
+
+import "time"
+
+// EndpointOption 网关端点选项
+type EndpointOption func(endpoint *Endpoint)
+
+// WithEndpointWeight 设置端点权重，默认为 1
+//   - 权重仅对加权轮询等依赖权重的选择器生效
+func WithEndpointWeight(weight int) EndpointOption {
+	return func(endpoint *Endpoint) {
+		if weight > 0 {
+			endpoint.weight = weight
+		}
+	}
+}
+
+// WithEndpointHealthCheck 开启端点健康探测
+//   - 网关将每隔 interval 向端点发送 PacketKindPing 控制帧，端点需要原样返回携带相同数据的 PacketKindPong 控制帧
+//   - 连续 maxFailures 个探测间隔内未收到任何响应时，端点将被视为不可用，直到再次收到响应
+//   - 晚于下一次探测到达的响应依旧有效，延迟将根据其对应探测的发出时间计算，早于最近一次响应的过期响应将被忽略
+//   - 端点健康值将根据探测延迟进行更新
+func WithEndpointHealthCheck(interval time.Duration, maxFailures int) EndpointOption {
+	return func(endpoint *Endpoint) {
+		if maxFailures <= 0 {
+			maxFailures = 1
+		}
+		endpoint.healthInterval = interval
+		endpoint.healthMaxFailures = maxFailures
+	}
+}
+
+// WithEndpointTransport 设置网关与端点之间的传输层，默认为 NewWebsocketTransport
+//   - 内置的传输层包括：NewWebsocketTransport、NewTCPTransport、NewKCPTransport
+func WithEndpointTransport(transport Transport) EndpointOption {
+	return func(endpoint *Endpoint) {
+		endpoint.transport = transport
+	}
+}
@@ -0,0 +1,79 @@
+package gateway_test
+
+import (
+	gateway2 "github.com/kercylan98/minotaur/server/gateway"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// probeTransport 按照固定延迟响应健康探测的传输层
+type probeTransport struct {
+	mutex   sync.Mutex
+	receive func(data []byte)
+	delay   time.Duration
+	silent  atomic.Bool
+	closed  atomic.Bool
+}
+
+func (slf *probeTransport) Run(receive func(data []byte), closed func(err any)) error {
+	slf.mutex.Lock()
+	slf.receive = receive
+	slf.mutex.Unlock()
+	return nil
+}
+
+func (slf *probeTransport) Write(key string, data []byte) {
+	p, err := gateway2.UnmarshalPacket(data)
+	if err != nil || p.Kind != gateway2.PacketKindPing || slf.silent.Load() {
+		return
+	}
+	time.AfterFunc(slf.delay, func() {
+		slf.mutex.Lock()
+		defer slf.mutex.Unlock()
+		slf.receive(mustMarshalPacket(gateway2.Packet{Kind: gateway2.PacketKindPong, Data: p.Data}))
+	})
+}
+
+func (slf *probeTransport) Close() {
+	slf.closed.Store(true)
+}
+
+func (slf *probeTransport) IsConnected() bool {
+	return !slf.closed.Load()
+}
+
+func TestEndpoint_HealthCheck(t *testing.T) {
+	var interval = 100 * time.Millisecond
+	var transport = &probeTransport{delay: interval * 3 / 2}
+	defer transport.Close()
+	endpoint := gateway2.NewEndpoint("probe", "probe", gateway2.WithEndpointTransport(transport), gateway2.WithEndpointHealthCheck(interval, 2))
+	endpoint.Connect()
+
+	var waitHealthy = func(healthy bool) {
+		var deadline = time.Now().Add(interval * 10)
+		for time.Now().Before(deadline) && endpoint.IsHealthy() != healthy {
+			time.Sleep(10 * time.Millisecond)
+		}
+		if endpoint.IsHealthy() != healthy {
+			t.Fatalf("endpoint should be healthy=%v", healthy)
+		}
+	}
+
+	// 响应晚于下一次探测到达时端点依旧可用
+	for deadline := time.Now().Add(interval * 10); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		if !endpoint.IsHealthy() {
+			t.Fatal("endpoint with late pongs should stay healthy")
+		}
+	}
+	if latency := endpoint.GetLatency(); latency < transport.delay || latency > transport.delay+interval {
+		t.Fatalf("latency %s, expected about %s", latency, transport.delay)
+	}
+
+	// 连续未收到响应时端点不可用，再次收到响应后恢复
+	transport.silent.Store(true)
+	waitHealthy(false)
+	transport.silent.Store(false)
+	waitHealthy(true)
+}
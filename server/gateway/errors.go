@@ -9,4 +9,22 @@ var (
 	ErrCannotAddRunningEndpoint = errors.New("gateway: cannot add a running endpoint")
 	// ErrEndpointNotExists 该名称下不存在任何端点
 	ErrEndpointNotExists = errors.New("gateway: endpoint not exists")
+	// ErrRouteNotFound 数据包未匹配到任何路由规则
+	ErrRouteNotFound = errors.New("gateway: no route matched the packet")
+	// ErrIllegalPacket 网关数据包格式不正确
+	ErrIllegalPacket = errors.New("gateway: illegal packet")
+	// ErrAttachTimeout 等待端点确认会话附加超时
+	ErrAttachTimeout = errors.New("gateway: session attach timeout")
+	// ErrClientDisconnected 客户端已与网关断开连接
+	ErrClientDisconnected = errors.New("gateway: client disconnected")
+	// ErrTransportClosed 传输层已被主动关闭
+	ErrTransportClosed = errors.New("gateway: transport closed")
+	// ErrTransportQueueFull 传输层写入队列已满，端点无法及时处理数据包
+	ErrTransportQueueFull = errors.New("gateway: transport write queue is full")
+	// ErrPacketVersion 不支持的网关数据包版本
+	ErrPacketVersion = errors.New("gateway: unsupported packet version")
+	// ErrPacketConnIDTooLong 网关数据包的连接 ID 超过 65535 字节
+	ErrPacketConnIDTooLong = errors.New("gateway: packet conn id is too long")
+	// ErrPacketWebsocketType 网关数据包的 websocket 消息类型超出 0 ~ 255 的范围
+	ErrPacketWebsocketType = errors.New("gateway: packet websocket type out of range")
 )
@@ -2,10 +2,12 @@ package gateway
 
 import (
 	"github.com/kercylan98/minotaur/server"
-	"github.com/kercylan98/minotaur/utils/super"
+	"github.com/kercylan98/minotaur/utils/log"
 )
 
 // NewGateway 基于 server.Server 创建网关服务器
+//   - 未通过 WithRoute 设置路由规则时，所有数据包都将被转发至唯一的服务，与未支持路由规则前的行为相同
+//   - 存在多个服务时需要通过 WithRoute 设置路由规则，否则数据包将无法被转发
 func NewGateway(srv *server.Server, options ...Option) *Gateway {
 	gateway := &Gateway{
 		srv:             srv,
@@ -21,54 +23,85 @@ func NewGateway(srv *server.Server, options ...Option) *Gateway {
 type Gateway struct {
 	*EndpointManager                // 端点管理器
 	srv              *server.Server // 网关服务器核心
+	routes           []RouteRule    // 路由规则
+
+	discovery       func() (func(), error) // 开始通过注册中心发现端点
+	discoveryCancel func()                 // 停止通过注册中心发现端点
 }
 
 // Run 运行网关
 func (slf *Gateway) Run(addr string) error {
-	slf.srv.RegConnectionOpenedEvent(slf.onConnectionOpened)
+	if slf.discovery != nil {
+		cancel, err := slf.discovery()
+		if err != nil {
+			return err
+		}
+		slf.discoveryCancel = cancel
+	}
 	slf.srv.RegConnectionReceivePacketEvent(slf.onConnectionReceivePacket)
+	slf.srv.RegConnectionClosedEvent(slf.onConnectionClosed)
 	return slf.srv.Run(addr)
 }
 
 // Shutdown 关闭网关
 func (slf *Gateway) Shutdown() {
+	if slf.discoveryCancel != nil {
+		slf.discoveryCancel()
+	}
 	slf.srv.Shutdown()
 }
 
-// onConnectionOpened 连接打开事件
-func (slf *Gateway) onConnectionOpened(srv *server.Server, conn *server.Conn) {
-	endpoint, err := slf.GetEndpoint("test", conn)
-	if err != nil {
-		conn.Close()
-		return
+// Route 根据路由规则获取数据包应当被转发至的服务名称
+//   - 未设置路由规则时，将返回唯一拥有端点的服务，存在多个服务时将不匹配
+func (slf *Gateway) Route(conn *server.Conn, packet server.Packet) (service string, ok bool) {
+	if len(slf.routes) == 0 {
+		return slf.onlyService()
+	}
+	for _, rule := range slf.routes {
+		if service, ok = rule(conn, packet); ok {
+			return
+		}
 	}
-	endpoint.client.SetData(conn.GetID(), conn)
-	conn.SetData("endpoint", endpoint)
+	return "", false
 }
 
-// onConnectionReceivePacket 连接接收数据包事件
-func (slf *Gateway) onConnectionReceivePacket(srv *server.Server, conn *server.Conn, packet server.Packet) {
-	conn.GetData("endpoint").(*Endpoint).Write(PackGatewayPacket(conn.GetID(), packet.WebsocketType, packet.Data))
+// onlyService 获取唯一拥有端点的服务名称
+func (slf *Gateway) onlyService() (service string, ok bool) {
+	slf.endpoints.Atom(func(m map[string][]*Endpoint) {
+		for name, endpoints := range m {
+			if len(endpoints) == 0 {
+				continue
+			}
+			if ok {
+				service, ok = "", false
+				return
+			}
+			service, ok = name, true
+		}
+	})
+	return
 }
 
-// PackGatewayPacket 打包网关数据包
-func PackGatewayPacket(connID string, websocketType int, data []byte) server.Packet {
-	var gatewayPacket = Packet{
-		ConnID:        connID,
-		WebsocketType: websocketType,
-		Data:          data,
+// onConnectionReceivePacket 连接接收数据包事件
+//   - 每个数据包将根据路由规则转发至对应服务的端点，同一连接在同一服务下将总是使用相同的端点
+func (slf *Gateway) onConnectionReceivePacket(srv *server.Server, conn *server.Conn, packet server.Packet) {
+	service, ok := slf.Route(conn, packet)
+	if !ok {
+		log.Warn("Gateway", log.String("conn", conn.GetID()), log.Err(ErrRouteNotFound))
+		return
 	}
-	return server.Packet{
-		WebsocketType: websocketType,
-		Data:          super.MarshalJSON(&gatewayPacket),
+	endpoint, err := slf.GetEndpoint(service, conn)
+	if err != nil {
+		log.Warn("Gateway", log.String("conn", conn.GetID()), log.String("service", service), log.Err(err))
+		return
 	}
+	endpoint.Forward(conn, packet)
 }
 
-// UnpackGatewayPacket 解包网关数据包
-func UnpackGatewayPacket(packet server.Packet) Packet {
-	var gatewayPacket Packet
-	if err := super.UnmarshalJSON(packet.Data, &gatewayPacket); err != nil {
-		panic(err)
+// onConnectionClosed 连接关闭事件
+//   - 将释放该连接绑定的端点，并向这些端点发送断开连接的控制帧
+func (slf *Gateway) onConnectionClosed(srv *server.Server, conn *server.Conn, err any) {
+	for _, endpoint := range slf.ReleaseConn(conn) {
+		endpoint.write(Packet{Kind: PacketKindDisconnect, ConnID: conn.GetID()})
 	}
-	return gatewayPacket
 }
@@ -4,24 +4,37 @@ import (
 	"fmt"
 	"github.com/kercylan98/minotaur/server"
 	gateway2 "github.com/kercylan98/minotaur/server/gateway"
+	"github.com/kercylan98/minotaur/server/registry"
+	"github.com/kercylan98/minotaur/server/router"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestGateway_RunEndpointServer(t *testing.T) {
+	t.Skip("manual: runs an endpoint server on :8889 until the process exits")
 	srv := server.New(server.NetworkWebsocket)
+	gateway2.NewBackend(srv)
+	srv.RegConnectionOpenedEvent(func(srv *server.Server, conn *server.Conn) {
+		fmt.Println("endpoint client opened", conn.GetID(), conn.GetIP())
+	})
 	srv.RegConnectionReceivePacketEvent(func(srv *server.Server, conn *server.Conn, packet server.Packet) {
-		p := gateway2.UnpackGatewayPacket(packet)
-		fmt.Println("endpoint receive packet", string(p.Data))
+		fmt.Println("endpoint receive packet", string(packet.Data))
 		conn.Write(packet)
 	})
+	srv.RegConnectionClosedEvent(func(srv *server.Server, conn *server.Conn, err any) {
+		fmt.Println("endpoint client closed", conn.GetID(), err)
+	})
 	if err := srv.Run(":8889"); err != nil {
 		panic(err)
 	}
 }
 
 func TestGateway_Run(t *testing.T) {
+	t.Skip("manual: runs a gateway on :8888 routing to TestGateway_RunEndpointServer until the process exits")
 	srv := server.New(server.NetworkWebsocket)
-	gw := gateway2.NewGateway(srv)
+	gw := gateway2.NewGateway(srv, gateway2.WithRoute(gateway2.RouteTo("test")))
 	srv.RegStartFinishEvent(func(srv *server.Server) {
 		if err := gw.AddEndpoint(gateway2.NewEndpoint("test", "ws://127.0.0.1:8889")); err != nil {
 			panic(err)
@@ -31,3 +44,182 @@ func TestGateway_Run(t *testing.T) {
 		panic(err)
 	}
 }
+
+func TestGateway_Route(t *testing.T) {
+	codec := router.NewBinaryHeaderCodec(4, 0, 0, nil)
+	srv := server.New(server.NetworkNone)
+	gw := gateway2.NewGateway(srv, gateway2.WithRoute(
+		gateway2.RouteByMessageID("login", 1000, 1999, codec),
+		gateway2.RouteByMessageID("battle", 3000, 3999, codec),
+		gateway2.RouteByQuery("service"),
+		gateway2.RouteTo("lobby"),
+	))
+	conn := server.NewEmptyConn(srv)
+	for _, c := range []struct {
+		id      uint32
+		query   string
+		service string
+	}{
+		{1001, "", "login"},
+		{3999, "", "battle"},
+		{5000, "", "lobby"},
+		{5000, "chat", "chat"},
+	} {
+		conn.SetData("service", c.query)
+		service, ok := gw.Route(conn, server.Packet{Data: codec.Pack(router.MessageHeader{ID: c.id}, nil)})
+		if !ok || service != c.service {
+			t.Fatalf("message %d route to %s, expected %s", c.id, service, c.service)
+		}
+	}
+}
+
+func TestGateway_DefaultRoute(t *testing.T) {
+	srv := server.New(server.NetworkNone)
+	gw := gateway2.NewGateway(srv)
+	conn := server.NewEmptyConn(srv)
+	if _, ok := gw.Route(conn, server.Packet{}); ok {
+		t.Fatal("packets should not be routed without any service")
+	}
+	if err := gw.AddEndpoint(gateway2.NewEndpoint("lobby", "lobby", gateway2.WithEndpointTransport(newMemoryTransport()))); err != nil {
+		t.Fatal(err)
+	}
+	if service, ok := gw.Route(conn, server.Packet{}); !ok || service != "lobby" {
+		t.Fatalf("packets should be routed to the only service, got %s", service)
+	}
+	if err := gw.AddEndpoint(gateway2.NewEndpoint("battle", "battle", gateway2.WithEndpointTransport(newMemoryTransport()))); err != nil {
+		t.Fatal(err)
+	}
+	if service, ok := gw.Route(conn, server.Packet{}); ok {
+		t.Fatalf("packets should not be routed when multiple services exist, got %s", service)
+	}
+}
+
+func TestGateway_RouteSelector(t *testing.T) {
+	srv := server.New(server.NetworkNone)
+	var first = func(conn *server.Conn, endpoints []*gateway2.Endpoint) *gateway2.Endpoint {
+		return endpoints[0]
+	}
+	var last = func(conn *server.Conn, endpoints []*gateway2.Endpoint) *gateway2.Endpoint {
+		return endpoints[len(endpoints)-1]
+	}
+	gw := gateway2.NewGateway(srv, gateway2.WithSelector(first), gateway2.WithRouteSelector("battle", last))
+	var endpoints = map[string][]*gateway2.Endpoint{}
+	for _, service := range []string{"lobby", "battle"} {
+		for _, address := range []string{"a", "b"} {
+			endpoint := gateway2.NewEndpoint(service, service+"-"+address, gateway2.WithEndpointTransport(newMemoryTransport()))
+			if err := gw.AddEndpoint(endpoint); err != nil {
+				t.Fatal(err)
+			}
+			for deadline := time.Now().Add(time.Second); time.Now().Before(deadline) && !endpoint.IsHealthy(); {
+				time.Sleep(10 * time.Millisecond)
+			}
+			endpoints[service] = append(endpoints[service], endpoint)
+		}
+	}
+
+	conn := server.NewEmptyConn(srv)
+	if endpoint, err := gw.GetEndpoint("lobby", conn); err != nil || endpoint != endpoints["lobby"][0] {
+		t.Fatalf("lobby should use the default selector, got %v, err %v", endpoint, err)
+	}
+	if endpoint, err := gw.GetEndpoint("battle", conn); err != nil || endpoint != endpoints["battle"][1] {
+		t.Fatalf("battle should use the route selector, got %v, err %v", endpoint, err)
+	}
+}
+
+func TestMarshalPacket(t *testing.T) {
+	var packet = gateway2.Packet{Kind: gateway2.PacketKindData, Flags: 3, ConnID: "127.0.0.1:9999", WebsocketType: 1, Data: []byte("hello")}
+	p, err := gateway2.UnmarshalPacket(mustMarshalPacket(packet))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Kind != packet.Kind || p.Flags != packet.Flags || p.ConnID != packet.ConnID || p.WebsocketType != packet.WebsocketType || string(p.Data) != string(packet.Data) {
+		t.Fatalf("unmarshal packet %+v, expected %+v", p, packet)
+	}
+	if _, err = gateway2.UnmarshalPacket([]byte{gateway2.PacketVersion, 0, 0, 0, 0, 9}); err != gateway2.ErrIllegalPacket {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if _, err = gateway2.MarshalPacket(gateway2.Packet{ConnID: strings.Repeat("a", 65536)}); err != gateway2.ErrPacketConnIDTooLong {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if _, err = gateway2.MarshalPacket(gateway2.Packet{WebsocketType: 256}); err != gateway2.ErrPacketWebsocketType {
+		t.Fatalf("unexpected error %v", err)
+	}
+}
+
+func TestSelector(t *testing.T) {
+	a := gateway2.NewEndpoint("test", "ws://127.0.0.1:8001", gateway2.WithEndpointWeight(1))
+	b := gateway2.NewEndpoint("test", "ws://127.0.0.1:8002", gateway2.WithEndpointWeight(2))
+	endpoints := []*gateway2.Endpoint{a, b}
+
+	wrr := gateway2.WeightedRoundRobinSelector()
+	var counter = map[*gateway2.Endpoint]int{}
+	for i := 0; i < 30; i++ {
+		counter[wrr(nil, endpoints)]++
+	}
+	if counter[a] != 10 || counter[b] != 20 {
+		t.Fatalf("weighted round robin selected a=%d b=%d", counter[a], counter[b])
+	}
+
+	ch := gateway2.ConsistentHashSelector(10, func(conn *server.Conn) any { return "player-1" })
+	for i := 0; i < 10; i++ {
+		if ch(nil, endpoints) != ch(nil, endpoints) {
+			t.Fatal("consistent hash selected different endpoints for the same key")
+		}
+	}
+
+	// 两个地址的 crc32 哈希值相同，均应能够被选中，且选择结果与端点顺序无关
+	c := gateway2.NewEndpoint("test", "ws://127.0.0.1:8158/12158")
+	d := gateway2.NewEndpoint("test", "ws://127.0.0.1:8123/300123")
+	var key int
+	var forward = gateway2.ConsistentHashSelector(10, func(conn *server.Conn) any { return key })
+	var backward = gateway2.ConsistentHashSelector(10, func(conn *server.Conn) any { return key })
+	counter = map[*gateway2.Endpoint]int{}
+	for key = 0; key < 100; key++ {
+		selected := forward(nil, []*gateway2.Endpoint{c, d})
+		if selected != backward(nil, []*gateway2.Endpoint{d, c}) {
+			t.Fatal("consistent hash selected different endpoints for different endpoint orders")
+		}
+		counter[selected]++
+	}
+	if counter[c] == 0 || counter[d] == 0 {
+		t.Fatalf("consistent hash with colliding addresses selected c=%d d=%d", counter[c], counter[d])
+	}
+
+	if gateway2.LeastConnectionsSelector()(nil, endpoints) != a {
+		t.Fatal("least connections should select the first endpoint when all are idle")
+	}
+}
+
+func TestEndpointManager_Discover(t *testing.T) {
+	r := registry.NewMemory()
+	_ = r.Register(server.ServiceInstance{ID: 1, Type: "lobby", Address: "memory://lobby-1", Metadata: map[string]string{"weight": "3"}}, 0)
+	_ = r.Register(server.ServiceInstance{ID: 2, Type: "battle", Address: "memory://battle-1"}, 0)
+
+	em := gateway2.NewEndpointManager()
+	cancel, err := em.Discover(r, func(instance server.ServiceInstance) *gateway2.Endpoint {
+		var options = []gateway2.EndpointOption{gateway2.WithEndpointTransport(newMemoryTransport())}
+		if weight, err := strconv.Atoi(instance.Metadata["weight"]); err == nil {
+			options = append(options, gateway2.WithEndpointWeight(weight))
+		}
+		return gateway2.NewEndpoint(instance.Type, instance.Address, options...)
+	}, "lobby")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cancel()
+	if endpoints := em.GetEndpoints("lobby"); len(endpoints) != 1 || endpoints[0].GetWeight() != 3 {
+		t.Fatalf("discover lobby endpoints: %v", endpoints)
+	}
+	if endpoints := em.GetEndpoints("battle"); len(endpoints) != 0 {
+		t.Fatal("battle should not be discovered")
+	}
+
+	_ = r.Register(server.ServiceInstance{ID: 3, Type: "lobby", Address: "memory://lobby-2"}, 0)
+	if endpoints := em.GetEndpoints("lobby"); len(endpoints) != 2 {
+		t.Fatalf("lobby endpoints should be 2, got %d", len(endpoints))
+	}
+	_ = r.Deregister(server.ServiceInstance{ID: 1})
+	if endpoints := em.GetEndpoints("lobby"); len(endpoints) != 1 || endpoints[0].GetAddress() != "memory://lobby-2" {
+		t.Fatal("deregistered endpoint should be removed")
+	}
+}
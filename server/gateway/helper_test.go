@@ -0,0 +1,75 @@
+package gateway_test
+
+import (
+	"fmt"
+	"github.com/kercylan98/minotaur/server"
+	gateway2 "github.com/kercylan98/minotaur/server/gateway"
+	"net"
+	"testing"
+	"time"
+)
+
+// runEndpointServer 运行端点服务器并等待其开始侦听，返回的 stop 函数将关闭服务器并等待其退出
+//   - path 为 WebSocket 服务器的路由，其他网络类型应为空，路由将附加端口号避免在同一进程中重复注册
+//   - 返回的 addr 包含附加端口号后的路由
+func runEndpointServer(t *testing.T, srv *server.Server, path string) (addr string, stop func()) {
+	return runServer(t, srv, path, srv.Run)
+}
+
+// runGateway 运行网关并等待其开始侦听，参数及返回值与 runEndpointServer 相同
+func runGateway(t *testing.T, gw *gateway2.Gateway, srv *server.Server, path string) (addr string, stop func()) {
+	return runServer(t, srv, path, gw.Run)
+}
+
+// runServer 通过 run 运行服务器并等待其开始侦听
+func runServer(t *testing.T, srv *server.Server, path string, run func(addr string) error) (addr string, stop func()) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var host = listener.Addr().String()
+	_ = listener.Close()
+	addr = host
+	if len(path) > 0 {
+		addr = fmt.Sprintf("%s%s-%d", host, path, listener.Addr().(*net.TCPAddr).Port)
+	}
+
+	var started, stopped = make(chan struct{}), make(chan struct{})
+	srv.RegStartFinishEvent(func(srv *server.Server) {
+		close(started)
+	})
+	go func() {
+		_ = run(addr)
+		close(stopped)
+	}()
+	select {
+	case <-started:
+	case <-time.After(time.Second * 5):
+		t.Fatal("endpoint server start timeout")
+	}
+	var deadline = time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		if conn, err := net.Dial("tcp", host); err == nil {
+			_ = conn.Close()
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return addr, func() {
+		select {
+		case <-stopped:
+		default:
+			srv.Shutdown()
+			<-stopped
+		}
+	}
+}
+
+// mustMarshalPacket 编码网关数据包，编码失败时将会 panic
+func mustMarshalPacket(packet gateway2.Packet) []byte {
+	data, err := gateway2.MarshalPacket(packet)
+	if err != nil {
+		panic(err)
+	}
+	return data
+}
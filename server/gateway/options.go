@@ -1,12 +1,91 @@
 package gateway
 
+import (
+	"github.com/kercylan98/minotaur/server"
+	"time"
+)
+
 // Option 网关选项
 type Option func(gateway *Gateway)
 
 // WithEndpointSelector 设置端点选择器
 //   - 默认情况下，网关会随机选择一个端点作为目标，如果需要自定义端点选择器，可以通过该选项设置
+//   - 当选择需要依赖连接信息时，应使用 WithSelector
 func WithEndpointSelector(selector func([]*Endpoint) *Endpoint) Option {
+	return WithSelector(func(conn *server.Conn, endpoints []*Endpoint) *Endpoint {
+		return selector(endpoints)
+	})
+}
+
+// WithSelector 设置端点选择器
+//   - 内置的选择器包括：RandomSelector、WeightedRoundRobinSelector、LeastConnectionsSelector、ConsistentHashSelector
+//   - 需要针对特定服务使用不同的选择器时，应使用 WithRouteSelector
+func WithSelector(selector EndpointSelector) Option {
 	return func(gateway *Gateway) {
 		gateway.EndpointManager.selector = selector
 	}
 }
+
+// WithRouteSelector 设置路由至特定服务时使用的端点选择器，将覆盖通过 WithSelector 设置的默认选择器
+//   - 连接首次访问服务及会话迁移时均将采用该选择器
+//
+// 例如大厅服务随机选择端点，战斗服务根据房间号选择端点：
+//
+//	gateway.WithRoute(
+//		gateway.RouteByMessageID("battle", 3000, 3999, codec),
+//		gateway.RouteTo("lobby"),
+//	),
+//	gateway.WithRouteSelector("battle", gateway.ConsistentHashSelector(10, roomKey)),
+func WithRouteSelector(service string, selector EndpointSelector) Option {
+	return func(gateway *Gateway) {
+		gateway.EndpointManager.selectors[service] = selector
+	}
+}
+
+// WithRoute 设置网关路由规则
+//   - 未设置任何路由规则时，所有数据包都将被转发至唯一的服务
+//   - 路由规则将按照设置的顺序进行匹配，首个匹配成功的规则将决定数据包被转发至的服务
+//   - 可多次使用该选项，后设置的规则将追加在已有规则之后
+//
+// 例如登录、大厅、战斗服务分别处理不同范围的消息 ID 时：
+//
+//	gateway.WithRoute(
+//		gateway.RouteByMessageID("login", 1000, 1999, codec),
+//		gateway.RouteByMessageID("lobby", 2000, 2999, codec),
+//		gateway.RouteByMessageID("battle", 3000, 3999, codec),
+//	)
+func WithRoute(rules ...RouteRule) Option {
+	return func(gateway *Gateway) {
+		gateway.routes = append(gateway.routes, rules...)
+	}
+}
+
+// WithRouteFunc 通过自定义函数设置网关路由规则
+//   - 当 handle 返回空字符串时视为不匹配
+func WithRouteFunc(handle func(conn *server.Conn, packet server.Packet) string) Option {
+	return WithRoute(func(conn *server.Conn, packet server.Packet) (string, bool) {
+		service := handle(conn, packet)
+		return service, len(service) > 0
+	})
+}
+
+// WithMigrationTimeout 设置会话迁移时等待端点确认的超时时间，默认为 DefaultMigrationTimeout
+//   - 超时后客户端连接将被关闭
+func WithMigrationTimeout(timeout time.Duration) Option {
+	return func(gateway *Gateway) {
+		if timeout > 0 {
+			gateway.EndpointManager.migrationTimeout = timeout
+		}
+	}
+}
+
+// WithDiscovery 通过注册中心自动发现端点，网关运行时将通过 EndpointManager.Discover 开始监听
+//   - generator 为 nil 时将采用 DefaultEndpointGenerator
+//   - serverTypes 为空时将监听所有类型的服务实例
+func WithDiscovery(registry server.Registry, generator EndpointGenerator, serverTypes ...string) Option {
+	return func(gateway *Gateway) {
+		gateway.discovery = func() (func(), error) {
+			return gateway.Discover(registry, generator, serverTypes...)
+		}
+	}
+}
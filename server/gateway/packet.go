@@ -1,7 +1,137 @@
 package gateway
 
+import (
+	"encoding/binary"
+	"github.com/gorilla/websocket"
+	"github.com/kercylan98/minotaur/server"
+	"math"
+)
+
+const (
+	// PacketVersion 网关数据包格式版本
+	PacketVersion byte = 1
+
+	packetHeaderSize = 6 // [版本 1B][类型 1B][标记位 1B][websocket 类型 1B][连接 ID 长度 2B]
+)
+
+const (
+	// PacketKindData 数据帧，携带客户端与端点之间转发的数据
+	PacketKindData PacketKind = iota
+	// PacketKindConnect 控制帧，客户端首次访问端点时由网关发出，Data 为客户端 IP
+	PacketKindConnect
+	// PacketKindDisconnect 控制帧，客户端断开连接时由网关发出；由端点发出时网关将断开客户端的连接
+	PacketKindDisconnect
+	// PacketKindPing 控制帧，网关对端点的健康探测，Data 为探测发出的时间
+	PacketKindPing
+	// PacketKindPong 控制帧，端点对 PacketKindPing 的响应，需要携带与探测相同的 Data
+	PacketKindPong
+	// PacketKindAttach 控制帧，连接从其他端点迁移至该端点时由网关发出，Data 为 JSON 格式的 AttachMetadata
+	PacketKindAttach
+	// PacketKindAttachAck 控制帧，端点完成会话附加后需要返回该控制帧，网关将在收到后发送缓冲的数据包
+	PacketKindAttachAck
+)
+
+// AttachMetadata 会话附加控制帧携带的连接元数据
+type AttachMetadata struct {
+	IP      string `json:"ip"`      // 客户端 IP
+	Service string `json:"service"` // 服务名称
+	From    string `json:"from"`    // 迁移前的端点地址
+}
+
+// PacketKind 网关数据包类型
+type PacketKind byte
+
+// Packet 网关数据包
+//   - 数据包格式：[版本 1B][类型 1B][标记位 1B][websocket 类型 1B][连接 ID 长度 2B][连接 ID][数据]
 type Packet struct {
-	ConnID        string
-	WebsocketType int
-	Data          []byte
+	Kind          PacketKind // 数据包类型
+	Flags         byte       // 标记位，网关不会对其进行处理，可用于网关与端点之间的自定义扩展
+	ConnID        string     // 客户端连接 ID
+	WebsocketType int        // 客户端数据包的 websocket 消息类型
+	Data          []byte     // 数据
+}
+
+// IsControl 是否为控制帧
+func (slf Packet) IsControl() bool {
+	return slf.Kind != PacketKindData
+}
+
+// MarshalPacket 将网关数据包编码为二进制格式
+//   - 当连接 ID 超过 65535 字节或 websocket 消息类型超出 0 ~ 255 的范围时将返回错误
+func MarshalPacket(packet Packet) ([]byte, error) {
+	if len(packet.ConnID) > math.MaxUint16 {
+		return nil, ErrPacketConnIDTooLong
+	}
+	if packet.WebsocketType < 0 || packet.WebsocketType > math.MaxUint8 {
+		return nil, ErrPacketWebsocketType
+	}
+	var data = make([]byte, packetHeaderSize+len(packet.ConnID)+len(packet.Data))
+	data[0] = PacketVersion
+	data[1] = byte(packet.Kind)
+	data[2] = packet.Flags
+	data[3] = byte(packet.WebsocketType)
+	binary.BigEndian.PutUint16(data[4:], uint16(len(packet.ConnID)))
+	copy(data[packetHeaderSize:], packet.ConnID)
+	copy(data[packetHeaderSize+len(packet.ConnID):], packet.Data)
+	return data, nil
+}
+
+// UnmarshalPacket 从二进制格式中解码网关数据包
+//   - 解码后的 Data 将引用 data 的内存
+func UnmarshalPacket(data []byte) (packet Packet, err error) {
+	if len(data) < packetHeaderSize {
+		return packet, ErrIllegalPacket
+	}
+	if data[0] != PacketVersion {
+		return packet, ErrPacketVersion
+	}
+	var connIDSize = int(binary.BigEndian.Uint16(data[4:]))
+	if len(data) < packetHeaderSize+connIDSize {
+		return packet, ErrIllegalPacket
+	}
+	packet.Kind = PacketKind(data[1])
+	packet.Flags = data[2]
+	packet.WebsocketType = int(data[3])
+	packet.ConnID = string(data[packetHeaderSize : packetHeaderSize+connIDSize])
+	packet.Data = data[packetHeaderSize+connIDSize:]
+	return packet, nil
+}
+
+// PackGatewayPacket 打包网关数据帧
+//   - 错误与 MarshalPacket 相同
+func PackGatewayPacket(connID string, websocketType int, data []byte) (server.Packet, error) {
+	packet, err := MarshalPacket(Packet{
+		Kind:          PacketKindData,
+		ConnID:        connID,
+		WebsocketType: websocketType,
+		Data:          data,
+	})
+	if err != nil {
+		return server.Packet{}, err
+	}
+	return server.Packet{WebsocketType: websocket.BinaryMessage, Data: packet}, nil
+}
+
+// PackGatewayControlPacket 打包网关控制帧
+//   - 错误与 MarshalPacket 相同
+func PackGatewayControlPacket(kind PacketKind, connID string, data []byte) (server.Packet, error) {
+	packet, err := MarshalPacket(Packet{
+		Kind:   kind,
+		ConnID: connID,
+		Data:   data,
+	})
+	if err != nil {
+		return server.Packet{}, err
+	}
+	return server.Packet{WebsocketType: websocket.BinaryMessage, Data: packet}, nil
+}
+
+// UnpackGatewayPacket 解包网关数据包
+//   - 当数据包格式不正确时将会 panic
+func UnpackGatewayPacket(packet server.Packet) Packet {
+	gatewayPacket, err := UnmarshalPacket(packet.Data)
+	if err != nil {
+		panic(err)
+	}
+	return gatewayPacket
 }
@@ -0,0 +1,47 @@
+package gateway
+
+import (
+	"github.com/kercylan98/minotaur/server"
+	"github.com/kercylan98/minotaur/server/router"
+)
+
+// RouteRule 网关路由规则，用于决定数据包应当被转发至哪个服务
+//   - service 为端点名称，ok 为 false 时表示该规则不匹配，将继续尝试下一条规则
+//   - 路由规则将针对每一个数据包进行匹配，因此同一个连接可以同时与多个服务进行通讯
+type RouteRule func(conn *server.Conn, packet server.Packet) (service string, ok bool)
+
+// RouteByMessageID 根据消息 ID 的范围进行路由
+//   - 当消息 ID 处于 [min, max] 范围内时，数据包将被转发至 service
+//   - codec 用于从数据包中解析消息 ID，解析失败时视为不匹配
+func RouteByMessageID(service string, min, max uint32, codec router.HeaderCodec) RouteRule {
+	return func(conn *server.Conn, packet server.Packet) (string, bool) {
+		header, _, err := codec.Unpack(packet.Data)
+		if err != nil {
+			return "", false
+		}
+		return service, header.ID >= min && header.ID <= max
+	}
+}
+
+// RouteByQuery 根据 WebSocket 连接建立时的查询参数进行路由，参数值即为服务名称
+func RouteByQuery(key string) RouteRule {
+	return func(conn *server.Conn, packet server.Packet) (string, bool) {
+		service, ok := conn.GetData(key).(string)
+		return service, ok && len(service) > 0
+	}
+}
+
+// RouteByHeader 根据 WebSocket 连接建立时的请求头进行路由，请求头的值即为服务名称
+func RouteByHeader(key string) RouteRule {
+	return func(conn *server.Conn, packet server.Packet) (string, bool) {
+		service := conn.GetHeader(key)
+		return service, len(service) > 0
+	}
+}
+
+// RouteTo 将所有数据包路由至特定服务，通常作为最后一条规则进行兜底
+func RouteTo(service string) RouteRule {
+	return func(conn *server.Conn, packet server.Packet) (string, bool) {
+		return service, true
+	}
+}
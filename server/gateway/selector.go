@@ -0,0 +1,108 @@
+package gateway
+
+import (
+	"github.com/kercylan98/minotaur/server"
+	"github.com/kercylan98/minotaur/utils/hash"
+	"github.com/kercylan98/minotaur/utils/random"
+	"hash/crc32"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// EndpointSelector 端点选择器，用于在连接首次访问服务时从可用的端点中选择一个端点
+//   - endpoints 仅包含健康的端点，且总是不为空
+type EndpointSelector func(conn *server.Conn, endpoints []*Endpoint) *Endpoint
+
+// RandomSelector 随机选择端点，这是默认的端点选择器
+func RandomSelector() EndpointSelector {
+	return func(conn *server.Conn, endpoints []*Endpoint) *Endpoint {
+		return endpoints[random.Int(0, len(endpoints)-1)]
+	}
+}
+
+// WeightedRoundRobinSelector 平滑加权轮询选择端点
+//   - 权重通过 WithEndpointWeight 进行设置
+func WeightedRoundRobinSelector() EndpointSelector {
+	var mutex sync.Mutex
+	var current = map[*Endpoint]int{}
+	return func(conn *server.Conn, endpoints []*Endpoint) *Endpoint {
+		mutex.Lock()
+		defer mutex.Unlock()
+		var total int
+		var selected *Endpoint
+		for _, endpoint := range endpoints {
+			current[endpoint] += endpoint.weight
+			total += endpoint.weight
+			if selected == nil || current[endpoint] > current[selected] {
+				selected = endpoint
+			}
+		}
+		current[selected] -= total
+		if len(current) > len(endpoints)*2 {
+			var available = make(map[*Endpoint]bool, len(endpoints))
+			for _, endpoint := range endpoints {
+				available[endpoint] = true
+			}
+			for endpoint := range current {
+				if !available[endpoint] {
+					delete(current, endpoint)
+				}
+			}
+		}
+		return selected
+	}
+}
+
+// LeastConnectionsSelector 选择正在使用的客户端连接数量最少的端点
+func LeastConnectionsSelector() EndpointSelector {
+	return func(conn *server.Conn, endpoints []*Endpoint) *Endpoint {
+		var selected = endpoints[0]
+		var least = selected.GetConnectionCount()
+		for _, endpoint := range endpoints[1:] {
+			if count := endpoint.GetConnectionCount(); count < least {
+				selected, least = endpoint, count
+			}
+		}
+		return selected
+	}
+}
+
+// ConsistentHashSelector 基于 hash.Consistency 的一致性哈希选择端点
+//   - key 用于获取连接的哈希键，例如玩家 ID，以确保相同玩家总是被分配到相同的端点
+//   - replicas 为每个端点的虚拟节点数量
+//   - 端点将按地址排序后加入哈希环，因此相同的端点集合总是得到相同的选择结果
+func ConsistentHashSelector(replicas int, key func(conn *server.Conn) any) EndpointSelector {
+	var mutex sync.Mutex
+	var signature string
+	var consistency *hash.Consistency
+	var nodes map[int]*Endpoint
+	return func(conn *server.Conn, endpoints []*Endpoint) *Endpoint {
+		var sorted = make([]*Endpoint, len(endpoints))
+		copy(sorted, endpoints)
+		sort.Slice(sorted, func(i, j int) bool {
+			return sorted[i].address < sorted[j].address
+		})
+		var addresses = make([]string, len(sorted))
+		for i, endpoint := range sorted {
+			addresses[i] = endpoint.address
+		}
+		mutex.Lock()
+		defer mutex.Unlock()
+		if s := strings.Join(addresses, ","); s != signature || consistency == nil {
+			signature = s
+			consistency = hash.NewConsistency(replicas)
+			nodes = make(map[int]*Endpoint, len(sorted))
+			for _, endpoint := range sorted {
+				// 地址的哈希值发生碰撞时顺延至下一个未被占用的节点，按地址排序后添加以确保结果与端点顺序无关
+				var node = int(crc32.ChecksumIEEE([]byte(endpoint.address)))
+				for _, exist := nodes[node]; exist; _, exist = nodes[node] {
+					node++
+				}
+				nodes[node] = endpoint
+				consistency.AddNode(node)
+			}
+		}
+		return nodes[consistency.PickNode(key(conn))]
+	}
+}
@@ -0,0 +1,230 @@
+package gateway
+
+import (
+	"encoding/binary"
+	"github.com/gorilla/websocket"
+	"github.com/kercylan98/minotaur/server"
+	"github.com/kercylan98/minotaur/server/client"
+	"github.com/kercylan98/minotaur/utils/log"
+	"github.com/xtaci/kcp-go/v5"
+	"hash/crc32"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Transport 网关与端点之间的传输层
+type Transport interface {
+	// Run 建立与端点的连接
+	//   - receive 将在收到端点发送的网关数据包时被调用
+	//   - closed 将在连接断开时被调用
+	Run(receive func(data []byte), closed func(err any)) error
+	// Write 向端点写入网关数据包
+	//   - key 用于在多路复用的连接池中选择连接，相同 key 的数据包将保持顺序
+	Write(key string, data []byte)
+	// Close 关闭与端点的连接
+	Close()
+	// IsConnected 是否已连接
+	IsConnected() bool
+}
+
+// TransportCodec 获取 TCP、KCP 传输层所使用的数据包编解码器
+//   - 端点服务器需要通过 server.WithPacketCodec 使用相同的编解码器
+func TransportCodec() server.PacketCodec {
+	return server.NewLengthFieldCodec(4, binary.BigEndian, 0)
+}
+
+// NewWebsocketTransport 创建基于 WebSocket 的传输层，这是端点默认的传输层
+//   - 端点服务器需要以 server.NetworkWebsocket 运行
+func NewWebsocketTransport(address string) Transport {
+	transport := &websocketTransport{client: client.NewWebsocket(address)}
+	transport.client.RegConnectionReceivePacketEvent(func(conn *client.Websocket, packet server.Packet) {
+		transport.receive(packet.Data)
+	})
+	transport.client.RegConnectionClosedEvent(func(conn *client.Websocket, err any) {
+		transport.closed(err)
+	})
+	return transport
+}
+
+// websocketTransport 基于 WebSocket 的传输层
+type websocketTransport struct {
+	client  *client.Websocket
+	receive func(data []byte)
+	closed  func(err any)
+}
+
+func (slf *websocketTransport) Run(receive func(data []byte), closed func(err any)) error {
+	slf.receive, slf.closed = receive, closed
+	return slf.client.Run()
+}
+
+func (slf *websocketTransport) Write(key string, data []byte) {
+	slf.client.Write(server.Packet{WebsocketType: websocket.BinaryMessage, Data: data})
+}
+
+func (slf *websocketTransport) Close() {
+	slf.client.Close()
+}
+
+func (slf *websocketTransport) IsConnected() bool {
+	return slf.client.IsConnected()
+}
+
+// NewTCPTransport 创建基于 TCP 的传输层
+//   - 将与端点建立 poolSize 个连接，客户端数据包将根据连接 ID 分配至固定的连接上多路复用
+//   - 端点服务器需要以 server.NetworkTcp 运行，并通过 server.WithPacketCodec 使用 TransportCodec
+func NewTCPTransport(address string, poolSize int) Transport {
+	return newStreamTransport(poolSize, func() (net.Conn, error) {
+		return net.DialTimeout("tcp", address, time.Second*5)
+	})
+}
+
+// NewKCPTransport 创建基于 KCP 的传输层
+//   - 将与端点建立 poolSize 个连接，客户端数据包将根据连接 ID 分配至固定的连接上多路复用
+//   - 端点服务器需要以 server.NetworkKcp 运行，并通过 server.WithPacketCodec 使用 TransportCodec
+func NewKCPTransport(address string, poolSize int) Transport {
+	return newStreamTransport(poolSize, func() (net.Conn, error) {
+		return kcp.DialWithOptions(address, nil, 0, 0)
+	})
+}
+
+func newStreamTransport(poolSize int, dial func() (net.Conn, error)) *streamTransport {
+	if poolSize <= 0 {
+		poolSize = 1
+	}
+	return &streamTransport{
+		dial:  dial,
+		size:  poolSize,
+		codec: TransportCodec(),
+	}
+}
+
+// streamTransport 基于流式连接池的传输层
+type streamTransport struct {
+	dial    func() (net.Conn, error)
+	size    int
+	codec   server.PacketCodec
+	session atomic.Pointer[streamSession]
+}
+
+// streamSession 一次成功建立的连接池
+type streamSession struct {
+	streams []*transportStream
+	done    chan struct{}
+	once    sync.Once
+	closed  func(err any)
+}
+
+// transportStream 连接池中的连接
+type transportStream struct {
+	conn  net.Conn
+	queue chan []byte
+}
+
+func (slf *streamTransport) Run(receive func(data []byte), closed func(err any)) error {
+	var session = &streamSession{done: make(chan struct{}), closed: closed}
+	for i := 0; i < slf.size; i++ {
+		conn, err := slf.dial()
+		if err != nil {
+			for _, stream := range session.streams {
+				_ = stream.conn.Close()
+			}
+			return err
+		}
+		session.streams = append(session.streams, &transportStream{conn: conn, queue: make(chan []byte, 1024)})
+	}
+	slf.session.Store(session)
+	for _, stream := range session.streams {
+		go slf.readLoop(session, stream, receive)
+		go slf.writeLoop(session, stream)
+	}
+	return nil
+}
+
+func (slf *streamTransport) readLoop(session *streamSession, stream *transportStream, receive func(data []byte)) {
+	var buf = make([]byte, 4096)
+	var unpacked []byte
+	for {
+		n, err := stream.conn.Read(buf)
+		if err != nil {
+			slf.fail(session, err)
+			return
+		}
+		unpacked = append(unpacked, buf[:n]...)
+		for {
+			packet, size, err := slf.codec.Decode(unpacked)
+			if err != nil {
+				slf.fail(session, err)
+				return
+			}
+			if size == 0 {
+				break
+			}
+			unpacked = unpacked[size:]
+			receive(packet)
+		}
+	}
+}
+
+func (slf *streamTransport) writeLoop(session *streamSession, stream *transportStream) {
+	for {
+		select {
+		case data := <-stream.queue:
+			if _, err := stream.conn.Write(data); err != nil {
+				slf.fail(session, err)
+				return
+			}
+		case <-session.done:
+			return
+		}
+	}
+}
+
+// fail 关闭连接池中的所有连接，任一连接断开都将导致整个连接池被关闭
+func (slf *streamTransport) fail(session *streamSession, err any) {
+	session.once.Do(func() {
+		slf.session.CompareAndSwap(session, nil)
+		close(session.done)
+		for _, stream := range session.streams {
+			_ = stream.conn.Close()
+		}
+		session.closed(err)
+	})
+}
+
+// Write 将数据包加入连接的写入队列
+//   - 该函数通常在网关的消息分发中调用，因此不会阻塞等待；写入队列已满时表示端点无法及时处理，连接池将被关闭，客户端将被迁移至其他端点
+func (slf *streamTransport) Write(key string, data []byte) {
+	var session = slf.session.Load()
+	if session == nil {
+		return
+	}
+	packet, err := slf.codec.Encode(data)
+	if err != nil {
+		log.Warn("Gateway", log.String("transport", "stream"), log.String("key", key), log.Err(err))
+		return
+	}
+	var stream = session.streams[0]
+	if len(key) > 0 {
+		stream = session.streams[crc32.ChecksumIEEE([]byte(key))%uint32(len(session.streams))]
+	}
+	select {
+	case stream.queue <- packet:
+	case <-session.done:
+	default:
+		log.Warn("Gateway", log.String("transport", "stream"), log.String("stream", stream.conn.RemoteAddr().String()), log.Err(ErrTransportQueueFull))
+		slf.fail(session, ErrTransportQueueFull)
+	}
+}
+
+func (slf *streamTransport) Close() {
+	if session := slf.session.Load(); session != nil {
+		slf.fail(session, ErrTransportClosed)
+	}
+}
+
+func (slf *streamTransport) IsConnected() bool {
+	return slf.session.Load() != nil
+}
@@ -0,0 +1,149 @@
+package gateway_test
+
+import (
+	"github.com/kercylan98/minotaur/server"
+	gateway2 "github.com/kercylan98/minotaur/server/gateway"
+	"net"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestNewTCPTransport(t *testing.T) {
+	srv := server.New(server.NetworkTcp,
+		server.WithPacketCodec(gateway2.TransportCodec()),
+		server.WithConnectionRateLimit(2, 0, server.RateLimitActionDrop),
+	)
+	gateway2.NewBackend(srv)
+	var opened, closed = make(chan string, 8), make(chan string, 8)
+	srv.RegConnectionOpenedEvent(func(srv *server.Server, conn *server.Conn) {
+		opened <- conn.GetID()
+	})
+	srv.RegConnectionClosedEvent(func(srv *server.Server, conn *server.Conn, err any) {
+		closed <- conn.GetID()
+	})
+	srv.RegConnectionReceivePacketEvent(func(srv *server.Server, conn *server.Conn, packet server.Packet) {
+		if string(packet.Data) == "close" {
+			conn.Close()
+			return
+		}
+		conn.Write(server.Packet{WebsocketType: packet.WebsocketType, Data: append([]byte(conn.GetID()+":"), packet.Data...)})
+	})
+	addr, stop := runEndpointServer(t, srv, "")
+	defer stop()
+
+	var received = make(chan gateway2.Packet, 16)
+	var transportClosed = make(chan struct{})
+	var transportCloseOnce sync.Once
+	transport := gateway2.NewTCPTransport(addr, 2)
+	if err := transport.Run(func(data []byte) {
+		p, err := gateway2.UnmarshalPacket(data)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		received <- p
+	}, func(err any) {
+		transportCloseOnce.Do(func() { close(transportClosed) })
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	var wait = func(ch chan string, expected ...string) {
+		var got = map[string]bool{}
+		for len(got) < len(expected) {
+			select {
+			case id := <-ch:
+				got[id] = true
+			case <-time.After(time.Second * 5):
+				t.Fatalf("wait %v timeout, got %v", expected, got)
+			}
+		}
+		for _, id := range expected {
+			if !got[id] {
+				t.Fatalf("expected %s, got %v", id, got)
+			}
+		}
+	}
+	var receive = func() gateway2.Packet {
+		select {
+		case p := <-received:
+			return p
+		case <-time.After(time.Second * 5):
+			t.Fatal("receive timeout")
+		}
+		return gateway2.Packet{}
+	}
+
+	for _, id := range []string{"client-a", "client-b"} {
+		transport.Write(id, mustMarshalPacket(gateway2.Packet{Kind: gateway2.PacketKindConnect, ConnID: id, Data: []byte("127.0.0.1")}))
+		transport.Write(id, mustMarshalPacket(gateway2.Packet{ConnID: id, WebsocketType: 2, Data: []byte("hello")}))
+	}
+	wait(opened, "client-a", "client-b")
+	var replies = map[string]string{}
+	for i := 0; i < 2; i++ {
+		p := receive()
+		replies[p.ConnID] = string(p.Data)
+	}
+	for _, id := range []string{"client-a", "client-b"} {
+		if replies[id] != id+":hello" {
+			t.Fatalf("client %s received %q", id, replies[id])
+		}
+	}
+
+	transport.Write("client-a", mustMarshalPacket(gateway2.Packet{ConnID: "client-a", WebsocketType: 2, Data: []byte("close")}))
+	wait(closed, "client-a")
+	if p := receive(); p.Kind != gateway2.PacketKindDisconnect || p.ConnID != "client-a" {
+		t.Fatalf("endpoint closed client-a, gateway received %+v", p)
+	}
+
+	transport.Close()
+	wait(closed, "client-b")
+	select {
+	case <-transportClosed:
+	case <-time.After(time.Second * 5):
+		t.Fatal("transport closed event timeout")
+	}
+}
+
+func TestNewTCPTransport_QueueFull(t *testing.T) {
+	// 端点接受连接但不读取任何数据，写入队列将被填满
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+
+	var closed = make(chan any, 1)
+	transport := gateway2.NewTCPTransport(listener.Addr().String(), 1)
+	if err := transport.Run(func(data []byte) {}, func(err any) {
+		closed <- err
+	}); err != nil {
+		t.Fatal(err)
+	}
+	var data = make([]byte, 64*1024)
+	var start = time.Now()
+	for i := 0; i < 4096 && transport.IsConnected(); i++ {
+		transport.Write("client-a", data)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second*2 {
+		t.Fatalf("write blocked for %s", elapsed)
+	}
+	select {
+	case err := <-closed:
+		if err != gateway2.ErrTransportQueueFull {
+			t.Fatalf("transport closed with %v", err)
+		}
+	case <-time.After(time.Second * 5):
+		t.Fatal("transport should be closed when the write queue is full")
+	}
+}
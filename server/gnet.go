@@ -1,7 +1,6 @@
 package server
 
 import (
-	"bytes"
 	"github.com/panjf2000/gnet"
 	"time"
 )
@@ -11,6 +10,7 @@ type gNet struct {
 }
 
 func (slf *gNet) OnInitComplete(server gnet.Server) (action gnet.Action) {
+	slf.hotUpgradeDone(string(slf.network), slf.addr)
 	return
 }
 
@@ -19,14 +19,21 @@ func (slf *gNet) OnShutdown(server gnet.Server) {
 }
 
 func (slf *gNet) OnOpened(c gnet.Conn) (out []byte, action gnet.Action) {
+	ip := addrIP(c.RemoteAddr())
+	if !slf.accepting() || !slf.acquireIP(ip) {
+		return nil, gnet.Close
+	}
 	conn := newGNetConn(slf.Server, c)
+	conn.acquiredIP = ip
 	c.SetContext(conn)
 	slf.OnConnectionOpenedEvent(conn)
 	return
 }
 
 func (slf *gNet) OnClosed(c gnet.Conn, err error) (action gnet.Action) {
-	slf.OnConnectionClosedEvent(c.Context().(*Conn), err)
+	if conn, ok := c.Context().(*Conn); ok {
+		slf.OnConnectionClosedEvent(conn, err)
+	}
 	return
 }
 
@@ -39,10 +46,16 @@ func (slf *gNet) AfterWrite(c gnet.Conn, b []byte) {
 }
 
 func (slf *gNet) React(packet []byte, c gnet.Conn) (out []byte, action gnet.Action) {
-	PushPacketMessage(slf.Server, c.Context().(*Conn), append(bytes.Clone(packet), 0))
+	if err := c.Context().(*Conn).receive(packet); err != nil {
+		return nil, gnet.Close
+	}
 	return nil, gnet.None
 }
 
 func (slf *gNet) Tick() (delay time.Duration, action gnet.Action) {
-	return
+	if !slf.keepaliveEnabled() {
+		return time.Second, gnet.None
+	}
+	slf.checkKeepalive()
+	return slf.keepaliveCheckInterval(), gnet.None
 }
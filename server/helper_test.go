@@ -0,0 +1,55 @@
+package server_test
+
+import (
+	"github.com/kercylan98/minotaur/server"
+	"net"
+	"time"
+)
+
+// runServer 运行服务器并等待其启动完成，返回的 stop 函数将关闭服务器并等待其退出，服务器已退出时将直接返回
+//   - addr 为空时将以 NetworkNone 的方式运行
+func runServer(srv *server.Server, addr ...string) (stop func()) {
+	var started, stopped = make(chan struct{}), make(chan struct{})
+	srv.RegStartFinishEvent(func(srv *server.Server) {
+		close(started)
+	})
+	go func() {
+		if len(addr) > 0 {
+			_ = srv.Run(addr[0])
+		} else {
+			_ = srv.RunNone()
+		}
+		close(stopped)
+	}()
+	<-started
+	return func() {
+		select {
+		case <-stopped:
+		default:
+			srv.Shutdown()
+			<-stopped
+		}
+	}
+}
+
+// freeAddr 获取一个可用的本地地址
+func freeAddr() string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		panic(err)
+	}
+	defer listener.Close()
+	return listener.Addr().String()
+}
+
+// waitListen 等待地址开始侦听，服务器启动完成时侦听器可能尚未就绪
+func waitListen(addr string) {
+	var deadline = time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		if conn, err := net.Dial("tcp", addr); err == nil {
+			_ = conn.Close()
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,69 @@
+package server
+
+import (
+	"time"
+)
+
+// keepaliveEnabled 是否开启了连接保活
+func (slf *Server) keepaliveEnabled() bool {
+	return slf.idleTimeout > 0 || slf.heartbeatInterval > 0
+}
+
+// keepaliveCheckInterval 获取连接保活检查的间隔
+func (slf *Server) keepaliveCheckInterval() time.Duration {
+	var interval = slf.idleTimeout
+	if slf.heartbeatInterval > 0 && (interval <= 0 || slf.heartbeatInterval < interval) {
+		interval = slf.heartbeatInterval
+	}
+	interval /= 2
+	if interval < 100*time.Millisecond {
+		interval = 100 * time.Millisecond
+	}
+	return interval
+}
+
+// keepaliveLoop 对于非 gnet 驱动的网络类型，通过独立的协程定期进行连接保活检查
+func (slf *Server) keepaliveLoop() {
+	ticker := time.NewTicker(slf.keepaliveCheckInterval())
+	defer ticker.Stop()
+	for range ticker.C {
+		if slf.isShutdown.Load() {
+			return
+		}
+		slf.checkKeepalive()
+	}
+}
+
+// checkKeepalive 检查所有在线连接，关闭空闲超时的连接并向需要的连接发送心跳包
+//   - 该函数运行在 gnet 事件循环或保活协程中，心跳包将通过系统消息在消息分发中写入，与其他写入一样触发 ConnectionWritePacketBeforeEvent
+func (slf *Server) checkKeepalive() {
+	var now = time.Now().UnixNano()
+	for _, conn := range slf.online.Map() {
+		idle := time.Duration(now - conn.active.Load())
+		if slf.idleTimeout > 0 && idle >= slf.idleTimeout {
+			conn.closeTransportWithReason(ErrConnectionIdleTimeout)
+			continue
+		}
+		if slf.heartbeatInterval > 0 && idle >= slf.heartbeatInterval && time.Duration(now-conn.pinged.Load()) >= slf.heartbeatInterval {
+			conn.pinged.Store(now)
+			conn := conn
+			PushSystemMessage(slf, func() {
+				conn.Write(slf.heartbeatPacket)
+			}, "Heartbeat")
+		}
+	}
+}
+
+// touch 刷新连接的活跃时间
+func (slf *Conn) touch() {
+	if resumed := slf.resumedTo.Load(); resumed != nil {
+		resumed.touch()
+		return
+	}
+	slf.active.Store(time.Now().UnixNano())
+}
+
+// GetLastActiveTime 获取连接最后一次接收到数据的时间
+func (slf *Conn) GetLastActiveTime() time.Time {
+	return time.Unix(0, slf.active.Load())
+}
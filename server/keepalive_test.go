@@ -0,0 +1,91 @@
+package server_test
+
+import (
+	"github.com/gorilla/websocket"
+	"github.com/kercylan98/minotaur/server"
+	. "github.com/smartystreets/goconvey/convey"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWithIdleTimeout_Session(t *testing.T) {
+	Convey("TestWithIdleTimeout_Session", t, func() {
+		var addr = freeAddr()
+		var pattern = "/idle-" + strings.ReplaceAll(addr, ":", "-")
+		srv := server.New(server.NetworkWebsocket,
+			server.WithIdleTimeout(200*time.Millisecond),
+			server.WithSession(time.Minute, "token"),
+		)
+		var closed = make(chan any, 1)
+		var resumed = make(chan *server.Conn, 1)
+		srv.RegConnectionOpenedEvent(func(srv *server.Server, conn *server.Conn) {
+			conn.Write(server.Packet{WebsocketType: server.WebsocketMessageTypeText, Data: []byte(conn.GetSessionToken())})
+		})
+		srv.RegConnectionClosedEvent(func(srv *server.Server, conn *server.Conn, err any) {
+			closed <- err
+		})
+		srv.RegSessionResumedEvent(func(srv *server.Server, conn *server.Conn) {
+			resumed <- conn
+		})
+		stop := runServer(srv, addr+pattern)
+		defer stop()
+		waitListen(addr)
+
+		ws, _, err := websocket.DefaultDialer.Dial("ws://"+addr+pattern, nil)
+		So(err, ShouldBeNil)
+		_, token, err := ws.ReadMessage()
+		So(err, ShouldBeNil)
+		So(token, ShouldNotBeEmpty)
+
+		select {
+		case err := <-closed:
+			So(err, ShouldEqual, server.ErrConnectionIdleTimeout)
+		case <-time.After(2 * time.Second):
+			t.Fatal("idle connection was not closed")
+		}
+		_ = ws.Close()
+
+		ws, _, err = websocket.DefaultDialer.Dial("ws://"+addr+pattern+"?token="+string(token), nil)
+		So(err, ShouldBeNil)
+		defer ws.Close()
+		select {
+		case <-resumed:
+		case <-time.After(time.Second):
+			t.Fatal("session was not retained after idle timeout")
+		}
+	})
+}
+
+func TestWithHeartbeat(t *testing.T) {
+	Convey("TestWithHeartbeat", t, func() {
+		var addr = freeAddr()
+		var pattern = "/heartbeat-" + strings.ReplaceAll(addr, ":", "-")
+		srv := server.New(server.NetworkWebsocket,
+			server.WithHeartbeat(100*time.Millisecond, server.Packet{WebsocketType: server.WebsocketMessageTypeText, Data: []byte("ping")}, nil),
+		)
+		// 心跳包与其他写入一样在消息分发中触发写入前事件
+		var written []string
+		srv.RegConnectionWritePacketBeforeEvent(func(srv *server.Server, conn *server.Conn, packet server.Packet) server.Packet {
+			written = append(written, string(packet.Data))
+			return packet
+		})
+		stop := runServer(srv, addr+pattern)
+		defer stop()
+		waitListen(addr)
+
+		ws, _, err := websocket.DefaultDialer.Dial("ws://"+addr+pattern, nil)
+		So(err, ShouldBeNil)
+		defer ws.Close()
+		_ = ws.SetReadDeadline(time.Now().Add(2 * time.Second))
+		_, data, err := ws.ReadMessage()
+		So(err, ShouldBeNil)
+		So(string(data), ShouldEqual, "ping")
+
+		var done = make(chan []string)
+		server.PushSystemMessage(srv, func() {
+			done <- append([]string(nil), written...)
+		})
+		So(<-done, ShouldContain, "ping")
+	})
+}
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"reflect"
+	"time"
 )
 
 const (
@@ -24,6 +25,9 @@ const (
 
 	// MessageTypeSystem 系统消息类型
 	MessageTypeSystem
+
+	// messageTypeCount 消息类型数量，新增的消息类型需要定义在此之前
+	messageTypeCount
 )
 
 var messageNames = map[MessageType]string{
@@ -65,8 +69,11 @@ func (slf MessageErrorAction) String() string {
 
 // Message 服务器消息
 type Message struct {
-	t     MessageType // 消息类型
-	attrs []any       // 消息属性
+	t      MessageType // 消息类型
+	attrs  []any       // 消息属性
+	shunt  *shunt      // 消息所在的分流通道，为 nil 时表示系统通道
+	pushAt time.Time   // 消息推送至分流通道的时间
+	conn   bool        // 是否为连接生命周期消息，开启消息优先级通道时将与数据包位于同一通道，从而保证与该连接数据包的先后顺序
 }
 
 // String 返回消息的字符串表示
@@ -154,13 +161,59 @@ func PushAsyncMessage(srv *Server, caller func() error, callback func(err error)
 }
 
 // PushSystemMessage 向特定服务器中推送 MessageTypeSystem 消息
+//   - 服务器关闭后推送的消息将被直接丢弃
 func PushSystemMessage(srv *Server, handle func(), mark ...any) {
+	if srv.isShutdown.Load() {
+		return
+	}
+	msg := srv.messagePool.Get()
+	msg.t = MessageTypeSystem
+	msg.attrs = append([]any{handle}, mark...)
+	srv.pushMessage(msg)
+}
+
+// pushConnSystemMessage 推送连接生命周期相关的 MessageTypeSystem 消息
+//   - 服务器关闭后网络层仍可能报告连接关闭，此时消息池可能已被关闭，消息将被直接丢弃
+func pushConnSystemMessage(srv *Server, handle func(), mark ...any) {
+	if srv.isShutdown.Load() {
+		return
+	}
 	msg := srv.messagePool.Get()
 	msg.t = MessageTypeSystem
 	msg.attrs = append([]any{handle}, mark...)
+	msg.conn = true
 	srv.pushMessage(msg)
 }
 
+// PushTickerMessageTo 向特定服务器的特定分流通道中推送 MessageTypeTicker 消息
+//   - 分流通道不存在时将自动创建，未开启分流时将推送至系统通道
+func PushTickerMessageTo(srv *Server, guid int64, caller func(), mark ...any) {
+	msg := srv.messagePool.Get()
+	msg.t = MessageTypeTicker
+	msg.attrs = append([]any{caller}, mark...)
+	srv.pushMessageTo(guid, msg)
+}
+
+// PushAsyncMessageTo 向特定服务器的特定分流通道中推送 MessageTypeAsync 消息
+//   - 分流通道不存在时将自动创建，未开启分流时将推送至系统通道
+//   - callback 函数将通过系统消息在同一分流通道中执行
+func PushAsyncMessageTo(srv *Server, guid int64, caller func() error, callback func(err error), mark ...any) {
+	msg := srv.messagePool.Get()
+	msg.t = MessageTypeAsync
+	msg.attrs = append([]any{caller, callback}, mark...)
+	srv.pushMessageTo(guid, msg)
+}
+
+// PushSystemMessageTo 向特定服务器的特定分流通道中推送 MessageTypeSystem 消息
+//   - 分流通道不存在时将自动创建，未开启分流时将推送至系统通道
+//   - 适用于例如将房间的定时逻辑、异步结果等转移到房间所在的分流通道中串行执行
+func PushSystemMessageTo(srv *Server, guid int64, handle func(), mark ...any) {
+	msg := srv.messagePool.Get()
+	msg.t = MessageTypeSystem
+	msg.attrs = append([]any{handle}, mark...)
+	srv.pushMessageTo(guid, msg)
+}
+
 // SetMessagePacketVisualizer 设置消息可视化函数
 //   - 消息可视化将在慢消息等情况用于打印，使用自定消息可视化函数可以便于开发者进行调试
 //   - 默认的消息可视化函数将直接返回消息的字符串表示
@@ -0,0 +1,147 @@
+package server
+
+// MessageLane 消息优先级通道
+type MessageLane byte
+
+const (
+	MessageLaneHigh   MessageLane = iota // 高优先级通道：MessageTypeError、MessageTypeSystem、MessageTypeTicker
+	MessageLaneNormal                    // 普通优先级通道：MessageTypeCross、MessageTypeAsync
+	MessageLaneLow                       // 低优先级通道：MessageTypePacket 及连接的打开、关闭事件
+	messageLaneCount
+)
+
+var messageLaneNames = map[MessageLane]string{
+	MessageLaneHigh:   "high",
+	MessageLaneNormal: "normal",
+	MessageLaneLow:    "low",
+}
+
+// String 返回消息优先级通道的字符串表示
+func (slf MessageLane) String() string {
+	return messageLaneNames[slf]
+}
+
+// getMessageLane 获取消息所属的优先级通道
+//   - 连接生命周期消息将与数据包位于同一通道，避免连接的打开及关闭事件越过该连接的数据包被处理
+func getMessageLane(message *Message) MessageLane {
+	if message.conn {
+		return MessageLaneLow
+	}
+	switch message.t {
+	case MessageTypeError, MessageTypeSystem, MessageTypeTicker:
+		return MessageLaneHigh
+	case MessageTypeCross, MessageTypeAsync:
+		return MessageLaneNormal
+	default:
+		return MessageLaneLow
+	}
+}
+
+// messageLanes 消息优先级通道
+//   - 采用加权轮询的方式进行调度，每一轮中各个通道最多处理与其权重相同数量的消息，优先级高的通道先被处理
+//   - 由于所有通道的权重至少为 1，低优先级通道在每一轮中至少会被处理一次，从而避免饥饿
+type messageLanes struct {
+	weights  [messageLaneCount]int
+	channels [messageLaneCount]chan *Message
+}
+
+func newMessageLanes(weights [messageLaneCount]int, size int) *messageLanes {
+	lanes := &messageLanes{weights: weights}
+	for i := range lanes.channels {
+		lanes.channels[i] = make(chan *Message, size)
+	}
+	return lanes
+}
+
+func (slf *messageLanes) push(message *Message) {
+	slf.channels[getMessageLane(message)] <- message
+}
+
+// backlog 获取各个通道中等待处理的消息数量
+func (slf *messageLanes) backlog() map[MessageLane]int {
+	var backlog = make(map[MessageLane]int, messageLaneCount)
+	for lane, channel := range slf.channels {
+		backlog[MessageLane(lane)] = len(channel)
+	}
+	return backlog
+}
+
+func (slf *messageLanes) close() {
+	for _, channel := range slf.channels {
+		close(channel)
+	}
+}
+
+// run 持续调度各个通道中的消息，直到通道被关闭
+//   - 所有通道均为空时将阻塞等待任意通道的消息，被唤醒后优先处理更高优先级通道中已就绪的消息
+//   - 通道被关闭后将按优先级处理完所有通道中剩余的消息后返回
+func (slf *messageLanes) run(dispatch func(message *Message)) {
+	defer slf.drain(dispatch)
+	for {
+		dispatched, closed := slf.round(dispatch, messageLaneCount)
+		if closed {
+			return
+		}
+		if dispatched {
+			continue
+		}
+
+		var lane MessageLane
+		var message *Message
+		var ok bool
+		select {
+		case message, ok = <-slf.channels[MessageLaneHigh]:
+			lane = MessageLaneHigh
+		case message, ok = <-slf.channels[MessageLaneNormal]:
+			lane = MessageLaneNormal
+		case message, ok = <-slf.channels[MessageLaneLow]:
+			lane = MessageLaneLow
+		}
+		if !ok {
+			return
+		}
+		_, closed = slf.round(dispatch, lane)
+		dispatch(message)
+		if closed {
+			return
+		}
+	}
+}
+
+// drain 处理所有通道中剩余的消息，直到所有通道被关闭
+func (slf *messageLanes) drain(dispatch func(message *Message)) {
+	for _, channel := range slf.channels {
+		for message := range channel {
+			dispatch(message)
+		}
+	}
+}
+
+// round 对优先级高于 until 的通道进行一轮加权轮询，返回本轮是否处理了消息及通道是否已被关闭
+func (slf *messageLanes) round(dispatch func(message *Message), until MessageLane) (dispatched, closed bool) {
+	for lane, channel := range slf.channels[:until] {
+	next:
+		for i := 0; i < slf.weights[lane]; i++ {
+			select {
+			case message, ok := <-channel:
+				if !ok {
+					return dispatched, true
+				}
+				dispatch(message)
+				dispatched = true
+			default:
+				break next
+			}
+		}
+	}
+	return
+}
+
+// GetMessageLaneBacklog 获取各个消息优先级通道中等待处理的消息数量
+//   - 未通过 WithMessageLanes 开启消息优先级通道时，所有消息均位于 MessageLaneLow 中
+func (slf *Server) GetMessageLaneBacklog() map[MessageLane]int {
+	if slf.messageLanes == nil {
+		return map[MessageLane]int{MessageLaneLow: len(slf.messageChannel)}
+	}
+	return slf.messageLanes.backlog()
+}
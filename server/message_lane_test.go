@@ -0,0 +1,75 @@
+package server_test
+
+import (
+	"github.com/kercylan98/minotaur/server"
+	. "github.com/smartystreets/goconvey/convey"
+	"testing"
+)
+
+func TestWithMessageLanes(t *testing.T) {
+	Convey("TestWithMessageLanes", t, func() {
+		srv := server.New(server.NetworkNone, server.WithMessageLanes(4, 1, 1))
+		stop := runServer(srv)
+		defer stop()
+
+		var order []string
+		var done = make(chan struct{})
+		srv.RegConnectionReceivePacketEvent(func(srv *server.Server, conn *server.Conn, packet server.Packet) {
+			order = append(order, "P")
+			if len(order) == 10 {
+				close(done)
+			}
+		})
+		conn := server.NewEmptyConn(srv)
+
+		var backlog map[server.MessageLane]int
+		server.PushSystemMessage(srv, func() {
+			for i := 0; i < 5; i++ {
+				server.PushPacketMessage(srv, conn, []byte{'p', server.WebsocketMessageTypeBinary})
+			}
+			for i := 0; i < 5; i++ {
+				server.PushSystemMessage(srv, func() {
+					order = append(order, "S")
+				})
+			}
+			backlog = srv.GetMessageLaneBacklog()
+		})
+		<-done
+		So(backlog[server.MessageLaneHigh], ShouldEqual, 5)
+		So(backlog[server.MessageLaneLow], ShouldEqual, 5)
+		So(order, ShouldResemble, []string{"S", "S", "S", "S", "P", "S", "P", "P", "P", "P"})
+	})
+}
+
+func TestWithMessageLanes_ConnLifecycle(t *testing.T) {
+	Convey("TestWithMessageLanes_ConnLifecycle", t, func() {
+		srv := server.New(server.NetworkNone, server.WithMessageLanes(4, 1, 1))
+		var order []string
+		var done = make(chan struct{})
+		srv.RegConnectionOpenedEvent(func(srv *server.Server, conn *server.Conn) {
+			order = append(order, "O")
+		})
+		srv.RegConnectionReceivePacketEvent(func(srv *server.Server, conn *server.Conn, packet server.Packet) {
+			order = append(order, "P")
+		})
+		srv.RegConnectionClosedEvent(func(srv *server.Server, conn *server.Conn, err any) {
+			order = append(order, "C")
+			close(done)
+		})
+		stop := runServer(srv)
+		defer stop()
+
+		conn := server.NewVirtualConn(srv, "c1", "127.0.0.1", func(packet server.Packet) error {
+			return nil
+		}, nil)
+		server.PushSystemMessage(srv, func() {
+			srv.OnConnectionOpenedEvent(conn)
+			for i := 0; i < 3; i++ {
+				conn.ReceiveVirtualPacket(server.Packet{Data: []byte("p")})
+			}
+			srv.OnConnectionClosedEvent(conn, nil)
+		})
+		<-done
+		So(order, ShouldResemble, []string{"O", "P", "P", "P", "C"})
+	})
+}
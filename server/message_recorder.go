@@ -0,0 +1,269 @@
+package server
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/kercylan98/minotaur/utils/log"
+)
+
+const (
+	messageRecordMagic         = "MNTR"      // 消息记录文件头
+	messageRecordVersion       = 1           // 消息记录文件版本
+	messageRecordFlushInterval = time.Second // 缓冲区中的记录写入 writer 的最大间隔
+)
+
+const (
+	// MessageRecordKindMessage 消息记录类型：被 dispatchMessage 处理的消息
+	MessageRecordKindMessage MessageRecordKind = iota
+
+	// MessageRecordKindConnOpened 消息记录类型：连接打开
+	MessageRecordKindConnOpened
+
+	// MessageRecordKindConnClosed 消息记录类型：连接关闭
+	MessageRecordKindConnClosed
+)
+
+const (
+	messageRecordConnOpened byte = 0xF0 // 连接打开记录标记
+	messageRecordConnClosed byte = 0xF1 // 连接关闭记录标记
+)
+
+// MessageRecordKind 消息记录类型
+type MessageRecordKind byte
+
+// MessageRecord 通过 WithMessageRecord 记录的单条消息
+//   - MessageTypePacket：ConnID、Packet（包含末尾的 websocket 消息类型）
+//   - MessageTypeCross：ServerID、Packet，当消息为跨服请求时 Request 为 true
+//   - MessageTypeTicker：Name 为定时器名称
+//   - MessageTypeSystem、MessageTypeAsync：Name 为消息标记；MessageTypeError：Name 为错误信息，仅用于查阅
+//   - MessageRecordKindConnOpened、MessageRecordKindConnClosed：ConnID、IP
+type MessageRecord struct {
+	Kind     MessageRecordKind // 记录类型
+	Type     MessageType       // 消息类型，仅在 Kind 为 MessageRecordKindMessage 时有效
+	Time     time.Time         // 消息被处理的时间
+	ConnID   string            // 连接 ID
+	IP       string            // 连接 IP
+	ServerID int64             // 跨服消息来源服务器 ID
+	Request  bool              // 是否为跨服请求
+	Name     string            // 定时器名称或消息标记
+	Packet   []byte            // 数据包
+}
+
+// newMessageRecorder 创建一个消息记录器，并写入文件头
+func newMessageRecorder(writer io.Writer) *messageRecorder {
+	recorder := &messageRecorder{
+		writer: writer,
+		buf:    bufio.NewWriter(writer),
+	}
+	recorder.write(append([]byte(messageRecordMagic), messageRecordVersion))
+	return recorder
+}
+
+// messageRecorder 消息记录器
+//   - 记录格式：[记录标记 1B][unix nano 8B][uvarint 内容长度][内容]
+//   - 记录将在写入后的 messageRecordFlushInterval 内从缓冲区写入 writer，避免进程异常退出时丢失大量记录
+type messageRecorder struct {
+	mutex    sync.Mutex
+	writer   io.Writer
+	buf      *bufio.Writer
+	body     []byte
+	err      error
+	flushing bool // 是否已经安排了缓冲区的写入
+}
+
+// recordMessage 记录即将被处理的消息
+func (slf *messageRecorder) recordMessage(msg *Message) {
+	slf.mutex.Lock()
+	defer slf.mutex.Unlock()
+	var body = slf.body[:0]
+	var attrs = msg.attrs
+	switch msg.t {
+	case MessageTypePacket:
+		body = appendRecordString(body, attrs[0].(*Conn).GetID())
+		body = append(body, attrs[1].([]byte)...)
+	case MessageTypeCross:
+		var request byte
+		if len(attrs) > 2 {
+			if _, ok := attrs[2].(func(packet []byte)); ok {
+				request = 1
+			}
+		}
+		body = binary.BigEndian.AppendUint64(body, uint64(attrs[0].(int64)))
+		body = append(body, request)
+		body = append(body, attrs[1].([]byte)...)
+	case MessageTypeError:
+		body = append(body, attrs[0].(error).Error()...)
+	case MessageTypeTicker, MessageTypeSystem, MessageTypeAsync:
+		var offset = 1
+		if msg.t == MessageTypeAsync {
+			offset = 2
+		}
+		if len(attrs) > offset {
+			if name, ok := attrs[offset].(string); ok {
+				body = append(body, name...)
+			}
+		}
+	}
+	slf.body = body
+	slf.record(byte(msg.t), body)
+}
+
+// recordConnOpened 记录连接打开
+func (slf *messageRecorder) recordConnOpened(conn *Conn) {
+	slf.mutex.Lock()
+	defer slf.mutex.Unlock()
+	slf.body = append(appendRecordString(slf.body[:0], conn.GetID()), conn.GetIP()...)
+	slf.record(messageRecordConnOpened, slf.body)
+}
+
+// recordConnClosed 记录连接关闭
+func (slf *messageRecorder) recordConnClosed(conn *Conn) {
+	slf.mutex.Lock()
+	defer slf.mutex.Unlock()
+	slf.body = append(slf.body[:0], conn.GetID()...)
+	slf.record(messageRecordConnClosed, slf.body)
+}
+
+func (slf *messageRecorder) record(flag byte, body []byte) {
+	var head = make([]byte, 0, 9+binary.MaxVarintLen64)
+	head = append(head, flag)
+	head = binary.BigEndian.AppendUint64(head, uint64(time.Now().UnixNano()))
+	head = binary.AppendUvarint(head, uint64(len(body)))
+	slf.write(head)
+	slf.write(body)
+	if !slf.flushing && slf.err == nil {
+		slf.flushing = true
+		time.AfterFunc(messageRecordFlushInterval, slf.flush)
+	}
+}
+
+// flush 将缓冲区中的记录写入 writer
+func (slf *messageRecorder) flush() {
+	slf.mutex.Lock()
+	defer slf.mutex.Unlock()
+	slf.flushing = false
+	if slf.err != nil {
+		return
+	}
+	if slf.err = slf.buf.Flush(); slf.err != nil {
+		log.Error("Server", log.String("action", "record"), log.Err(slf.err))
+	}
+}
+
+func (slf *messageRecorder) write(data []byte) {
+	if slf.err != nil {
+		return
+	}
+	if _, slf.err = slf.buf.Write(data); slf.err != nil {
+		log.Error("Server", log.String("action", "record"), log.Err(slf.err))
+	}
+}
+
+// close 将缓冲区中的记录写入并关闭记录器，当 writer 实现了 io.Closer 时将一并关闭
+func (slf *messageRecorder) close() {
+	slf.mutex.Lock()
+	defer slf.mutex.Unlock()
+	if err := slf.buf.Flush(); err != nil && slf.err == nil {
+		log.Error("Server", log.String("action", "record"), log.Err(err))
+	}
+	if closer, ok := slf.writer.(io.Closer); ok {
+		_ = closer.Close()
+	}
+	slf.err = io.ErrClosedPipe
+}
+
+func appendRecordString(dst []byte, s string) []byte {
+	dst = binary.AppendUvarint(dst, uint64(len(s)))
+	return append(dst, s...)
+}
+
+func readRecordString(data []byte) (string, []byte, error) {
+	length, n := binary.Uvarint(data)
+	if n <= 0 || uint64(len(data)-n) < length {
+		return "", nil, ErrMessageRecordIllegal
+	}
+	return string(data[n : n+int(length)]), data[n+int(length):], nil
+}
+
+// NewMessageRecordReader 创建一个读取 WithMessageRecord 所记录消息的读取器
+func NewMessageRecordReader(reader io.Reader) (*MessageRecordReader, error) {
+	var r = bufio.NewReader(reader)
+	var head = make([]byte, len(messageRecordMagic)+1)
+	if _, err := io.ReadFull(r, head); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrMessageRecordIllegal, err)
+	}
+	if string(head[:len(messageRecordMagic)]) != messageRecordMagic {
+		return nil, ErrMessageRecordIllegal
+	}
+	if head[len(messageRecordMagic)] != messageRecordVersion {
+		return nil, fmt.Errorf("%w: unsupported version %d", ErrMessageRecordIllegal, head[len(messageRecordMagic)])
+	}
+	return &MessageRecordReader{reader: r}, nil
+}
+
+// MessageRecordReader 消息记录读取器
+type MessageRecordReader struct {
+	reader *bufio.Reader
+}
+
+// Next 读取下一条消息记录，当所有记录读取完毕后将返回 io.EOF
+func (slf *MessageRecordReader) Next() (*MessageRecord, error) {
+	var head = make([]byte, 9)
+	if _, err := io.ReadFull(slf.reader, head); err != nil {
+		if errors.Is(err, io.ErrUnexpectedEOF) {
+			err = ErrMessageRecordIllegal
+		}
+		return nil, err
+	}
+	length, err := binary.ReadUvarint(slf.reader)
+	if err != nil {
+		return nil, ErrMessageRecordIllegal
+	}
+	var body = make([]byte, length)
+	if _, err = io.ReadFull(slf.reader, body); err != nil {
+		return nil, ErrMessageRecordIllegal
+	}
+
+	var record = &MessageRecord{Time: time.Unix(0, int64(binary.BigEndian.Uint64(head[1:])))}
+	switch flag := head[0]; flag {
+	case messageRecordConnOpened:
+		record.Kind = MessageRecordKindConnOpened
+		if record.ConnID, body, err = readRecordString(body); err != nil {
+			return nil, err
+		}
+		record.IP = string(body)
+	case messageRecordConnClosed:
+		record.Kind = MessageRecordKindConnClosed
+		record.ConnID = string(body)
+	default:
+		record.Kind, record.Type = MessageRecordKindMessage, MessageType(flag)
+		switch record.Type {
+		case MessageTypePacket:
+			if record.ConnID, body, err = readRecordString(body); err != nil {
+				return nil, err
+			}
+			if len(body) == 0 {
+				return nil, ErrMessageRecordIllegal
+			}
+			record.Packet = body
+		case MessageTypeCross:
+			if len(body) < 9 {
+				return nil, ErrMessageRecordIllegal
+			}
+			record.ServerID = int64(binary.BigEndian.Uint64(body))
+			record.Request = body[8] == 1
+			record.Packet = body[9:]
+		case MessageTypeError, MessageTypeTicker, MessageTypeSystem, MessageTypeAsync:
+			record.Name = string(body)
+		default:
+			return nil, fmt.Errorf("%w: unknown record flag %d", ErrMessageRecordIllegal, flag)
+		}
+	}
+	return record, nil
+}
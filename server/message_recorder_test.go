@@ -0,0 +1,169 @@
+package server_test
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/kercylan98/minotaur/server"
+	"github.com/kercylan98/minotaur/utils/offset"
+	. "github.com/smartystreets/goconvey/convey"
+	"sync"
+	"testing"
+	"time"
+)
+
+type replayEntry struct {
+	name string
+	at   time.Time
+}
+
+func newReplayServer(record *bytes.Buffer, entries *[]replayEntry) *server.Server {
+	var options = []server.Option{server.WithTicker(10, true)}
+	if record != nil {
+		options = append(options, server.WithMessageRecord(record))
+	}
+	srv := server.New(server.NetworkNone, options...)
+	srv.RegConnectionOpenedEvent(func(srv *server.Server, conn *server.Conn) {
+		*entries = append(*entries, replayEntry{"open:" + conn.GetID(), offset.Now()})
+	})
+	srv.RegConnectionReceivePacketEvent(func(srv *server.Server, conn *server.Conn, packet server.Packet) {
+		*entries = append(*entries, replayEntry{fmt.Sprintf("%s:%s", conn.GetID(), packet.Data), offset.Now()})
+		conn.Write(server.Packet{Data: packet.Data})
+		if string(packet.Data) == "b" {
+			srv.Ticker().Loop("tick", 10*time.Millisecond, 10*time.Millisecond, -1, func() {
+				*entries = append(*entries, replayEntry{"tick", offset.Now()})
+				srv.Ticker().StopTimer("tick")
+			})
+		}
+	})
+	srv.RegConnectionClosedEvent(func(srv *server.Server, conn *server.Conn, err any) {
+		*entries = append(*entries, replayEntry{"close:" + conn.GetID(), offset.Now()})
+	})
+	return srv
+}
+
+func TestReplay(t *testing.T) {
+	Convey("TestReplay", t, func() {
+		var record = new(bytes.Buffer)
+		var recorded []replayEntry
+		srv := newReplayServer(record, &recorded)
+		stop := runServer(srv)
+
+		conn := server.NewVirtualConn(srv, "c1", "127.0.0.1", func(packet server.Packet) error {
+			return nil
+		}, nil)
+		srv.OnConnectionOpenedEvent(conn)
+		for _, data := range []string{"a", "b", "c"} {
+			conn.ReceiveVirtualPacket(server.Packet{Data: []byte(data)})
+		}
+		for {
+			var ticked = make(chan bool)
+			server.PushSystemMessage(srv, func() {
+				ticked <- len(recorded) > 0 && recorded[len(recorded)-1].name == "tick"
+			})
+			if <-ticked {
+				break
+			}
+			time.Sleep(time.Millisecond)
+		}
+		stop()
+
+		reader, err := server.NewMessageRecordReader(bytes.NewReader(record.Bytes()))
+		So(err, ShouldBeNil)
+		var opened, packets, tickers int
+		for {
+			r, err := reader.Next()
+			if err != nil {
+				break
+			}
+			if r.Kind == server.MessageRecordKindConnOpened {
+				So(r.ConnID, ShouldEqual, "c1")
+				So(r.IP, ShouldEqual, "127.0.0.1")
+				opened++
+			}
+			if r.Kind == server.MessageRecordKindMessage && r.Type == server.MessageTypePacket {
+				So(r.ConnID, ShouldEqual, "c1")
+				packets++
+			}
+			if r.Kind == server.MessageRecordKindMessage && r.Type == server.MessageTypeTicker {
+				So(r.Name, ShouldEqual, "tick")
+				tickers++
+			}
+		}
+		So(opened, ShouldEqual, 1)
+		So(packets, ShouldEqual, 3)
+		So(tickers, ShouldEqual, 1)
+
+		var replayed []replayEntry
+		var writes = make(chan string, 3)
+		srv = newReplayServer(nil, &replayed)
+		stop = runServer(srv)
+		err = server.Replay(srv, bytes.NewReader(record.Bytes()), server.WithReplayConnWriter(func(conn *server.Conn, packet server.Packet) error {
+			writes <- conn.GetID() + ":" + string(packet.Data)
+			return nil
+		}))
+		for _, data := range []string{"a", "b", "c"} {
+			select {
+			case write := <-writes:
+				So(write, ShouldEqual, "c1:"+data)
+			case <-time.After(time.Second):
+				t.Fatal("replayed packet was not written")
+			}
+		}
+		stop()
+		So(err, ShouldBeNil)
+		So(offset.GetGlobal().GetOffset(), ShouldEqual, 0)
+		So(len(replayed), ShouldEqual, len(recorded))
+		for i, entry := range recorded {
+			So(replayed[i].name, ShouldEqual, entry.name)
+			So(replayed[i].at, ShouldHappenWithin, 5*time.Millisecond, entry.at)
+		}
+	})
+}
+
+func TestReplayIllegal(t *testing.T) {
+	Convey("TestReplayIllegal", t, func() {
+		srv := server.New(server.NetworkNone)
+		So(server.Replay(srv, bytes.NewReader([]byte("illegal"))), ShouldEqual, server.ErrMessageRecordIllegal)
+		srv = server.New(server.NetworkTcp)
+		So(server.Replay(srv, bytes.NewReader(nil)), ShouldEqual, server.ErrReplayNetwork)
+	})
+}
+
+// lockedBuffer 可被并发读写的缓冲区
+type lockedBuffer struct {
+	mutex sync.Mutex
+	buf   bytes.Buffer
+}
+
+func (slf *lockedBuffer) Write(p []byte) (int, error) {
+	slf.mutex.Lock()
+	defer slf.mutex.Unlock()
+	return slf.buf.Write(p)
+}
+
+func (slf *lockedBuffer) Len() int {
+	slf.mutex.Lock()
+	defer slf.mutex.Unlock()
+	return slf.buf.Len()
+}
+
+func TestMessageRecord_Flush(t *testing.T) {
+	Convey("TestMessageRecord_Flush", t, func() {
+		var record = new(lockedBuffer)
+		srv := server.New(server.NetworkNone, server.WithMessageRecord(record))
+		stop := runServer(srv)
+		defer stop()
+
+		conn := server.NewVirtualConn(srv, "c1", "127.0.0.1", func(packet server.Packet) error {
+			return nil
+		}, nil)
+		srv.OnConnectionOpenedEvent(conn)
+		conn.ReceiveVirtualPacket(server.Packet{Data: []byte("a")})
+
+		var deadline = time.Now().Add(3 * time.Second)
+		for record.Len() == 0 && time.Now().Before(deadline) {
+			time.Sleep(10 * time.Millisecond)
+		}
+		So(record.Len(), ShouldBeGreaterThan, 0)
+	})
+}
@@ -0,0 +1,163 @@
+package server
+
+import (
+	"errors"
+	"io"
+	"time"
+
+	"github.com/kercylan98/minotaur/utils/log"
+	"github.com/kercylan98/minotaur/utils/offset"
+)
+
+// ReplayOption 消息回放可选项
+type ReplayOption func(replay *replay)
+
+// WithReplayClock 通过特定的虚拟时钟进行回放
+//   - 默认采用 offset.GetGlobal() 作为虚拟时钟
+//   - 每条消息被处理前，虚拟时钟将被调整至该消息被记录时的时间，回放结束后将恢复原有的偏移
+func WithReplayClock(clock *offset.Time) ReplayOption {
+	return func(replay *replay) {
+		replay.clock = clock
+	}
+}
+
+// WithReplayConnWriter 通过特定的写入函数接收回放过程中服务器向虚拟连接写入的数据包
+//   - 默认将丢弃所有写入的数据包
+func WithReplayConnWriter(writer func(conn *Conn, packet Packet) error) ReplayOption {
+	return func(replay *replay) {
+		replay.writer = writer
+	}
+}
+
+// replay 消息回放
+type replay struct {
+	srv    *Server
+	clock  *offset.Time
+	writer func(conn *Conn, packet Packet) error
+	conns  map[string]*Conn
+}
+
+// Replay 将通过 WithMessageRecord 记录的消息按照原有顺序在 NetworkNone 服务器中进行回放，直到所有记录回放完毕
+//   - 应当在服务器启动完成后调用，例如 StartFinishEvent 中通过协程调用
+//   - 回放过程中数据包将通过与记录中连接 ID 相同的虚拟连接进行处理，跨服消息将直接交由 ReceiveCrossPacketEvent 或 ReceiveCrossRequestEvent 处理，跨服请求的回复将被丢弃
+//   - 定时器消息将通过 timer.Ticker.Trigger 触发同名调度器，回放期间由 WithTicker 产生的实时定时器消息将被丢弃
+//   - 系统消息及异步消息由回放的逻辑自行产生，不会被重复回放
+//   - 游戏逻辑应当通过 utils/offset 获取时间，才能在回放时获得与记录时一致的时间
+func Replay(srv *Server, reader io.Reader, options ...ReplayOption) error {
+	if srv.network != NetworkNone {
+		return ErrReplayNetwork
+	}
+	records, err := NewMessageRecordReader(reader)
+	if err != nil {
+		return err
+	}
+	var r = &replay{
+		srv:   srv,
+		clock: offset.GetGlobal(),
+		conns: map[string]*Conn{},
+	}
+	for _, option := range options {
+		option(r)
+	}
+
+	var origin = r.clock.GetOffset()
+	srv.replaying.Store(true)
+	defer func() {
+		srv.replaying.Store(false)
+		r.clock.SetOffset(origin)
+	}()
+
+	for {
+		record, err := records.Next()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+		r.step(record)
+	}
+}
+
+// step 在系统消息中回放一条记录，并等待由其产生的系统消息处理完毕
+func (slf *replay) step(record *MessageRecord) {
+	var done = make(chan struct{})
+	PushSystemMessage(slf.srv, func() {
+		slf.handle(record)
+		PushSystemMessage(slf.srv, func() {
+			close(done)
+		}, "ReplayBarrier")
+	}, "Replay")
+	<-done
+}
+
+func (slf *replay) handle(record *MessageRecord) {
+	switch record.Kind {
+	case MessageRecordKindConnOpened:
+		conn := slf.conn(record.ConnID, record.IP)
+		slf.at(record)
+		slf.srv.onConnectionOpened(conn)
+	case MessageRecordKindConnClosed:
+		if conn, exist := slf.conns[record.ConnID]; exist {
+			delete(slf.conns, record.ConnID)
+			if _, online := slf.srv.online.GetExist(conn.GetID()); online {
+				slf.at(record)
+				slf.srv.OnConnectionClosedEvent(conn, nil)
+			}
+		}
+	case MessageRecordKindMessage:
+		switch record.Type {
+		case MessageTypePacket:
+			conn, exist := slf.conns[record.ConnID]
+			if !exist {
+				conn = slf.conn(record.ConnID, "")
+			}
+			slf.at(record)
+			if !exist {
+				slf.srv.onConnectionOpened(conn)
+			}
+			slf.dispatch(MessageTypePacket, conn, record.Packet)
+		case MessageTypeCross:
+			slf.at(record)
+			if record.Request {
+				slf.dispatch(MessageTypeCross, record.ServerID, record.Packet, func(packet []byte) {})
+			} else {
+				slf.dispatch(MessageTypeCross, record.ServerID, record.Packet)
+			}
+		case MessageTypeTicker:
+			if record.Name == "" || slf.srv.ticker == nil {
+				return
+			}
+			slf.at(record)
+			if !slf.srv.ticker.Trigger(record.Name) {
+				log.Warn("Server", log.String("action", "replay"), log.String("ticker", record.Name), log.String("state", "not found"))
+			}
+		}
+	}
+}
+
+// at 将虚拟时钟调整至记录被记录时的时间
+func (slf *replay) at(record *MessageRecord) {
+	slf.clock.SetOffset(time.Until(record.Time))
+}
+
+// conn 创建一个与记录中连接 ID 相同的虚拟连接
+func (slf *replay) conn(id, ip string) *Conn {
+	var conn *Conn
+	conn = NewVirtualConn(slf.srv, id, ip, func(packet Packet) error {
+		if slf.writer == nil {
+			return nil
+		}
+		return slf.writer(conn, packet)
+	}, nil)
+	slf.conns[id] = conn
+	return conn
+}
+
+// dispatch 在当前系统消息中直接处理回放的消息，避免进入分流通道导致顺序不一致
+func (slf *replay) dispatch(t MessageType, attrs ...any) {
+	msg := slf.srv.messagePool.Get()
+	msg.t = t
+	msg.attrs = attrs
+	slf.srv.dispatchMessage(msg)
+}
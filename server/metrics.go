@@ -0,0 +1,222 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/kercylan98/minotaur/utils/log"
+	"io"
+	"net"
+	"net/http"
+	"sort"
+	"sync/atomic"
+	"time"
+)
+
+// metricsLatencyBuckets 消息处理耗时直方图的桶（秒）
+var metricsLatencyBuckets = []float64{0.0005, 0.001, 0.005, 0.01, 0.05, 0.1, 0.5, 1, 5}
+
+// newServerMetrics 创建服务器指标
+func newServerMetrics() *serverMetrics {
+	m := &serverMetrics{}
+	for t := range m.messages {
+		m.messages[t] = &messageMetrics{buckets: make([]atomic.Uint64, len(metricsLatencyBuckets))}
+	}
+	return m
+}
+
+// serverMetrics 服务器指标
+type serverMetrics struct {
+	messages    [messageTypeCount]*messageMetrics // 每种消息类型的指标
+	connOpened  atomic.Uint64                     // 打开的连接数量
+	connClosed  atomic.Uint64                     // 关闭的连接数量
+	bytesIn     atomic.Uint64                     // 接收的字节数
+	bytesOut    atomic.Uint64                     // 发送的字节数
+	httpServer  *http.Server                      // 独立的指标 HTTP 服务器
+	metricsAddr string                            // 独立的指标 HTTP 服务侦听地址，为空时表示不开启
+	metricsPath string                            // 指标路径
+}
+
+// messageMetrics 消息指标
+type messageMetrics struct {
+	count   atomic.Uint64   // 处理的消息数量
+	sum     atomic.Uint64   // 处理消息的总耗时（纳秒）
+	buckets []atomic.Uint64 // 耗时直方图
+}
+
+// recordMessage 记录消息的处理耗时
+func (slf *serverMetrics) recordMessage(t MessageType, cost time.Duration) {
+	if slf == nil || int(t) >= len(slf.messages) {
+		return
+	}
+	m := slf.messages[t]
+	m.count.Add(1)
+	m.sum.Add(uint64(cost))
+	seconds := cost.Seconds()
+	for i, bucket := range metricsLatencyBuckets {
+		if seconds <= bucket {
+			m.buckets[i].Add(1)
+		}
+	}
+}
+
+// recordConnOpened 记录连接打开
+func (slf *serverMetrics) recordConnOpened() {
+	if slf != nil {
+		slf.connOpened.Add(1)
+	}
+}
+
+// recordConnClosed 记录连接关闭
+func (slf *serverMetrics) recordConnClosed() {
+	if slf != nil {
+		slf.connClosed.Add(1)
+	}
+}
+
+// recordBytesIn 记录接收的字节数
+func (slf *serverMetrics) recordBytesIn(n int) {
+	if slf != nil {
+		slf.bytesIn.Add(uint64(n))
+	}
+}
+
+// recordBytesOut 记录发送的字节数
+func (slf *serverMetrics) recordBytesOut(n int) {
+	if slf != nil {
+		slf.bytesOut.Add(uint64(n))
+	}
+}
+
+// WriteMetrics 将服务器指标以 Prometheus 文本格式写入 w
+//   - 需要通过 WithMetrics 开启指标采集，否则仅会写入运行时的瞬时指标
+//   - 可用于将指标挂载到自定义的 HTTP 服务中
+func (slf *Server) WriteMetrics(w io.Writer) error {
+	var buf = bufio.NewWriter(w)
+	var network = fmt.Sprintf(`network="%s",listen="%s"`, slf.network, slf.addr)
+	var write = func(name, kind, help string) {
+		_, _ = fmt.Fprintf(buf, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+	}
+
+	write("minotaur_online_connections", "gauge", "Number of online connections.")
+	_, _ = fmt.Fprintf(buf, "minotaur_online_connections{%s} %d\n", network, slf.online.Size())
+
+	write("minotaur_messages_in_flight", "gauge", "Number of messages being dispatched.")
+	_, _ = fmt.Fprintf(buf, "minotaur_messages_in_flight{%s} %d\n", network, slf.messageCounter.Load())
+
+	var backlog = slf.GetMessageLaneBacklog()
+	var depth int
+	for _, n := range backlog {
+		depth += n
+	}
+	write("minotaur_message_queue_depth", "gauge", "Number of messages waiting in the message channel.")
+	_, _ = fmt.Fprintf(buf, "minotaur_message_queue_depth{%s} %d\n", network, depth)
+
+	write("minotaur_message_lane_backlog", "gauge", "Number of messages waiting in each message lane.")
+	for lane := MessageLaneHigh; lane < messageLaneCount; lane++ {
+		_, _ = fmt.Fprintf(buf, "minotaur_message_lane_backlog{%s,lane=\"%s\"} %d\n", network, lane, backlog[lane])
+	}
+
+	if slf.shunts != nil {
+		var stats = slf.GetShuntStats()
+		var guids = make([]int64, 0, len(stats))
+		for guid := range stats {
+			guids = append(guids, guid)
+		}
+		sort.Slice(guids, func(i, j int) bool { return guids[i] < guids[j] })
+		write("minotaur_shunt_queue_depth", "gauge", "Number of messages waiting or being dispatched in each shunt channel.")
+		for _, guid := range guids {
+			_, _ = fmt.Fprintf(buf, "minotaur_shunt_queue_depth{%s,shunt=\"%d\"} %d\n", network, guid, stats[guid].Depth)
+		}
+		write("minotaur_shunt_processed_total", "counter", "Number of messages processed by each shunt channel.")
+		for _, guid := range guids {
+			_, _ = fmt.Fprintf(buf, "minotaur_shunt_processed_total{%s,shunt=\"%d\"} %d\n", network, guid, stats[guid].Processed)
+		}
+		write("minotaur_shunt_latency_seconds", "gauge", "Average and max latency from push to completion of messages in each shunt channel.")
+		for _, guid := range guids {
+			_, _ = fmt.Fprintf(buf, "minotaur_shunt_latency_seconds{%s,shunt=\"%d\",stat=\"avg\"} %g\n", network, guid, stats[guid].AvgLatency.Seconds())
+			_, _ = fmt.Fprintf(buf, "minotaur_shunt_latency_seconds{%s,shunt=\"%d\",stat=\"max\"} %g\n", network, guid, stats[guid].MaxLatency.Seconds())
+		}
+	}
+
+	if slf.ants != nil {
+		write("minotaur_async_pool_workers", "gauge", "Async pool worker usage.")
+		_, _ = fmt.Fprintf(buf, "minotaur_async_pool_workers{%s,state=\"running\"} %d\n", network, slf.ants.Running())
+		_, _ = fmt.Fprintf(buf, "minotaur_async_pool_workers{%s,state=\"free\"} %d\n", network, slf.ants.Free())
+		_, _ = fmt.Fprintf(buf, "minotaur_async_pool_workers{%s,state=\"waiting\"} %d\n", network, slf.ants.Waiting())
+		_, _ = fmt.Fprintf(buf, "minotaur_async_pool_workers{%s,state=\"capacity\"} %d\n", network, slf.ants.Cap())
+	}
+
+	if slf.ticker != nil {
+		write("minotaur_ticker_schedulers", "gauge", "Number of ticker schedulers.")
+		_, _ = fmt.Fprintf(buf, "minotaur_ticker_schedulers{%s} %d\n", network, len(slf.ticker.GetSchedulers()))
+	}
+
+	if m := slf.metrics; m != nil {
+		write("minotaur_connections_opened_total", "counter", "Total number of opened connections.")
+		_, _ = fmt.Fprintf(buf, "minotaur_connections_opened_total{%s} %d\n", network, m.connOpened.Load())
+		write("minotaur_connections_closed_total", "counter", "Total number of closed connections.")
+		_, _ = fmt.Fprintf(buf, "minotaur_connections_closed_total{%s} %d\n", network, m.connClosed.Load())
+		write("minotaur_bytes_received_total", "counter", "Total number of bytes received from connections.")
+		_, _ = fmt.Fprintf(buf, "minotaur_bytes_received_total{%s} %d\n", network, m.bytesIn.Load())
+		write("minotaur_bytes_sent_total", "counter", "Total number of bytes sent to connections.")
+		_, _ = fmt.Fprintf(buf, "minotaur_bytes_sent_total{%s} %d\n", network, m.bytesOut.Load())
+
+		write("minotaur_message_duration_seconds", "histogram", "Message dispatch latency by message type.")
+		for t, mm := range m.messages {
+			var label = fmt.Sprintf(`%s,type="%s"`, network, MessageType(t))
+			for i, bucket := range metricsLatencyBuckets {
+				_, _ = fmt.Fprintf(buf, "minotaur_message_duration_seconds_bucket{%s,le=\"%g\"} %d\n", label, bucket, mm.buckets[i].Load())
+			}
+			var count = mm.count.Load()
+			_, _ = fmt.Fprintf(buf, "minotaur_message_duration_seconds_bucket{%s,le=\"+Inf\"} %d\n", label, count)
+			_, _ = fmt.Fprintf(buf, "minotaur_message_duration_seconds_sum{%s} %g\n", label, time.Duration(mm.sum.Load()).Seconds())
+			_, _ = fmt.Fprintf(buf, "minotaur_message_duration_seconds_count{%s} %d\n", label, count)
+		}
+	}
+	return buf.Flush()
+}
+
+// serveMetrics 启动指标 HTTP 服务
+//   - 将在 Server.Run 中启动，侦听失败时 Server.Run 将返回该错误
+func (slf *Server) serveMetrics() error {
+	if slf.metrics == nil || len(slf.metrics.metricsAddr) == 0 {
+		return nil
+	}
+	var addr = slf.metrics.metricsAddr
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	var handler = http.NewServeMux()
+	handler.HandleFunc(slf.metrics.metricsPath, func(writer http.ResponseWriter, request *http.Request) {
+		writer.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		_ = slf.WriteMetrics(writer)
+	})
+	slf.metrics.httpServer = &http.Server{Handler: handler}
+	go func() {
+		if err := slf.metrics.httpServer.Serve(listener); err != nil && err != http.ErrServerClosed {
+			log.Error("Metrics", log.String("listen", addr), log.Err(err))
+		}
+	}()
+	return nil
+}
+
+// registerMetricsRoute 在 NetworkHttp 模式下将指标挂载到服务器的路由中
+func (slf *Server) registerMetricsRoute() {
+	slf.ginServer.GET(slf.metrics.metricsPath, func(ctx *gin.Context) {
+		ctx.Header("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		_ = slf.WriteMetrics(ctx.Writer)
+	})
+}
+
+// releaseMetrics 关闭指标 HTTP 服务
+func (slf *Server) releaseMetrics() {
+	if slf.metrics == nil || slf.metrics.httpServer == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	_ = slf.metrics.httpServer.Shutdown(ctx)
+}
@@ -0,0 +1,49 @@
+package server_test
+
+import (
+	"bytes"
+	"github.com/kercylan98/minotaur/server"
+	. "github.com/smartystreets/goconvey/convey"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+)
+
+func TestServer_WriteMetrics(t *testing.T) {
+	Convey("TestServer_WriteMetrics", t, func() {
+		srv := server.New(server.NetworkNone, server.WithMetrics("", ""))
+		var buf bytes.Buffer
+		So(srv.WriteMetrics(&buf), ShouldBeNil)
+		So(buf.String(), ShouldContainSubstring, "minotaur_message_queue_depth")
+		So(buf.String(), ShouldContainSubstring, `minotaur_message_duration_seconds_count{network="none",listen="",type="MessageTypePacket"} 0`)
+	})
+}
+
+func TestWithMetrics(t *testing.T) {
+	Convey("TestWithMetrics", t, func() {
+		var addr = freeAddr()
+		srv := server.New(server.NetworkNone, server.WithMetrics(addr, ""))
+		_, err := net.Dial("tcp", addr)
+		So(err, ShouldNotBeNil)
+
+		stop := runServer(srv)
+		resp, err := http.Get("http://" + addr + "/metrics")
+		So(err, ShouldBeNil)
+		body, _ := io.ReadAll(resp.Body)
+		_ = resp.Body.Close()
+		So(string(body), ShouldContainSubstring, "minotaur_message_queue_depth")
+
+		stop()
+		_, err = net.Dial("tcp", addr)
+		So(err, ShouldNotBeNil)
+	})
+
+	Convey("TestWithMetrics_ListenError", t, func() {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		So(err, ShouldBeNil)
+		defer listener.Close()
+		srv := server.New(server.NetworkNone, server.WithMetrics(listener.Addr().String(), ""))
+		So(srv.RunNone(), ShouldNotBeNil)
+	})
+}
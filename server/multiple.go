@@ -10,8 +10,9 @@ import (
 
 func NewMultipleServer(serverHandle ...func() (addr string, srv *Server)) *MultipleServer {
 	ms := &MultipleServer{
-		servers:   make([]*Server, len(serverHandle), len(serverHandle)),
-		addresses: make([]string, len(serverHandle), len(serverHandle)),
+		servers:      make([]*Server, len(serverHandle), len(serverHandle)),
+		addresses:    make([]string, len(serverHandle), len(serverHandle)),
+		systemSignal: make(chan os.Signal, 1),
 	}
 	for i := 0; i < len(serverHandle); i++ {
 		ms.addresses[i], ms.servers[i] = serverHandle[i]()
@@ -23,6 +24,7 @@ type MultipleServer struct {
 	servers          []*Server
 	addresses        []string
 	exitEventHandles []func()
+	systemSignal     chan os.Signal // 系统信号
 }
 
 func (slf *MultipleServer) Run() {
@@ -68,10 +70,10 @@ func (slf *MultipleServer) Run() {
 	}
 	log.Info("Server", log.String(serverMultipleMark, "===================================================================="))
 
-	systemSignal := make(chan os.Signal, 1)
-	signal.Notify(systemSignal, syscall.SIGHUP, syscall.SIGQUIT, syscall.SIGTERM, syscall.SIGINT)
+	signal.Notify(slf.systemSignal, syscall.SIGHUP, syscall.SIGQUIT, syscall.SIGTERM, syscall.SIGINT)
 	select {
 	case err := <-exceptionChannel:
+		drainMultiple(slf.servers)
 		for _, server := range slf.servers {
 			server.OnStopEvent()
 		}
@@ -82,6 +84,7 @@ func (slf *MultipleServer) Run() {
 		}
 		break
 	case <-runtimeExceptionChannel:
+		drainMultiple(slf.servers)
 		for _, server := range slf.servers {
 			server.OnStopEvent()
 		}
@@ -92,7 +95,8 @@ func (slf *MultipleServer) Run() {
 			slf.servers = slf.servers[1:]
 		}
 		break
-	case <-systemSignal:
+	case <-slf.systemSignal:
+		drainMultiple(slf.servers)
 		for _, server := range slf.servers {
 			server.OnStopEvent()
 		}
@@ -108,6 +112,14 @@ func (slf *MultipleServer) Run() {
 	slf.OnExitEvent()
 }
 
+// Shutdown 主动停止运行所有服务器
+func (slf *MultipleServer) Shutdown() {
+	select {
+	case slf.systemSignal <- syscall.SIGQUIT:
+	default:
+	}
+}
+
 // RegExitEvent 注册退出事件
 func (slf *MultipleServer) RegExitEvent(handle func()) {
 	slf.exitEventHandles = append(slf.exitEventHandles, handle)
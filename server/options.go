@@ -6,7 +6,8 @@ import (
 	"github.com/kercylan98/minotaur/utils/log"
 	"github.com/kercylan98/minotaur/utils/timer"
 	"google.golang.org/grpc"
-	"reflect"
+	"io"
+	"net"
 	"time"
 )
 
@@ -30,17 +31,41 @@ type option struct {
 }
 
 type runtime struct {
-	id                        int64            // 服务器id
-	cross                     map[string]Cross // 跨服
-	deadlockDetect            time.Duration    // 是否开启死锁检测
-	supportMessageTypes       map[int]bool     // websocket模式下支持的消息类型
-	certFile, keyFile         string           // TLS文件
-	messagePoolSize           int              // 消息池大小
-	messageChannelSize        int              // 消息通道大小
-	ticker                    *timer.Ticker    // 定时器
-	websocketReadDeadline     time.Duration    // websocket连接超时时间
-	websocketCompression      int              // websocket压缩等级
-	websocketWriteCompression bool             // websocket写入压缩
+	id                        int64                                // 服务器id
+	cross                     map[string]Cross                     // 跨服
+	deadlockDetect            time.Duration                        // 是否开启死锁检测
+	supportMessageTypes       map[int]bool                         // websocket模式下支持的消息类型
+	certFile, keyFile         string                               // TLS文件
+	messagePoolSize           int                                  // 消息池大小
+	messageChannelSize        int                                  // 消息通道大小
+	ticker                    *timer.Ticker                        // 定时器
+	websocketReadDeadline     time.Duration                        // websocket连接超时时间
+	websocketCompression      int                                  // websocket压缩等级
+	websocketWriteCompression bool                                 // websocket写入压缩
+	packetCodec               PacketCodec                          // 数据包编解码器
+	rpcMaxInflight            int                                  // 请求/响应模式下每个连接同时等待响应的请求数量上限，为 0 时表示未开启
+	sessionGrace              time.Duration                        // 会话保持时间，为 0 时表示未开启
+	sessionQueryKey           string                               // websocket模式下用于自动恢复会话的url参数名称
+	writeQueueLimit           int                                  // 每个连接写入队列的最大字节数，为 0 时表示不限制
+	writeQueuePolicy          WriteQueueFullPolicy                 // 连接写入队列已满时的处理策略
+	rateLimitPackets          int                                  // 每个连接每秒允许接收的数据包数量，为 0 时表示不限制
+	rateLimitBytes            int                                  // 每个连接每秒允许接收的字节数，为 0 时表示不限制
+	rateLimitAction           RateLimitAction                      // 连接超出速率限制时的处理方式
+	ipConnectionLimit         int                                  // 每个 IP 允许建立的连接数量，为 0 时表示不限制
+	ipTrustedProxies          []*net.IPNet                         // 受信任的代理，仅来自受信任代理的请求会采用 X-Real-IP 进行连接数量限制
+	idleTimeout               time.Duration                        // 连接空闲超时时间，为 0 时表示不限制
+	heartbeatInterval         time.Duration                        // 服务器主动发送心跳包的间隔，为 0 时表示不发送
+	heartbeatPacket           Packet                               // 服务器主动发送的心跳包
+	heartbeatMatcher          func(conn *Conn, packet []byte) bool // 心跳包匹配器
+	drainTimeout              time.Duration                        // 关闭服务器时排空连接的超时时间，为 0 时表示不排空
+	crossNames                []string                             // 跨服中间件名称，按添加顺序初始化
+	crossRetryAttempts        int                                  // 跨服中间件初始化最大尝试次数，<= 0 时表示无限重试
+	crossRetryBackoff         time.Duration                        // 跨服中间件初始化首次重试前的等待时间
+	crossRetryMaxBackoff      time.Duration                        // 跨服中间件初始化单次重试前的最大等待时间
+	registry                  *serverRegistry                      // 服务注册中心
+	messageLaneWeights        *[messageLaneCount]int               // 消息优先级通道权重，为 nil 时表示不开启
+	hotUpgrade                bool                                 // 是否开启热更新
+	hotUpgradeTimeout         time.Duration                        // 热更新时等待子进程就绪的超时时间
 }
 
 // WithWebsocketWriteCompression 通过数据写入压缩的方式创建Websocket服务器
@@ -80,6 +105,52 @@ func WithMessageChannelSize(size int) Option {
 	}
 }
 
+// WithMessageLanes 通过消息优先级通道的方式创建服务器
+//   - 消息将根据类型进入不同的优先级通道：MessageLaneHigh（错误、系统、定时器消息）、MessageLaneNormal（跨服、异步消息）、MessageLaneLow（数据包消息及连接的打开、关闭事件）
+//   - high、normal、low 分别为各个通道的权重，调度时每一轮中各个通道最多处理与其权重相同数量的消息，优先级高的通道先被处理
+//   - 权重 <= 0 时将被视为 1，即每个通道在每一轮中至少会被处理一次，从而避免低优先级通道饥饿
+//   - 每个通道的大小均为 WithMessageChannelSize 设置的大小
+//
+// 需要注意的是，开启后不同通道之间的消息将不再保证先后顺序，例如系统消息可能先于更早推送的数据包被处理
+func WithMessageLanes(high, normal, low int) Option {
+	return func(srv *Server) {
+		var weights = [messageLaneCount]int{high, normal, low}
+		for i, weight := range weights {
+			if weight <= 0 {
+				weights[i] = 1
+			}
+		}
+		srv.messageLaneWeights = &weights
+	}
+}
+
+// WithMessageRecord 通过记录所有被处理消息的方式创建服务器，记录可通过 Replay 在 NetworkNone 服务器中进行回放
+//   - 记录包含：数据包消息的连接 ID 及数据、跨服消息的服务器 ID 及数据、定时器名称、连接的打开及关闭，以及每条消息被处理的时间
+//   - 系统消息、异步消息及错误消息仅记录其标记，用于查阅
+//   - 记录将经过缓冲后每秒写入 writer，服务器关闭时将写入剩余的记录，当 writer 实现了 io.Closer 时将一并关闭
+//   - 可通过 NewMessageRecordReader 读取记录
+//
+// 需要注意的是，开启后每条消息都将产生一次写入，通常仅建议在排查问题时开启
+func WithMessageRecord(writer io.Writer) Option {
+	return func(srv *Server) {
+		srv.recorder = newMessageRecorder(writer)
+	}
+}
+
+// WithCrashReport 通过生成崩溃报告的方式创建服务器，处理消息时发生的 panic 将生成 CrashReport 并交由 sinks 处理
+//   - window：相同调用栈签名的崩溃在该时间内仅上报一次，其余仅进行计数，<= 0 时默认为 DefaultCrashReportWindow
+//   - sinks：崩溃报告的接收器，内置了 NewCrashFileSink、NewCrashNotifySink，也可以通过 CrashSinkFunc 自定义
+//   - 崩溃报告包含：消息类型、连接 ID 及连接数据的键、通过 SetMessagePacketVisualizer 可视化后的数据包预览、调用栈等
+//   - 可通过 Server.GetCrashReports 获取所有崩溃报告及其计数
+func WithCrashReport(window time.Duration, sinks ...CrashSink) Option {
+	return func(srv *Server) {
+		if window <= 0 {
+			window = DefaultCrashReportWindow
+		}
+		srv.crashReporter = newCrashReporter(window, sinks)
+	}
+}
+
 // WithDeadlockDetect 通过死锁、死循环、永久阻塞检测的方式创建服务器
 //   - 当检测到死锁、死循环、永久阻塞时，服务器将会生成 WARN 类型的日志，关键字为 "SuspectedDeadlock"
 //   - 默认不开启死锁检测
@@ -128,6 +199,9 @@ func WithTicker(size int, autonomy bool) Option {
 			srv.ticker = timer.GetTicker(size)
 		} else {
 			srv.ticker = timer.GetTicker(size, timer.WithCaller(func(name string, caller func()) {
+				if srv.replaying.Load() {
+					return
+				}
 				PushTickerMessage(srv, caller, name)
 			}))
 		}
@@ -137,27 +211,53 @@ func WithTicker(size int, autonomy bool) Option {
 // WithCross 通过跨服的方式创建服务器
 //   - 推送跨服消息时，将推送到对应 crossName 的跨服中间件中，crossName 可以满足不同功能采用不同的跨服/消息中间件
 //   - 通常情况下 crossName 仅需一个即可
+//   - 跨服中间件将在 Server.Run 中进行初始化，初始化失败时将根据 WithCrossRetry 的策略进行重试
+//   - 初始化期间接收到的跨服消息将被缓存于消息队列中，在服务器开始处理消息后依次处理
+//   - 当跨服中间件实现了 CrossAdvanced 接口时，将支持广播、分组推送及请求/响应，请求将交由 ReceiveCrossRequestEvent 处理
 func WithCross(crossName string, serverId int64, cross Cross) Option {
 	return func(srv *Server) {
-	start:
-		{
-			srv.id = serverId
-			if srv.cross == nil {
-				srv.cross = map[string]Cross{}
-			}
-			srv.cross[crossName] = cross
-			err := cross.Init(srv, func(serverId int64, packet []byte) {
-				msg := srv.messagePool.Get()
-				msg.t = MessageTypeCross
-				msg.attrs = []any{serverId, packet}
-				srv.pushMessage(msg)
-			})
-			if err != nil {
-				log.Info("Cross", log.Int64("ServerID", serverId), log.String("Cross", reflect.TypeOf(cross).String()), log.String("State", "WaitNatsRun"))
-				time.Sleep(1 * time.Second)
-				goto start
-			}
-			log.Info("Cross", log.Int64("ServerID", serverId), log.String("Cross", reflect.TypeOf(cross).String()))
+		srv.id = serverId
+		if srv.cross == nil {
+			srv.cross = map[string]Cross{}
+		}
+		if _, exist := srv.cross[crossName]; !exist {
+			srv.crossNames = append(srv.crossNames, crossName)
+		}
+		srv.cross[crossName] = cross
+	}
+}
+
+// WithCrossRetry 通过特定的重试策略初始化跨服中间件
+//   - attempts：最大尝试次数，<= 0 时表示无限重试，默认为 DefaultCrossRetryAttempts
+//   - backoff：首次重试前的等待时间，此后每次翻倍，默认为 DefaultCrossRetryBackoff
+//   - maxBackoff：单次重试前的最大等待时间，默认为 DefaultCrossRetryMaxBackoff
+//
+// 当跨服中间件在重试后依旧初始化失败时，Server.Run 将返回该错误
+func WithCrossRetry(attempts int, backoff, maxBackoff time.Duration) Option {
+	return func(srv *Server) {
+		srv.crossRetryAttempts = attempts
+		if backoff > 0 {
+			srv.crossRetryBackoff = backoff
+		}
+		if maxBackoff > 0 {
+			srv.crossRetryMaxBackoff = maxBackoff
+		}
+	}
+}
+
+// WithRegistry 通过服务注册中心创建服务器，服务器启动完成后将自动注册并定时续期，关闭时将自动注销
+//   - instance.ID 为 0 时将采用 WithCross 设置的服务器id
+//   - instance.Address 应当为其他服务器可直接连接的地址，例如 "ws://10.0.0.1:8888/ws"，为空时 Server.Run 将返回 ErrRegistryAddress
+//   - ttl：服务实例的存活时间，服务器将以 ttl/3 的间隔进行续期，<= 0 时表示永不过期
+//
+// 注册中心的实现可参考 server/registry 包，通过 Server.Registry 可以获取注册中心以查询或监听其他服务器
+func WithRegistry(registry Registry, instance ServiceInstance, ttl time.Duration) Option {
+	return func(srv *Server) {
+		srv.registry = &serverRegistry{
+			Registry: registry,
+			instance: instance,
+			ttl:      ttl,
+			stop:     make(chan struct{}),
 		}
 	}
 }
@@ -241,14 +341,254 @@ func WithPProf(pattern ...string) Option {
 //
 // 注意事项：
 //   - 需要在分流通道使用完成后主动调用 Server.ShuntChannelFreed 函数释放分流通道，避免内存泄漏
+//   - 如需自动管理分流通道的生命周期，应使用 WithManagedShunt
 func WithShunt(channelGenerator func(guid int64) chan *Message, shuntMatcher func(conn *Conn) (guid int64, allowToCreate bool)) Option {
 	return func(srv *Server) {
 		if channelGenerator == nil || shuntMatcher == nil {
 			log.Warn("WithShunt", log.String("State", "Ignore"), log.String("Reason", "channelGenerator or shuntMatcher is nil"))
 			return
 		}
-		srv.shuntChannels = concurrent.NewBalanceMap[int64, chan *Message]()
+		srv.shunts = concurrent.NewBalanceMap[int64, *shunt]()
 		srv.channelGenerator = channelGenerator
 		srv.shuntMatcher = shuntMatcher
 	}
 }
+
+// WithManagedShunt 通过自动管理生命周期的分流通道创建服务器
+//   - 分流通道将在首次使用时按需创建，并在空闲超过 idleTimeout 且不存在待处理的消息时自动回收，无需调用 Server.ShuntChannelFreed
+//   - shuntMatcher：用于匹配连接的函数，返回值为分流通道的 GUID 和是否进行分流，当返回不进行分流时，将会使用默认的系统通道
+//   - idleTimeout：分流通道的空闲回收时间，<= 0 时默认为 DefaultShuntIdleTimeout
+//   - channelSize：每个分流通道的缓冲大小，<= 0 时默认为 DefaultShuntChannelSize
+//
+// 除 MessageTypePacket 外，可通过 PushSystemMessageTo、PushTickerMessageTo、PushAsyncMessageTo 将消息推送至特定的分流通道，
+// 例如将房间的定时器及异步回调与房间内的数据包在同一分流通道中串行处理
+//
+// 通过 Server.GetShuntStats 可以获取每个分流通道的队列深度及消息延迟
+func WithManagedShunt(shuntMatcher func(conn *Conn) (guid int64, ok bool), idleTimeout time.Duration, channelSize int) Option {
+	return func(srv *Server) {
+		if shuntMatcher == nil {
+			log.Warn("WithManagedShunt", log.String("State", "Ignore"), log.String("Reason", "shuntMatcher is nil"))
+			return
+		}
+		if idleTimeout <= 0 {
+			idleTimeout = DefaultShuntIdleTimeout
+		}
+		if channelSize <= 0 {
+			channelSize = DefaultShuntChannelSize
+		}
+		srv.shunts = concurrent.NewBalanceMap[int64, *shunt]()
+		srv.shuntIdleTimeout = idleTimeout
+		srv.channelGenerator = func(guid int64) chan *Message {
+			return make(chan *Message, channelSize)
+		}
+		srv.shuntMatcher = shuntMatcher
+	}
+}
+
+// WithPacketCodec 通过特定的数据包编解码器创建服务器，用于处理流式传输下的粘包、半包问题
+//   - 支持：NetworkTcp、NetworkTcp4、NetworkTcp6、NetworkUnix、NetworkKcp
+//   - 设置后 ConnectionReceivePacketEvent 将总是接收到完整的应用层数据包，Conn.Write 写入的数据也将采用相同的方式进行封包
+//   - 内置的编解码器可通过 NewLengthFieldCodec、NewVarintLengthCodec、NewDelimiterCodec 创建
+//   - 默认不进行任何封包处理
+func WithPacketCodec(codec PacketCodec) Option {
+	return func(srv *Server) {
+		switch srv.network {
+		case NetworkTcp, NetworkTcp4, NetworkTcp6, NetworkUnix, NetworkKcp:
+			srv.packetCodec = codec
+		}
+	}
+}
+
+// WithRPC 通过请求/响应模式创建服务器，开启后可通过 Conn.Call 向客户端发起请求并等待响应
+//   - 开启后连接上的所有数据包都将采用 PackRPCFrame 的格式进行传输，推送、请求、响应帧可共用同一个连接
+//   - 来自客户端的请求帧将交由 ConnectionReceiveRequestEvent 进行处理，推送帧依旧交由 ConnectionReceivePacketEvent 处理
+//   - maxInflight：每个连接同时等待响应的请求数量上限，<= 0 时默认为 DefaultRPCMaxInflight
+func WithRPC(maxInflight int) Option {
+	return func(srv *Server) {
+		if maxInflight <= 0 {
+			maxInflight = DefaultRPCMaxInflight
+		}
+		srv.rpcMaxInflight = maxInflight
+	}
+}
+
+// WithSession 通过会话保持的方式创建服务器，适用于移动端切换网络等情况下的断线重连
+//   - 每个连接都将被分配一个会话令牌，可通过 Conn.GetSessionToken 获取
+//   - 连接断开后依旧会触发 ConnectionClosedEvent，但连接数据及未发送的数据包将保留 grace 时间，期间写入的数据包也将被缓存
+//   - 客户端在 grace 时间内携带令牌重新连接时，可通过 Server.ResumeSession 恢复会话，超时后将触发 SessionExpiredEvent
+//   - queryKey：NetworkWebsocket 模式下用于自动恢复会话的url参数名称，为空时表示不自动恢复
+//   - 通过 Conn.Close 主动关闭的连接将不会保留会话
+func WithSession(grace time.Duration, queryKey string) Option {
+	return func(srv *Server) {
+		if grace <= 0 {
+			return
+		}
+		srv.sessionGrace = grace
+		srv.sessionQueryKey = queryKey
+		srv.sessions = concurrent.NewBalanceMap[string, *Conn]()
+	}
+}
+
+// WithWriteQueueLimit 通过限制每个连接写入队列大小的方式创建服务器，避免慢速消费者占用过多内存
+//   - maxQueuedBytes：每个连接写入队列中允许堆积的最大字节数，<= 0 时表示不限制，当队列为空时单个数据包不受该限制
+//   - policy：队列已满时的处理策略，可选 WriteQueueFullDrop、WriteQueueFullBlock、WriteQueueFullDisconnect
+//   - 可通过 Conn.GetWriteQueueMetrics 获取连接的写入队列指标
+//   - 默认不限制
+func WithWriteQueueLimit(maxQueuedBytes int, policy WriteQueueFullPolicy) Option {
+	return func(srv *Server) {
+		if maxQueuedBytes <= 0 {
+			return
+		}
+		srv.writeQueueLimit = maxQueuedBytes
+		srv.writeQueuePolicy = policy
+	}
+}
+
+// WithConnectionRateLimit 通过限制每个连接接收数据包速率的方式创建服务器，避免单个连接的洪泛阻塞服务器消息队列
+//   - 采用令牌桶算法，允许的突发量与每秒速率相同
+//   - packetsPerSecond：每秒允许接收的数据包数量，<= 0 时表示不限制
+//   - bytesPerSecond：每秒允许接收的字节数，<= 0 时表示不限制
+//   - action：超出限制时的处理方式，可选 RateLimitActionDrop、RateLimitActionDelay、RateLimitActionClose
+//   - 超出限制时将触发 ConnectionRateLimitedEvent
+//   - 通过 UseVirtualConnEvents 使用虚拟连接时，代理链路连接不受限制，限制将作用于每个虚拟连接
+func WithConnectionRateLimit(packetsPerSecond, bytesPerSecond int, action RateLimitAction) Option {
+	return func(srv *Server) {
+		srv.rateLimitPackets = packetsPerSecond
+		srv.rateLimitBytes = bytesPerSecond
+		srv.rateLimitAction = action
+	}
+}
+
+// WithConnectionLimitPerIP 通过限制每个 IP 允许建立的连接数量的方式创建服务器
+//   - 支持：NetworkWebsocket、NetworkKcp 以及 gnet 驱动的面向连接的网络类型（NetworkTcp、NetworkTcp4、NetworkTcp6、NetworkUnix）
+//   - 超出限制的连接将在建立时被拒绝，并触发 ConnectionLimitExceededEvent
+//   - trustedProxies：受信任的代理 IP 或 CIDR，仅当 WebSocket 请求来自受信任的代理时才会采用 X-Real-IP 请求头中的 IP 进行限制，否则将采用连接的远程地址
+//   - 默认不限制
+func WithConnectionLimitPerIP(max int, trustedProxies ...string) Option {
+	return func(srv *Server) {
+		if max <= 0 {
+			return
+		}
+		srv.ipConnectionLimit = max
+		srv.ipConnections = concurrent.NewBalanceMap[string, int]()
+		for _, proxy := range trustedProxies {
+			_, ipNet, err := net.ParseCIDR(proxy)
+			if err != nil {
+				ip := net.ParseIP(proxy)
+				if ip == nil {
+					log.Warn("Server", log.String("TrustedProxy", proxy), log.Err(err))
+					continue
+				}
+				ipNet = &net.IPNet{IP: ip, Mask: net.CIDRMask(len(ip)*8, len(ip)*8)}
+			}
+			srv.ipTrustedProxies = append(srv.ipTrustedProxies, ipNet)
+		}
+	}
+}
+
+// WithIdleTimeout 通过空闲超时的方式创建服务器，适用于所有面向连接的网络类型
+//   - 当连接超过 timeout 时间未接收到任何数据时，将会被关闭，ConnectionClosedEvent 将收到 ErrConnectionIdleTimeout
+//   - 空闲超时仅关闭连接的传输层，与网络断开相同，开启 WithSession 时连接的会话将被保留
+//   - 与 WithHeartbeat 配合使用时，服务器主动发送的心跳包可以促使客户端进行响应，从而维持连接活跃
+//   - NetworkWebsocket 模式下依旧会受到 WithWebsocketReadDeadline 的影响
+//   - 默认不开启
+func WithIdleTimeout(timeout time.Duration) Option {
+	return func(srv *Server) {
+		if timeout <= 0 {
+			return
+		}
+		srv.idleTimeout = timeout
+	}
+}
+
+// WithHeartbeat 通过心跳的方式创建服务器
+//   - interval：当连接超过 interval 时间未接收到任何数据时，服务器将主动向连接发送 packet 作为心跳包，<= 0 时表示不主动发送
+//   - packet：NetworkWebsocket 模式下可以使用 WebsocketMessageTypePing 类型的数据包，客户端的 pong 响应同样会刷新连接的活跃时间
+//   - matcher：应用层心跳包匹配器，返回 true 的数据包仅用于刷新连接的活跃时间，不会进入 ConnectionReceivePacketEvent，为 nil 时表示不进行匹配
+//   - 开启请求/响应模式时，matcher 接收到的数据包为 PackRPCFrame 格式
+func WithHeartbeat(interval time.Duration, packet Packet, matcher func(conn *Conn, packet []byte) bool) Option {
+	return func(srv *Server) {
+		if interval > 0 {
+			srv.heartbeatInterval = interval
+			srv.heartbeatPacket = packet
+		}
+		srv.heartbeatMatcher = matcher
+	}
+}
+
+// WithDrain 通过在关闭时排空连接的方式创建服务器
+//   - 服务器关闭时将首先停止接受新的连接，随后对每个在线连接执行 ConnectionDrainEvent，可在其中向客户端发送告别数据包
+//   - 在连接写入队列中的数据发送完毕后，将以 ErrServerShutdown 作为原因关闭连接，ConnectionClosedEvent 将收到该原因
+//   - 整个排空过程不会超过 timeout，超时后剩余的连接将被直接关闭
+//   - 在 MultipleServer 中，所有服务器将并行排空
+//   - 默认不开启
+func WithDrain(timeout time.Duration) Option {
+	return func(srv *Server) {
+		if timeout <= 0 {
+			return
+		}
+		srv.drainTimeout = timeout
+	}
+}
+
+// WithMetrics 通过采集服务器指标的方式创建服务器，指标将以 Prometheus 文本格式提供
+//   - addr：独立的指标 HTTP 服务侦听地址，例如 ":9100"；当服务器为 NetworkHttp 且 addr 为空时，指标将挂载到服务器自身的路由中
+//   - 独立的指标 HTTP 服务将在 Server.Run 中启动并在服务器关闭时关闭，侦听失败时 Server.Run 将返回该错误
+//   - path：指标路径，为空时默认为 "/metrics"
+//   - 指标包含：各类型消息的吞吐量及耗时、消息通道及分流通道的堆积数量、连接的打开及关闭数量、收发字节数、异步消息协程池使用情况、定时器调度器数量等
+//   - 也可以通过 Server.WriteMetrics 将指标挂载到自定义的 HTTP 服务中
+func WithMetrics(addr, path string) Option {
+	return func(srv *Server) {
+		if len(path) == 0 {
+			path = "/metrics"
+		}
+		srv.metrics = newServerMetrics()
+		srv.metrics.metricsPath = path
+		srv.metrics.metricsAddr = addr
+		if len(addr) == 0 && srv.network == NetworkHttp {
+			srv.registerMetricsRoute()
+		}
+	}
+}
+
+// WithHotUpgrade 通过支持热更新的方式创建服务器，热更新时新的进程将接管当前服务器的侦听地址，已建立的连接不会被立即断开
+//   - 仅支持 NetworkTcp、NetworkTcp4、NetworkTcp6、NetworkWebsocket 及 NetworkHttp，其他网络类型将不会产生任何效果
+//   - 通过 HotUpgrade 函数或向进程发送 SIGUSR2 信号触发热更新，将以相同的参数启动当前可执行文件作为子进程
+//   - 子进程完成侦听后，当前进程将停止接受新的连接，并通过 Server.Shutdown 的流程排空已有连接后关闭，可配合 WithDrain 使用
+//   - timeout：等待子进程完成侦听的超时时间，超时后子进程将被终止，当前进程继续运行，<= 0 时默认为 DefaultHotUpgradeTimeout
+//
+// 需要注意的是：
+//   - NetworkWebsocket 及 NetworkHttp 将直接向子进程传递侦听器的文件描述符
+//   - NetworkTcp 系列由于 gnet 不支持接管已有的侦听器，将通过 SO_REUSEPORT 在子进程中侦听相同的地址，在当前进程排空期间由系统分配至当前进程的新连接将被拒绝
+//   - 仅适用于通过 Server.Run 运行的服务器，不适用于 MultipleServer
+func WithHotUpgrade(timeout time.Duration) Option {
+	return func(srv *Server) {
+		switch srv.network {
+		case NetworkTcp, NetworkTcp4, NetworkTcp6, NetworkWebsocket, NetworkHttp:
+		default:
+			return
+		}
+		if timeout <= 0 {
+			timeout = DefaultHotUpgradeTimeout
+		}
+		srv.hotUpgrade = true
+		srv.hotUpgradeTimeout = timeout
+	}
+}
+
+// WithAdminConsole 通过开启管理控制台的方式创建服务器，用于替代基于标准输入的 RegConsoleCommandEvent
+//   - network：侦听的网络类型，支持 "tcp" 及 "unix"，当为 "unix" 时 addr 为套接字文件路径
+//   - token：鉴权令牌，请求时需携带 Authorization: Bearer <token> 请求头或 token 查询参数；为空时不进行鉴权，仅允许在 unix 套接字或回环地址下使用，否则 Server.Run 将返回 ErrAdminConsoleToken
+//   - 管理控制台将在 Server.Run 中启动并在服务器关闭时关闭，侦听失败时 Server.Run 将返回该错误
+//   - 开启管理控制台后，RegConsoleCommandEvent 将不再从标准输入读取指令，而是通过管理控制台执行
+//
+// 管理控制台提供以下接口：
+//   - GET /commands：获取所有可用的指令
+//   - POST /exec：执行指令，指令通过请求体或 cmd 查询参数传递，例如 "kick connId"
+//
+// 内置指令包括：help、conns、kick、shunts、tickers、loglevel、config、shutdown，可通过 Server.RegAdminCommand 注册自定义指令
+func WithAdminConsole(network, addr, token string) Option {
+	return func(srv *Server) {
+		srv.adminConsole = newAdminConsole(network, addr, token)
+	}
+}
@@ -0,0 +1,179 @@
+package server
+
+import (
+	"bytes"
+	"encoding/binary"
+)
+
+// PacketCodec 数据包编解码器，用于解决流式传输（TCP、Unix、KCP）下的粘包、半包问题
+//   - 通过 WithPacketCodec 设置后，ConnectionReceivePacketEvent 将总是接收到完整的应用层数据包
+//   - 通过 Conn.Write 写入的数据将会通过 Encode 进行封包后再发送
+type PacketCodec interface {
+	// Encode 对即将写入连接的数据进行封包
+	Encode(data []byte) ([]byte, error)
+	// Decode 尝试从缓冲区中解析出一个完整的数据包
+	//  - 当缓冲区中的数据不足以组成一个完整的数据包时，应当返回 nil, 0, nil
+	//  - n 为本次解析所消耗的字节数
+	Decode(buf []byte) (packet []byte, n int, err error)
+}
+
+// NewLengthFieldCodec 创建一个固定长度头部的数据包编解码器
+//   - lengthFieldSize：长度字段占用的字节数，仅支持 2 或 4
+//   - order：长度字段的字节序，为 nil 时默认采用 binary.BigEndian
+//   - maxPacketSize：单个数据包允许的最大长度，<= 0 时表示采用长度字段所能表示的最大值
+func NewLengthFieldCodec(lengthFieldSize int, order binary.ByteOrder, maxPacketSize int) *LengthFieldCodec {
+	if lengthFieldSize != 2 && lengthFieldSize != 4 {
+		panic(ErrPacketCodecLengthFieldSize)
+	}
+	if order == nil {
+		order = binary.BigEndian
+	}
+	var limit = 1<<(lengthFieldSize*8) - 1
+	if maxPacketSize <= 0 || maxPacketSize > limit {
+		maxPacketSize = limit
+	}
+	return &LengthFieldCodec{
+		size:  lengthFieldSize,
+		order: order,
+		max:   maxPacketSize,
+	}
+}
+
+// LengthFieldCodec 固定长度头部的数据包编解码器
+//   - 数据包格式：[长度字段][数据]
+type LengthFieldCodec struct {
+	size  int              // 长度字段占用的字节数
+	order binary.ByteOrder // 字节序
+	max   int              // 数据包最大长度
+}
+
+// Encode 对即将写入连接的数据进行封包
+func (slf *LengthFieldCodec) Encode(data []byte) ([]byte, error) {
+	if len(data) > slf.max {
+		return nil, ErrPacketTooLarge
+	}
+	var packet = make([]byte, slf.size+len(data))
+	switch slf.size {
+	case 2:
+		slf.order.PutUint16(packet, uint16(len(data)))
+	case 4:
+		slf.order.PutUint32(packet, uint32(len(data)))
+	}
+	copy(packet[slf.size:], data)
+	return packet, nil
+}
+
+// Decode 尝试从缓冲区中解析出一个完整的数据包
+func (slf *LengthFieldCodec) Decode(buf []byte) (packet []byte, n int, err error) {
+	if len(buf) < slf.size {
+		return nil, 0, nil
+	}
+	var length int
+	switch slf.size {
+	case 2:
+		length = int(slf.order.Uint16(buf))
+	case 4:
+		length = int(slf.order.Uint32(buf))
+	}
+	if length > slf.max {
+		return nil, 0, ErrPacketTooLarge
+	}
+	if len(buf) < slf.size+length {
+		return nil, 0, nil
+	}
+	n = slf.size + length
+	return bytes.Clone(buf[slf.size:n]), n, nil
+}
+
+// NewVarintLengthCodec 创建一个采用 varint 编码长度头部的数据包编解码器
+//   - maxPacketSize：单个数据包允许的最大长度，<= 0 时默认为 DefaultPacketCodecMaxSize
+func NewVarintLengthCodec(maxPacketSize int) *VarintLengthCodec {
+	if maxPacketSize <= 0 {
+		maxPacketSize = DefaultPacketCodecMaxSize
+	}
+	return &VarintLengthCodec{max: maxPacketSize}
+}
+
+// VarintLengthCodec 采用 varint 编码长度头部的数据包编解码器
+//   - 数据包格式：[uvarint 长度][数据]
+type VarintLengthCodec struct {
+	max int // 数据包最大长度
+}
+
+// Encode 对即将写入连接的数据进行封包
+func (slf *VarintLengthCodec) Encode(data []byte) ([]byte, error) {
+	if len(data) > slf.max {
+		return nil, ErrPacketTooLarge
+	}
+	var packet = make([]byte, binary.MaxVarintLen64+len(data))
+	var n = binary.PutUvarint(packet, uint64(len(data)))
+	copy(packet[n:], data)
+	return packet[:n+len(data)], nil
+}
+
+// Decode 尝试从缓冲区中解析出一个完整的数据包
+func (slf *VarintLengthCodec) Decode(buf []byte) (packet []byte, n int, err error) {
+	length, size := binary.Uvarint(buf)
+	if size == 0 {
+		return nil, 0, nil
+	}
+	if size < 0 || length > uint64(slf.max) {
+		return nil, 0, ErrPacketTooLarge
+	}
+	if uint64(len(buf)-size) < length {
+		return nil, 0, nil
+	}
+	n = size + int(length)
+	return bytes.Clone(buf[size:n]), n, nil
+}
+
+// NewDelimiterCodec 创建一个基于分隔符的数据包编解码器
+//   - delimiter：分隔符，例如 []byte("\n")
+//   - maxPacketSize：单个数据包允许的最大长度，<= 0 时默认为 DefaultPacketCodecMaxSize
+//
+// 需要注意的是，数据包中不应当包含分隔符，否则将会被拆分为多个数据包
+func NewDelimiterCodec(delimiter []byte, maxPacketSize int) *DelimiterCodec {
+	if len(delimiter) == 0 {
+		panic(ErrPacketCodecDelimiter)
+	}
+	if maxPacketSize <= 0 {
+		maxPacketSize = DefaultPacketCodecMaxSize
+	}
+	return &DelimiterCodec{
+		delimiter: bytes.Clone(delimiter),
+		max:       maxPacketSize,
+	}
+}
+
+// DelimiterCodec 基于分隔符的数据包编解码器
+//   - 数据包格式：[数据][分隔符]
+type DelimiterCodec struct {
+	delimiter []byte // 分隔符
+	max       int    // 数据包最大长度
+}
+
+// Encode 对即将写入连接的数据进行封包
+func (slf *DelimiterCodec) Encode(data []byte) ([]byte, error) {
+	if len(data) > slf.max {
+		return nil, ErrPacketTooLarge
+	}
+	var packet = make([]byte, 0, len(data)+len(slf.delimiter))
+	packet = append(packet, data...)
+	return append(packet, slf.delimiter...), nil
+}
+
+// Decode 尝试从缓冲区中解析出一个完整的数据包
+func (slf *DelimiterCodec) Decode(buf []byte) (packet []byte, n int, err error) {
+	var index = bytes.Index(buf, slf.delimiter)
+	if index == -1 {
+		if len(buf) > slf.max {
+			return nil, 0, ErrPacketTooLarge
+		}
+		return nil, 0, nil
+	}
+	if index > slf.max {
+		return nil, 0, ErrPacketTooLarge
+	}
+	n = index + len(slf.delimiter)
+	return bytes.Clone(buf[:index]), n, nil
+}
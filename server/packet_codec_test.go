@@ -0,0 +1,52 @@
+package server_test
+
+import (
+	"encoding/binary"
+	"github.com/kercylan98/minotaur/server"
+	. "github.com/smartystreets/goconvey/convey"
+	"testing"
+)
+
+func TestPacketCodec(t *testing.T) {
+	var codecs = map[string]server.PacketCodec{
+		"LengthField2": server.NewLengthFieldCodec(2, binary.BigEndian, 0),
+		"LengthField4": server.NewLengthFieldCodec(4, binary.LittleEndian, 0),
+		"Varint":       server.NewVarintLengthCodec(0),
+		"Delimiter":    server.NewDelimiterCodec([]byte("\r\n"), 0),
+	}
+	for name, codec := range codecs {
+		Convey(name, t, func() {
+			var stream []byte
+			for _, data := range []string{"hello", "minotaur", "!"} {
+				packet, err := codec.Encode([]byte(data))
+				So(err, ShouldBeNil)
+				stream = append(stream, packet...)
+			}
+
+			packet, n, err := codec.Decode(stream[:3])
+			So(err, ShouldBeNil)
+			So(n, ShouldEqual, 0)
+			So(packet, ShouldBeNil)
+
+			var result []string
+			for len(stream) > 0 {
+				packet, n, err = codec.Decode(stream)
+				So(err, ShouldBeNil)
+				So(n, ShouldBeGreaterThan, 0)
+				result = append(result, string(packet))
+				stream = stream[n:]
+			}
+			So(result, ShouldResemble, []string{"hello", "minotaur", "!"})
+		})
+	}
+}
+
+func TestPacketCodecTooLarge(t *testing.T) {
+	Convey("TestPacketCodecTooLarge", t, func() {
+		codec := server.NewLengthFieldCodec(2, nil, 4)
+		_, err := codec.Encode([]byte("hello"))
+		So(err, ShouldEqual, server.ErrPacketTooLarge)
+		_, _, err = codec.Decode([]byte{0, 5, 'h', 'e', 'l', 'l', 'o'})
+		So(err, ShouldEqual, server.ErrPacketTooLarge)
+	})
+}
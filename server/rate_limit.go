@@ -0,0 +1,199 @@
+package server
+
+import (
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// RateLimitActionDrop 超出速率限制时丢弃数据包
+	RateLimitActionDrop RateLimitAction = iota
+	// RateLimitActionDelay 超出速率限制时延迟读取，直到令牌足够后再进行处理
+	//   - 在 gnet 驱动的网络类型（NetworkTcp、NetworkUdp、NetworkUnix 等）下，延迟将会阻塞连接所在的事件循环，建议采用其他方式
+	RateLimitActionDelay
+	// RateLimitActionClose 超出速率限制时关闭连接，ConnectionClosedEvent 将收到 ErrConnectionRateLimited
+	RateLimitActionClose
+)
+
+var rateLimitActionNames = map[RateLimitAction]string{
+	RateLimitActionDrop:  "Drop",
+	RateLimitActionDelay: "Delay",
+	RateLimitActionClose: "Close",
+}
+
+// RateLimitAction 连接超出速率限制时的处理方式
+type RateLimitAction byte
+
+func (slf RateLimitAction) String() string {
+	return rateLimitActionNames[slf]
+}
+
+// newTokenBucket 创建一个每秒产生 rate 个令牌，最多存储 rate 个令牌的令牌桶
+func newTokenBucket(rate int) *tokenBucket {
+	return &tokenBucket{
+		rate:   float64(rate),
+		tokens: float64(rate),
+		last:   time.Now(),
+	}
+}
+
+// tokenBucket 令牌桶
+type tokenBucket struct {
+	mutex  sync.Mutex
+	rate   float64   // 每秒产生的令牌数量
+	tokens float64   // 当前令牌数量
+	last   time.Time // 上次更新时间
+}
+
+// take 尝试获取 n 个令牌，获取失败时返回需要等待的时间
+//   - 当 n 超过令牌桶容量时，将会在令牌桶满时允许通过
+func (slf *tokenBucket) take(n int) (ok bool, wait time.Duration) {
+	slf.mutex.Lock()
+	defer slf.mutex.Unlock()
+	now := time.Now()
+	slf.tokens += now.Sub(slf.last).Seconds() * slf.rate
+	if slf.tokens > slf.rate {
+		slf.tokens = slf.rate
+	}
+	slf.last = now
+	need := float64(n)
+	if need > slf.rate {
+		need = slf.rate
+	}
+	if slf.tokens >= need {
+		slf.tokens -= float64(n)
+		return true, 0
+	}
+	return false, time.Duration((need - slf.tokens) / slf.rate * float64(time.Second))
+}
+
+// refund 归还通过 take 获取的 n 个令牌
+func (slf *tokenBucket) refund(n int) {
+	slf.mutex.Lock()
+	defer slf.mutex.Unlock()
+	slf.tokens += float64(n)
+	if slf.tokens > slf.rate {
+		slf.tokens = slf.rate
+	}
+}
+
+// newConnRateLimiter 当服务器开启连接速率限制时为连接创建限制器
+func newConnRateLimiter(server *Server) *connRateLimiter {
+	if server.rateLimitPackets <= 0 && server.rateLimitBytes <= 0 {
+		return nil
+	}
+	limiter := new(connRateLimiter)
+	if server.rateLimitPackets > 0 {
+		limiter.packets = newTokenBucket(server.rateLimitPackets)
+	}
+	if server.rateLimitBytes > 0 {
+		limiter.bytes = newTokenBucket(server.rateLimitBytes)
+	}
+	return limiter
+}
+
+// connRateLimiter 连接速率限制器
+type connRateLimiter struct {
+	packets *tokenBucket // 数据包数量限制
+	bytes   *tokenBucket // 字节数限制
+	limited atomic.Bool  // 是否处于受限状态，仅在进入受限状态时触发 ConnectionRateLimitedEvent
+}
+
+// take 尝试通过一个大小为 size 的数据包
+//   - 字节数限制未通过时将归还已获取的数据包令牌，避免延迟重试时重复消耗
+func (slf *connRateLimiter) take(size int) (ok bool, wait time.Duration) {
+	if slf.packets != nil {
+		if ok, wait = slf.packets.take(1); !ok {
+			return
+		}
+	}
+	if slf.bytes != nil {
+		if ok, wait = slf.bytes.take(size); !ok {
+			if slf.packets != nil {
+				slf.packets.refund(1)
+			}
+			return
+		}
+	}
+	return true, 0
+}
+
+// limit 对连接接收到的数据包进行速率限制，返回值表示数据包是否允许被处理
+//   - 连续被限制的数据包仅在首次被限制时触发 ConnectionRateLimitedEvent，直到有数据包再次被允许处理
+func (slf *Conn) limit(size int) bool {
+	if slf.limiter == nil {
+		return true
+	}
+	for {
+		ok, wait := slf.limiter.take(size)
+		if ok {
+			slf.limiter.limited.Store(false)
+			return true
+		}
+		var action = slf.server.rateLimitAction
+		if slf.limiter.limited.CompareAndSwap(false, true) {
+			slf.server.OnConnectionRateLimitedEvent(slf, action)
+		}
+		switch action {
+		case RateLimitActionDelay:
+			time.Sleep(wait)
+		case RateLimitActionClose:
+			slf.closeWithReason(ErrConnectionRateLimited)
+			return false
+		default:
+			return false
+		}
+	}
+}
+
+// limitIP 获取 WebSocket 请求用于连接数量限制的 IP
+//   - 仅当请求来自 WithConnectionLimitPerIP 设置的受信任代理时才会采用 X-Real-IP，避免客户端通过伪造请求头绕过限制
+func (slf *Server) limitIP(request *http.Request) string {
+	ip := request.RemoteAddr
+	if index := strings.LastIndex(ip, ":"); index != -1 {
+		ip = ip[0:index]
+	}
+	if realIP := request.Header.Get("X-Real-IP"); len(realIP) > 0 && len(slf.ipTrustedProxies) > 0 {
+		remote := net.ParseIP(strings.Trim(ip, "[]"))
+		for _, proxy := range slf.ipTrustedProxies {
+			if remote != nil && proxy.Contains(remote) {
+				return realIP
+			}
+		}
+	}
+	return ip
+}
+
+// acquireIP 尝试为特定 IP 占用一个连接数量，超出 WithConnectionLimitPerIP 设置的上限时返回 false
+func (slf *Server) acquireIP(ip string) (ok bool) {
+	if slf.ipConnectionLimit <= 0 {
+		return true
+	}
+	slf.ipConnections.Atom(func(m map[string]int) {
+		if m[ip] >= slf.ipConnectionLimit {
+			return
+		}
+		m[ip]++
+		ok = true
+	})
+	if !ok {
+		slf.OnConnectionLimitExceededEvent(ip)
+	}
+	return
+}
+
+// releaseIP 释放特定 IP 占用的连接数量
+func (slf *Server) releaseIP(ip string) {
+	if slf.ipConnectionLimit <= 0 {
+		return
+	}
+	slf.ipConnections.Atom(func(m map[string]int) {
+		if m[ip]--; m[ip] <= 0 {
+			delete(m, ip)
+		}
+	})
+}
@@ -0,0 +1,129 @@
+package server_test
+
+import (
+	"github.com/gorilla/websocket"
+	"github.com/kercylan98/minotaur/server"
+	. "github.com/smartystreets/goconvey/convey"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWithConnectionLimitPerIP(t *testing.T) {
+	var dial = func(url, realIP string) (*websocket.Conn, int) {
+		ws, resp, err := websocket.DefaultDialer.Dial(url, http.Header{"X-Real-IP": []string{realIP}})
+		if err != nil {
+			if resp != nil {
+				return nil, resp.StatusCode
+			}
+			return nil, 0
+		}
+		return ws, http.StatusSwitchingProtocols
+	}
+
+	Convey("TestWithConnectionLimitPerIP", t, func() {
+		Convey("Untrusted", func() {
+			var addr = freeAddr()
+			var url = "ws://" + addr + "/limit-untrusted-" + strings.ReplaceAll(addr, ":", "-")
+			srv := server.New(server.NetworkWebsocket, server.WithConnectionLimitPerIP(1))
+			stop := runServer(srv, strings.TrimPrefix(url, "ws://"))
+			defer stop()
+			waitListen(addr)
+
+			first, code := dial(url, "10.0.0.1")
+			So(code, ShouldEqual, http.StatusSwitchingProtocols)
+			defer first.Close()
+			_, code = dial(url, "10.0.0.2")
+			So(code, ShouldEqual, http.StatusTooManyRequests)
+		})
+
+		Convey("Trusted", func() {
+			var addr = freeAddr()
+			var url = "ws://" + addr + "/limit-trusted-" + strings.ReplaceAll(addr, ":", "-")
+			srv := server.New(server.NetworkWebsocket, server.WithConnectionLimitPerIP(1, "127.0.0.0/8"))
+			stop := runServer(srv, strings.TrimPrefix(url, "ws://"))
+			defer stop()
+			waitListen(addr)
+
+			first, code := dial(url, "10.0.0.1")
+			So(code, ShouldEqual, http.StatusSwitchingProtocols)
+			defer first.Close()
+			second, code := dial(url, "10.0.0.2")
+			So(code, ShouldEqual, http.StatusSwitchingProtocols)
+			defer second.Close()
+			_, code = dial(url, "10.0.0.1")
+			So(code, ShouldEqual, http.StatusTooManyRequests)
+		})
+	})
+}
+
+func TestWithConnectionRateLimit(t *testing.T) {
+	Convey("TestWithConnectionRateLimit", t, func() {
+		var addr = freeAddr()
+		var url = "ws://" + addr + "/rate-limit-" + strings.ReplaceAll(addr, ":", "-")
+		srv := server.New(server.NetworkWebsocket, server.WithConnectionRateLimit(2, 0, server.RateLimitActionDrop))
+		var received, limited int
+		srv.RegConnectionReceivePacketEvent(func(srv *server.Server, conn *server.Conn, packet server.Packet) {
+			received++
+		})
+		srv.RegConnectionRateLimitedEvent(func(srv *server.Server, conn *server.Conn, action server.RateLimitAction) {
+			limited++
+		})
+		stop := runServer(srv, strings.TrimPrefix(url, "ws://"))
+		defer stop()
+		waitListen(addr)
+
+		ws, _, err := websocket.DefaultDialer.Dial(url, nil)
+		So(err, ShouldBeNil)
+		defer ws.Close()
+		for i := 0; i < 10; i++ {
+			So(ws.WriteMessage(websocket.BinaryMessage, []byte("p")), ShouldBeNil)
+		}
+		time.Sleep(600 * time.Millisecond)
+		for i := 0; i < 11; i++ {
+			So(ws.WriteMessage(websocket.BinaryMessage, []byte("p")), ShouldBeNil)
+		}
+		time.Sleep(100 * time.Millisecond)
+
+		var done = make(chan struct{})
+		server.PushSystemMessage(srv, func() {
+			close(done)
+		})
+		<-done
+		So(received, ShouldBeBetweenOrEqual, 3, 4)
+		So(limited, ShouldEqual, 2)
+	})
+}
+
+func TestWithConnectionRateLimit_Bytes(t *testing.T) {
+	Convey("TestWithConnectionRateLimit_Bytes", t, func() {
+		var addr = freeAddr()
+		var url = "ws://" + addr + "/rate-limit-bytes-" + strings.ReplaceAll(addr, ":", "-")
+		srv := server.New(server.NetworkWebsocket, server.WithConnectionRateLimit(2, 10, server.RateLimitActionDrop))
+		var received []string
+		srv.RegConnectionReceivePacketEvent(func(srv *server.Server, conn *server.Conn, packet server.Packet) {
+			received = append(received, string(packet.Data))
+		})
+		stop := runServer(srv, strings.TrimPrefix(url, "ws://"))
+		defer stop()
+		waitListen(addr)
+
+		ws, _, err := websocket.DefaultDialer.Dial(url, nil)
+		So(err, ShouldBeNil)
+		defer ws.Close()
+		So(ws.WriteMessage(websocket.BinaryMessage, []byte("aaaaaaaaaa")), ShouldBeNil)
+		So(ws.WriteMessage(websocket.BinaryMessage, []byte("bbbbbbbbbb")), ShouldBeNil)
+		// 被字节数限制的数据包不应消耗数据包令牌
+		time.Sleep(200 * time.Millisecond)
+		So(ws.WriteMessage(websocket.BinaryMessage, []byte("c")), ShouldBeNil)
+		time.Sleep(100 * time.Millisecond)
+
+		var done = make(chan struct{})
+		server.PushSystemMessage(srv, func() {
+			close(done)
+		})
+		<-done
+		So(received, ShouldResemble, []string{"aaaaaaaaaa", "c"})
+	})
+}
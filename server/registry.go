@@ -0,0 +1,110 @@
+package server
+
+import (
+	"github.com/kercylan98/minotaur/utils/log"
+	"sync"
+	"time"
+)
+
+// ServiceInstance 注册中心中的服务实例
+type ServiceInstance struct {
+	ID       int64             `json:"id"`                 // 服务器id
+	Type     string            `json:"type"`               // 服务器类型，例如 login、lobby、battle
+	Address  string            `json:"address"`            // 服务器地址
+	Metadata map[string]string `json:"metadata,omitempty"` // 元数据
+}
+
+// RegistryEventType 注册中心事件类型
+type RegistryEventType byte
+
+const (
+	RegistryEventAdd    RegistryEventType = iota + 1 // 服务实例被添加或发生变更
+	RegistryEventRemove                              // 服务实例被移除或过期
+)
+
+// RegistryEvent 注册中心事件
+type RegistryEvent struct {
+	Type     RegistryEventType
+	Instance ServiceInstance
+}
+
+// Registry 服务注册中心接口
+type Registry interface {
+	// Register 注册服务实例，服务实例将在 ttl 后过期，需要在过期前再次调用 Register 进行续期
+	//  - ttl <= 0 时表示永不过期
+	Register(instance ServiceInstance, ttl time.Duration) error
+	// Deregister 注销服务实例
+	Deregister(instance ServiceInstance) error
+	// Instances 获取特定类型的服务实例，serverType 为空时将获取所有服务实例
+	Instances(serverType string) ([]ServiceInstance, error)
+	// Watch 监听特定类型的服务实例变化，serverType 为空时将监听所有服务实例
+	//  - 开始监听时，已存在的服务实例将以 RegistryEventAdd 事件的形式立即通知
+	//  - 通过返回的 cancel 函数取消监听
+	Watch(serverType string, handle func(event RegistryEvent)) (cancel func(), err error)
+	// Release 释放资源
+	Release()
+}
+
+// serverRegistry 服务器在注册中心中的注册信息
+type serverRegistry struct {
+	Registry
+	instance ServiceInstance
+	ttl      time.Duration
+	once     sync.Once
+	stop     chan struct{}
+}
+
+// Registry 获取服务器使用的注册中心
+func (slf *Server) Registry() Registry {
+	if slf.registry == nil {
+		panic(ErrNoSupportRegistry)
+	}
+	return slf.registry.Registry
+}
+
+// startRegistry 将服务器注册至注册中心，并在服务器关闭前持续进行续期
+func (slf *Server) startRegistry() {
+	if slf.registry == nil {
+		return
+	}
+	var r = slf.registry
+	if r.instance.ID == 0 {
+		r.instance.ID = slf.id
+	}
+	if err := r.Register(r.instance, r.ttl); err != nil {
+		log.Error("Registry", log.Int64("ServerID", r.instance.ID), log.String("Type", r.instance.Type), log.Err(err))
+	} else {
+		log.Info("Registry", log.Int64("ServerID", r.instance.ID), log.String("Type", r.instance.Type), log.String("Address", r.instance.Address))
+	}
+	if r.ttl <= 0 {
+		return
+	}
+	go func() {
+		var ticker = time.NewTicker(r.ttl / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-r.stop:
+				return
+			case <-ticker.C:
+				if err := r.Register(r.instance, r.ttl); err != nil {
+					log.Error("Registry", log.Int64("ServerID", r.instance.ID), log.String("Type", r.instance.Type), log.Err(err))
+				}
+			}
+		}
+	}()
+}
+
+// releaseRegistry 从注册中心注销服务器并释放注册中心
+func (slf *Server) releaseRegistry() {
+	if slf.registry == nil {
+		return
+	}
+	slf.registry.once.Do(func() {
+		close(slf.registry.stop)
+		if err := slf.registry.Deregister(slf.registry.instance); err != nil {
+			log.Error("Registry", log.Int64("ServerID", slf.registry.instance.ID), log.Err(err))
+		}
+		slf.registry.Release()
+	})
+}
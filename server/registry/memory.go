@@ -0,0 +1,40 @@
+package registry
+
+import (
+	"github.com/kercylan98/minotaur/server"
+	"time"
+)
+
+// NewMemory 创建一个基于内存的服务注册中心
+//   - 同一个 Memory 可被同一进程内的多个服务器共享，适用于测试或单进程部署的场景
+func NewMemory() *Memory {
+	return &Memory{store: newStore()}
+}
+
+// Memory 基于内存的服务注册中心
+type Memory struct {
+	store *store
+}
+
+func (slf *Memory) Register(instance server.ServiceInstance, ttl time.Duration) error {
+	slf.store.put(instance, ttl)
+	return nil
+}
+
+func (slf *Memory) Deregister(instance server.ServiceInstance) error {
+	slf.store.remove(instance.ID)
+	return nil
+}
+
+func (slf *Memory) Instances(serverType string) ([]server.ServiceInstance, error) {
+	return slf.store.list(serverType), nil
+}
+
+func (slf *Memory) Watch(serverType string, handle func(event server.RegistryEvent)) (cancel func(), err error) {
+	return slf.store.watch(serverType, handle), nil
+}
+
+// Release 由于 Memory 可能被多个服务器共享，释放时不会清除任何服务实例及监听者
+func (slf *Memory) Release() {
+
+}
@@ -0,0 +1,218 @@
+package registry
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/kercylan98/minotaur/server"
+	"github.com/kercylan98/minotaur/utils/log"
+	"github.com/nats-io/nats.go"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	natsKVMark = "Registry.NatsKV"
+
+	// DefaultNatsKVLoadTimeout 首次加载 KV 存储中已存在的服务实例的超时时间
+	DefaultNatsKVLoadTimeout = 5 * time.Second
+)
+
+var (
+	// ErrNatsKVLoadTimeout 首次加载 KV 存储中已存在的服务实例超时
+	ErrNatsKVLoadTimeout = errors.New("registry: nats kv initial load timeout")
+)
+
+// NewNatsKV 创建一个基于 nats JetStream KV 存储的服务注册中心
+//   - 需要 nats 服务器开启 JetStream
+//   - 服务实例以服务器id为键存储，值中包含过期时间，各个节点将在本地对过期的服务实例进行移除
+func NewNatsKV(url string, options ...NatsKVOption) *NatsKV {
+	n := &NatsKV{
+		url:    url,
+		bucket: "MINOTAUR_REGISTRY",
+		store:  newStore(),
+	}
+	for _, option := range options {
+		option(n)
+	}
+	return n
+}
+
+// NatsKV 基于 nats JetStream KV 存储的服务注册中心
+type NatsKV struct {
+	conn      *nats.Conn
+	url       string
+	bucket    string
+	bucketTTL time.Duration
+	options   []nats.Option
+	kv        nats.KeyValue
+	watcher   nats.KeyWatcher
+	store     *store
+	mutex     sync.Mutex
+	inited    bool
+	loaded    chan struct{} // 首次加载完成或监听结束时关闭
+}
+
+// natsKVRecord KV 存储中的服务实例记录
+type natsKVRecord struct {
+	Instance server.ServiceInstance `json:"instance"`
+	ExpireAt int64                  `json:"expire_at,omitempty"` // 过期时间（毫秒时间戳），为 0 时表示永不过期
+}
+
+func (slf *NatsKV) Register(instance server.ServiceInstance, ttl time.Duration) error {
+	if err := slf.init(); err != nil {
+		return err
+	}
+	var record = natsKVRecord{Instance: instance}
+	if ttl > 0 {
+		record.ExpireAt = time.Now().Add(ttl).UnixMilli()
+	}
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	_, err = slf.kv.Put(slf.key(instance.ID), data)
+	return err
+}
+
+func (slf *NatsKV) Deregister(instance server.ServiceInstance) error {
+	if err := slf.init(); err != nil {
+		return err
+	}
+	return slf.kv.Delete(slf.key(instance.ID))
+}
+
+func (slf *NatsKV) Instances(serverType string) ([]server.ServiceInstance, error) {
+	if err := slf.init(); err != nil {
+		return nil, err
+	}
+	return slf.store.list(serverType), nil
+}
+
+func (slf *NatsKV) Watch(serverType string, handle func(event server.RegistryEvent)) (cancel func(), err error) {
+	if err = slf.init(); err != nil {
+		return nil, err
+	}
+	return slf.store.watch(serverType, handle), nil
+}
+
+func (slf *NatsKV) Release() {
+	if slf.watcher != nil {
+		_ = slf.watcher.Stop()
+	}
+	slf.store.release()
+	if slf.conn != nil {
+		slf.conn.Close()
+	}
+}
+
+// init 首次使用时连接 nats 并开始监听 KV 存储的变化
+//   - 仅在成功后标记为已初始化，失败时将在下一次使用时重试
+//   - 将在锁外等待已存在的服务实例加载完成，超过 DefaultNatsKVLoadTimeout 时返回 ErrNatsKVLoadTimeout，下一次使用时将继续等待
+func (slf *NatsKV) init() error {
+	slf.mutex.Lock()
+	if !slf.inited {
+		if err := slf.connect(); err != nil {
+			slf.mutex.Unlock()
+			return err
+		}
+		slf.inited = true
+	}
+	var loaded = slf.loaded
+	slf.mutex.Unlock()
+
+	var timer = time.NewTimer(DefaultNatsKVLoadTimeout)
+	defer timer.Stop()
+	select {
+	case <-loaded:
+		return nil
+	case <-timer.C:
+		return ErrNatsKVLoadTimeout
+	}
+}
+
+func (slf *NatsKV) connect() (err error) {
+	if slf.conn == nil {
+		if len(slf.options) == 0 {
+			slf.options = append(slf.options,
+				nats.ReconnectWait(time.Second*5),
+				nats.MaxReconnects(-1),
+				nats.DisconnectErrHandler(func(conn *nats.Conn, err error) {
+					log.Error(natsKVMark, log.String("info", "disconnect"), log.Err(err))
+				}),
+				nats.ReconnectHandler(func(conn *nats.Conn) {
+					log.Info(natsKVMark, log.String("info", "reconnect"))
+				}),
+			)
+		}
+		if slf.conn, err = nats.Connect(slf.url, slf.options...); err != nil {
+			return err
+		}
+	}
+	js, err := slf.conn.JetStream()
+	if err != nil {
+		return err
+	}
+	slf.kv, err = js.KeyValue(slf.bucket)
+	if errors.Is(err, nats.ErrBucketNotFound) {
+		slf.kv, err = js.CreateKeyValue(&nats.KeyValueConfig{Bucket: slf.bucket, TTL: slf.bucketTTL})
+	}
+	if err != nil {
+		return err
+	}
+	if slf.watcher, err = slf.kv.WatchAll(); err != nil {
+		return err
+	}
+
+	// 首次加载的数据将以 nil 结尾，在此之前的数据为已存在的服务实例，监听在此之前结束时同样视为加载完成
+	var loaded = make(chan struct{})
+	slf.loaded = loaded
+	go func() {
+		var done = loaded
+		defer func() {
+			if done != nil {
+				close(done)
+			}
+		}()
+		for entry := range slf.watcher.Updates() {
+			if entry == nil {
+				if done != nil {
+					close(done)
+					done = nil
+				}
+				continue
+			}
+			slf.apply(entry)
+		}
+	}()
+	return nil
+}
+
+// apply 将 KV 存储的变化同步至本地
+func (slf *NatsKV) apply(entry nats.KeyValueEntry) {
+	id, err := strconv.ParseInt(entry.Key(), 10, 64)
+	if err != nil {
+		return
+	}
+	if entry.Operation() != nats.KeyValuePut {
+		slf.store.remove(id)
+		return
+	}
+	var record natsKVRecord
+	if err = json.Unmarshal(entry.Value(), &record); err != nil {
+		log.Error(natsKVMark, log.String("key", entry.Key()), log.Err(err))
+		return
+	}
+	var ttl time.Duration
+	if record.ExpireAt > 0 {
+		if ttl = time.Until(time.UnixMilli(record.ExpireAt)); ttl <= 0 {
+			slf.store.remove(id)
+			return
+		}
+	}
+	slf.store.put(record.Instance, ttl)
+}
+
+func (slf *NatsKV) key(id int64) string {
+	return strconv.FormatInt(id, 10)
+}
@@ -0,0 +1,40 @@
+package registry
+
+import (
+	"github.com/nats-io/nats.go"
+	"time"
+)
+
+type NatsKVOption func(n *NatsKV)
+
+// WithNatsKVBucket 通过特定名称的 KV 存储桶创建
+//   - 默认为：MINOTAUR_REGISTRY
+func WithNatsKVBucket(bucket string) NatsKVOption {
+	return func(n *NatsKV) {
+		n.bucket = bucket
+	}
+}
+
+// WithNatsKVBucketTTL 设置创建 KV 存储桶时的过期时间，用于清理已过期的服务实例
+//   - 该值应当大于服务实例的 ttl，默认为 0，表示不进行清理
+//   - 存储桶已存在时该选项不会生效
+func WithNatsKVBucketTTL(ttl time.Duration) NatsKVOption {
+	return func(n *NatsKV) {
+		n.bucketTTL = ttl
+	}
+}
+
+// WithNatsKVOptions 通过nats自带的可选项创建连接
+func WithNatsKVOptions(options ...nats.Option) NatsKVOption {
+	return func(n *NatsKV) {
+		n.options = options
+	}
+}
+
+// WithNatsKVConn 指定通过特定的连接创建
+//   - 这将导致 WithNatsKVOptions 失效
+func WithNatsKVConn(conn *nats.Conn) NatsKVOption {
+	return func(n *NatsKV) {
+		n.conn = conn
+	}
+}
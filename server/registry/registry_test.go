@@ -0,0 +1,116 @@
+package registry_test
+
+import (
+	"github.com/kercylan98/minotaur/server"
+	"github.com/kercylan98/minotaur/server/registry"
+	. "github.com/smartystreets/goconvey/convey"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+type eventRecorder struct {
+	mutex  sync.Mutex
+	events []string
+}
+
+func (slf *eventRecorder) handle(event server.RegistryEvent) {
+	slf.mutex.Lock()
+	defer slf.mutex.Unlock()
+	var prefix = "+"
+	if event.Type == server.RegistryEventRemove {
+		prefix = "-"
+	}
+	slf.events = append(slf.events, prefix+event.Instance.Address)
+}
+
+func (slf *eventRecorder) get() []string {
+	slf.mutex.Lock()
+	defer slf.mutex.Unlock()
+	return append([]string(nil), slf.events...)
+}
+
+func TestMemory(t *testing.T) {
+	Convey("TestMemory", t, func() {
+		r := registry.NewMemory()
+		So(r.Register(server.ServiceInstance{ID: 1, Type: "lobby", Address: "a"}, 50*time.Millisecond), ShouldBeNil)
+
+		var recorder = new(eventRecorder)
+		cancel, err := r.Watch("lobby", recorder.handle)
+		So(err, ShouldBeNil)
+		defer cancel()
+
+		So(r.Register(server.ServiceInstance{ID: 1, Type: "lobby", Address: "a"}, 50*time.Millisecond), ShouldBeNil)
+		So(r.Register(server.ServiceInstance{ID: 2, Type: "battle", Address: "b"}, 0), ShouldBeNil)
+		So(r.Register(server.ServiceInstance{ID: 3, Type: "lobby", Address: "c"}, 0), ShouldBeNil)
+		instances, err := r.Instances("lobby")
+		So(err, ShouldBeNil)
+		So(instances, ShouldHaveLength, 2)
+
+		So(r.Deregister(server.ServiceInstance{ID: 3}), ShouldBeNil)
+		time.Sleep(100 * time.Millisecond)
+		So(recorder.get(), ShouldResemble, []string{"+a", "+c", "-c", "-a"})
+	})
+}
+
+func TestFile(t *testing.T) {
+	Convey("TestFile", t, func() {
+		path := filepath.Join(t.TempDir(), "servers.json")
+		So(os.WriteFile(path, []byte(`[{"id":1,"type":"lobby","address":"a"}]`), 0644), ShouldBeNil)
+
+		r := registry.NewFile(path, 10*time.Millisecond)
+		defer r.Release()
+		var recorder = new(eventRecorder)
+		_, err := r.Watch("", recorder.handle)
+		So(err, ShouldBeNil)
+
+		time.Sleep(20 * time.Millisecond)
+		So(os.WriteFile(path, []byte(`[{"id":2,"type":"lobby","address":"b"}]`), 0644), ShouldBeNil)
+		So(os.Chtimes(path, time.Now(), time.Now().Add(time.Second)), ShouldBeNil)
+		time.Sleep(100 * time.Millisecond)
+		So(recorder.get(), ShouldResemble, []string{"+a", "+b", "-a"})
+
+		_, err = registry.NewFile(filepath.Join(t.TempDir(), "none.json"), 0).Instances("")
+		So(err, ShouldNotBeNil)
+	})
+}
+
+func TestWithRegistry(t *testing.T) {
+	Convey("TestWithRegistry", t, func() {
+		r := registry.NewMemory()
+		srv := server.New(server.NetworkNone,
+			server.WithRegistry(r, server.ServiceInstance{ID: 1, Type: "lobby", Address: "ws://127.0.0.1:8888"}, 600*time.Millisecond),
+		)
+		var started = make(chan struct{})
+		srv.RegStartFinishEvent(func(srv *server.Server) {
+			close(started)
+		})
+		var stopped = make(chan struct{})
+		go func() {
+			_ = srv.RunNone()
+			close(stopped)
+		}()
+		<-started
+
+		time.Sleep(time.Second)
+		instances, _ := srv.Registry().Instances("lobby")
+		So(instances, ShouldHaveLength, 1)
+		So(instances[0].Address, ShouldEqual, "ws://127.0.0.1:8888")
+
+		srv.Shutdown()
+		<-stopped
+		instances, _ = r.Instances("lobby")
+		So(instances, ShouldHaveLength, 0)
+	})
+}
+
+func TestWithRegistry_Address(t *testing.T) {
+	Convey("TestWithRegistry_Address", t, func() {
+		srv := server.New(server.NetworkNone,
+			server.WithRegistry(registry.NewMemory(), server.ServiceInstance{ID: 1, Type: "lobby"}, 0),
+		)
+		So(srv.RunNone(), ShouldEqual, server.ErrRegistryAddress)
+	})
+}
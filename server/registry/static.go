@@ -0,0 +1,122 @@
+package registry
+
+import (
+	"encoding/json"
+	"github.com/kercylan98/minotaur/server"
+	"github.com/kercylan98/minotaur/utils/log"
+	"os"
+	"sync"
+	"time"
+)
+
+// NewStatic 创建一个基于固定服务列表的服务注册中心
+func NewStatic(instances ...server.ServiceInstance) *Static {
+	s := &Static{store: newStore()}
+	s.store.replace(instances)
+	return s
+}
+
+// NewFile 创建一个基于文件的服务注册中心
+//   - 文件内容为 ServiceInstance 的 json 数组
+//   - reloadInterval > 0 时将以该间隔检查文件的修改时间，文件发生变更时将重新加载并通知监听者
+func NewFile(path string, reloadInterval time.Duration) *Static {
+	return &Static{
+		store:          newStore(),
+		path:           path,
+		reloadInterval: reloadInterval,
+		stop:           make(chan struct{}),
+	}
+}
+
+// Static 基于固定服务列表或文件的服务注册中心
+//   - 服务列表由外部维护，Register 与 Deregister 不会产生任何影响，服务实例也不会过期
+type Static struct {
+	store          *store
+	path           string
+	reloadInterval time.Duration
+	modTime        time.Time
+	once           sync.Once
+	loadErr        error
+	stop           chan struct{}
+	stopOnce       sync.Once
+}
+
+func (slf *Static) Register(instance server.ServiceInstance, ttl time.Duration) error {
+	return nil
+}
+
+func (slf *Static) Deregister(instance server.ServiceInstance) error {
+	return nil
+}
+
+func (slf *Static) Instances(serverType string) ([]server.ServiceInstance, error) {
+	if err := slf.init(); err != nil {
+		return nil, err
+	}
+	return slf.store.list(serverType), nil
+}
+
+func (slf *Static) Watch(serverType string, handle func(event server.RegistryEvent)) (cancel func(), err error) {
+	if err = slf.init(); err != nil {
+		return nil, err
+	}
+	return slf.store.watch(serverType, handle), nil
+}
+
+func (slf *Static) Release() {
+	if slf.stop != nil {
+		slf.stopOnce.Do(func() {
+			close(slf.stop)
+		})
+	}
+	slf.store.release()
+}
+
+// init 首次使用时加载文件并开始检查文件变更
+func (slf *Static) init() error {
+	if slf.path == "" {
+		return nil
+	}
+	slf.once.Do(func() {
+		if slf.loadErr = slf.load(); slf.loadErr != nil || slf.reloadInterval <= 0 {
+			return
+		}
+		go func() {
+			var ticker = time.NewTicker(slf.reloadInterval)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-slf.stop:
+					return
+				case <-ticker.C:
+					if err := slf.load(); err != nil {
+						log.Error("Registry", log.String("File", slf.path), log.Err(err))
+					}
+				}
+			}
+		}()
+	})
+	return slf.loadErr
+}
+
+// load 文件发生变更时重新加载服务列表
+func (slf *Static) load() error {
+	info, err := os.Stat(slf.path)
+	if err != nil {
+		return err
+	}
+	if info.ModTime().Equal(slf.modTime) {
+		return nil
+	}
+	data, err := os.ReadFile(slf.path)
+	if err != nil {
+		return err
+	}
+	var instances []server.ServiceInstance
+	if err = json.Unmarshal(data, &instances); err != nil {
+		return err
+	}
+	slf.modTime = info.ModTime()
+	slf.store.replace(instances)
+	return nil
+}
@@ -0,0 +1,195 @@
+package registry
+
+import (
+	"github.com/kercylan98/minotaur/server"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// newStore 创建服务实例存储
+func newStore() *store {
+	return &store{
+		instances: map[int64]*storeEntry{},
+		watchers:  map[uint64]*storeWatcher{},
+	}
+}
+
+// store 服务实例存储，负责服务实例的过期及监听者的通知
+type store struct {
+	mutex     sync.Mutex
+	instances map[int64]*storeEntry
+	watchers  map[uint64]*storeWatcher
+	guid      uint64
+}
+
+type storeEntry struct {
+	instance server.ServiceInstance
+	timer    *time.Timer
+}
+
+type storeWatcher struct {
+	serverType string
+	handle     func(event server.RegistryEvent)
+	mutex      sync.Mutex // 保证同一监听者的事件顺序
+}
+
+func (slf *storeWatcher) notify(event server.RegistryEvent) {
+	if slf.serverType != "" && slf.serverType != event.Instance.Type {
+		return
+	}
+	slf.mutex.Lock()
+	defer slf.mutex.Unlock()
+	slf.handle(event)
+}
+
+// put 添加或更新服务实例，服务实例将在 ttl 后过期，ttl <= 0 时表示永不过期
+//   - 仅在服务实例新增或发生变更时通知监听者
+func (slf *store) put(instance server.ServiceInstance, ttl time.Duration) {
+	slf.mutex.Lock()
+	entry, exist := slf.instances[instance.ID]
+	var changed = !exist || !reflect.DeepEqual(entry.instance, instance)
+	var removed server.ServiceInstance
+	if exist {
+		if entry.timer != nil {
+			entry.timer.Stop()
+		}
+		if entry.instance.Type != instance.Type {
+			removed = entry.instance
+		}
+	} else {
+		entry = new(storeEntry)
+		slf.instances[instance.ID] = entry
+	}
+	entry.instance = instance
+	entry.timer = nil
+	if ttl > 0 {
+		var e = entry
+		entry.timer = time.AfterFunc(ttl, func() {
+			slf.expire(e)
+		})
+	}
+	var watchers = slf.watcherSlice()
+	slf.mutex.Unlock()
+
+	if !changed {
+		return
+	}
+	for _, watcher := range watchers {
+		if removed.ID != 0 {
+			watcher.notify(server.RegistryEvent{Type: server.RegistryEventRemove, Instance: removed})
+		}
+		watcher.notify(server.RegistryEvent{Type: server.RegistryEventAdd, Instance: instance})
+	}
+}
+
+// remove 移除服务实例
+func (slf *store) remove(id int64) {
+	slf.mutex.Lock()
+	entry, exist := slf.instances[id]
+	if exist {
+		delete(slf.instances, id)
+		if entry.timer != nil {
+			entry.timer.Stop()
+		}
+	}
+	var watchers = slf.watcherSlice()
+	slf.mutex.Unlock()
+	if exist {
+		slf.notify(watchers, server.RegistryEvent{Type: server.RegistryEventRemove, Instance: entry.instance})
+	}
+}
+
+// expire 服务实例过期
+func (slf *store) expire(entry *storeEntry) {
+	slf.mutex.Lock()
+	if slf.instances[entry.instance.ID] != entry {
+		slf.mutex.Unlock()
+		return
+	}
+	delete(slf.instances, entry.instance.ID)
+	var watchers = slf.watcherSlice()
+	slf.mutex.Unlock()
+	slf.notify(watchers, server.RegistryEvent{Type: server.RegistryEventRemove, Instance: entry.instance})
+}
+
+// replace 使用新的服务实例列表替换当前所有服务实例
+func (slf *store) replace(instances []server.ServiceInstance) {
+	var exists = make(map[int64]struct{}, len(instances))
+	for _, instance := range instances {
+		exists[instance.ID] = struct{}{}
+		slf.put(instance, 0)
+	}
+	for _, instance := range slf.list("") {
+		if _, exist := exists[instance.ID]; !exist {
+			slf.remove(instance.ID)
+		}
+	}
+}
+
+// list 获取特定类型的服务实例，serverType 为空时将获取所有服务实例
+func (slf *store) list(serverType string) []server.ServiceInstance {
+	slf.mutex.Lock()
+	defer slf.mutex.Unlock()
+	var instances = make([]server.ServiceInstance, 0, len(slf.instances))
+	for _, entry := range slf.instances {
+		if serverType == "" || entry.instance.Type == serverType {
+			instances = append(instances, entry.instance)
+		}
+	}
+	return instances
+}
+
+// watch 监听特定类型的服务实例变化，已存在的服务实例将立即通知
+func (slf *store) watch(serverType string, handle func(event server.RegistryEvent)) (cancel func()) {
+	var watcher = &storeWatcher{serverType: serverType, handle: handle}
+	watcher.mutex.Lock()
+	slf.mutex.Lock()
+	slf.guid++
+	var guid = slf.guid
+	slf.watchers[guid] = watcher
+	var instances = make([]server.ServiceInstance, 0, len(slf.instances))
+	for _, entry := range slf.instances {
+		instances = append(instances, entry.instance)
+	}
+	slf.mutex.Unlock()
+
+	// 在持有监听者锁的情况下通知已存在的服务实例，确保其先于后续的变更事件
+	for _, instance := range instances {
+		if serverType == "" || serverType == instance.Type {
+			handle(server.RegistryEvent{Type: server.RegistryEventAdd, Instance: instance})
+		}
+	}
+	watcher.mutex.Unlock()
+	return func() {
+		slf.mutex.Lock()
+		defer slf.mutex.Unlock()
+		delete(slf.watchers, guid)
+	}
+}
+
+// release 停止所有过期定时器并移除所有监听者
+func (slf *store) release() {
+	slf.mutex.Lock()
+	defer slf.mutex.Unlock()
+	for _, entry := range slf.instances {
+		if entry.timer != nil {
+			entry.timer.Stop()
+		}
+	}
+	slf.watchers = map[uint64]*storeWatcher{}
+}
+
+func (slf *store) watcherSlice() []*storeWatcher {
+	var watchers = make([]*storeWatcher, 0, len(slf.watchers))
+	for _, watcher := range slf.watchers {
+		watchers = append(watchers, watcher)
+	}
+	return watchers
+}
+
+func (slf *store) notify(watchers []*storeWatcher, event server.RegistryEvent) {
+	for _, watcher := range watchers {
+		watcher.notify(event)
+	}
+}
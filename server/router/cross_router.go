@@ -0,0 +1,131 @@
+package router
+
+import (
+	"fmt"
+	"github.com/kercylan98/minotaur/server"
+	"github.com/kercylan98/minotaur/server/cross"
+	"github.com/kercylan98/minotaur/utils/log"
+	"time"
+)
+
+// NewCrossRouter 创建一个基于主题进行分发的跨服消息路由器
+//   - crossName：发送跨服消息时所使用的跨服中间件名称
+//   - 默认采用 cross.BinaryEnvelopeCodec 作为信封编解码器，JSONCodec 作为消息体编解码器
+//   - 通过 CrossRouter.Handle 接入服务器：srv.RegReceiveCrossPacketEvent(crossRouter.Handle)
+func NewCrossRouter(crossName string, options ...CrossRouterOption) *CrossRouter {
+	router := &CrossRouter{
+		crossName: crossName,
+		router:    NewLevel1Router[string, crossRouterHandle](),
+		envelope:  cross.BinaryEnvelopeCodec,
+		codec:     JSONCodec,
+	}
+	for _, option := range options {
+		option(router)
+	}
+	return router
+}
+
+type crossRouterHandle func(srv *server.Server, envelope *cross.Envelope) error
+
+// CrossRouter 跨服消息路由器
+//   - 跨服数据包将被解码为 cross.Envelope，根据主题匹配已注册的处理函数，并将消息体解码为处理函数所需的类型
+//   - 超过存活时间的跨服消息将被丢弃
+type CrossRouter struct {
+	crossName   string
+	router      *Level1Router[string, crossRouterHandle]
+	envelope    cross.EnvelopeCodec
+	codec       PayloadCodec
+	errorHandle func(srv *server.Server, envelope *cross.Envelope, err error)
+}
+
+// RegisterCross 注册特定主题的跨服消息处理函数，消息体将被解码为 T 类型后传入处理函数
+//   - 同一个主题仅允许注册一次，重复注册将会发生 panic
+//   - 在处理函数中可以通过 CrossRouter.Reply 对该消息进行响应
+func RegisterCross[T any](router *CrossRouter, topic string, handle func(srv *server.Server, envelope *cross.Envelope, msg *T)) {
+	router.router.Route(topic, func(srv *server.Server, envelope *cross.Envelope) error {
+		var msg = new(T)
+		if err := router.codec.Unmarshal(envelope.Payload, msg); err != nil {
+			return err
+		}
+		handle(srv, envelope, msg)
+		return nil
+	})
+}
+
+// Handle 处理跨服数据包，可直接作为 server.ReceiveCrossPacketEventHandle 使用
+func (slf *CrossRouter) Handle(srv *server.Server, senderServerId int64, packet []byte) {
+	var envelope = new(cross.Envelope)
+	if err := slf.envelope.Unmarshal(packet, envelope); err != nil {
+		envelope.From = senderServerId
+		slf.onError(srv, envelope, err)
+		return
+	}
+	if envelope.IsExpired(time.Now()) {
+		slf.onError(srv, envelope, ErrCrossMessageExpired)
+		return
+	}
+	handle := slf.router.Match(envelope.Topic)
+	if handle == nil {
+		slf.onError(srv, envelope, ErrUnknownTopic)
+		return
+	}
+	if err := handle(srv, envelope); err != nil {
+		slf.onError(srv, envelope, fmt.Errorf("router: decode cross message %s failed: %w", envelope.Topic, err))
+	}
+}
+
+// Push 向特定服务器推送跨服消息
+func (slf *CrossRouter) Push(srv *server.Server, serverId int64, topic string, msg any, options ...cross.EnvelopeOption) error {
+	data, err := slf.Pack(srv, topic, msg, options...)
+	if err != nil {
+		return err
+	}
+	server.PushCrossMessage(srv, slf.crossName, serverId, data)
+	return nil
+}
+
+// Broadcast 向所有服务器广播跨服消息，包括本服
+//   - 跨服中间件需要实现 server.CrossAdvanced 接口
+func (slf *CrossRouter) Broadcast(srv *server.Server, topic string, msg any, options ...cross.EnvelopeOption) error {
+	data, err := slf.Pack(srv, topic, msg, options...)
+	if err != nil {
+		return err
+	}
+	return server.PushCrossBroadcast(srv, slf.crossName, data)
+}
+
+// PushGroup 向特定分组中的所有服务器推送跨服消息
+//   - 跨服中间件需要实现 server.CrossAdvanced 接口
+func (slf *CrossRouter) PushGroup(srv *server.Server, group string, topic string, msg any, options ...cross.EnvelopeOption) error {
+	data, err := slf.Pack(srv, topic, msg, options...)
+	if err != nil {
+		return err
+	}
+	return server.PushCrossGroup(srv, slf.crossName, group, data)
+}
+
+// Reply 对跨服消息进行响应，响应消息将发送至该消息的 ReplyTo 主题，并携带相同的追踪 ID
+func (slf *CrossRouter) Reply(srv *server.Server, envelope *cross.Envelope, msg any, options ...cross.EnvelopeOption) error {
+	if envelope.ReplyTo == "" {
+		return ErrNoReplyTopic
+	}
+	options = append([]cross.EnvelopeOption{cross.WithEnvelopeTraceID(envelope.TraceID)}, options...)
+	return slf.Push(srv, envelope.From, envelope.ReplyTo, msg, options...)
+}
+
+// Pack 将消息打包为跨服数据包
+func (slf *CrossRouter) Pack(srv *server.Server, topic string, msg any, options ...cross.EnvelopeOption) ([]byte, error) {
+	payload, err := slf.codec.Marshal(msg)
+	if err != nil {
+		return nil, err
+	}
+	return slf.envelope.Marshal(cross.NewEnvelope(srv.GetID(), topic, payload, options...))
+}
+
+func (slf *CrossRouter) onError(srv *server.Server, envelope *cross.Envelope, err error) {
+	if slf.errorHandle != nil {
+		slf.errorHandle(srv, envelope, err)
+		return
+	}
+	log.Warn("CrossRouter", log.Int64("from", envelope.From), log.String("topic", envelope.Topic), log.String("trace", envelope.TraceID), log.Err(err))
+}
@@ -0,0 +1,36 @@
+package router
+
+import (
+	"github.com/kercylan98/minotaur/server"
+	"github.com/kercylan98/minotaur/server/cross"
+)
+
+// CrossRouterOption 跨服消息路由器选项
+type CrossRouterOption func(router *CrossRouter)
+
+// WithCrossEnvelopeCodec 通过特定的信封编解码器创建跨服消息路由器
+//   - 内置 cross.BinaryEnvelopeCodec、cross.JSONEnvelopeCodec
+//   - 默认为 cross.BinaryEnvelopeCodec，所有服务器应当采用相同的信封编解码器
+func WithCrossEnvelopeCodec(codec cross.EnvelopeCodec) CrossRouterOption {
+	return func(router *CrossRouter) {
+		router.envelope = codec
+	}
+}
+
+// WithCrossPayloadCodec 通过特定的消息体编解码器创建跨服消息路由器
+//   - 内置 JSONCodec、ProtobufCodec、MsgpackCodec
+//   - 默认为 JSONCodec
+func WithCrossPayloadCodec(codec PayloadCodec) CrossRouterOption {
+	return func(router *CrossRouter) {
+		router.codec = codec
+	}
+}
+
+// WithCrossErrorHandle 设置跨服消息分发过程中发生错误时的处理函数
+//   - 未注册的主题将会传入 ErrUnknownTopic，过期的消息将会传入 ErrCrossMessageExpired，信封或消息体解析失败时将会传入对应的错误
+//   - 默认将会输出 WARN 日志
+func WithCrossErrorHandle(handle func(srv *server.Server, envelope *cross.Envelope, err error)) CrossRouterOption {
+	return func(router *CrossRouter) {
+		router.errorHandle = handle
+	}
+}
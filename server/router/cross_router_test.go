@@ -0,0 +1,79 @@
+package router_test
+
+import (
+	"github.com/kercylan98/minotaur/server"
+	"github.com/kercylan98/minotaur/server/cross"
+	"github.com/kercylan98/minotaur/server/router"
+	. "github.com/smartystreets/goconvey/convey"
+	"testing"
+	"time"
+)
+
+type matchRequest struct {
+	Player string `json:"player"`
+}
+
+type matchResponse struct {
+	Room int `json:"room"`
+}
+
+func TestCrossRouter(t *testing.T) {
+	Convey("TestCrossRouter", t, func() {
+		var network = cross.NewLoopbackNetwork()
+		var responses = make(chan *cross.Envelope, 1)
+		var errs = make(chan error, 1)
+		var run = func(id int64) (*server.Server, *router.CrossRouter) {
+			r := router.NewCrossRouter("cross", router.WithCrossErrorHandle(func(srv *server.Server, envelope *cross.Envelope, err error) {
+				errs <- err
+			}))
+			srv := server.New(server.NetworkNone, server.WithCross("cross", id, network.NewCross()))
+			srv.RegReceiveCrossPacketEvent(r.Handle)
+			var started = make(chan struct{})
+			srv.RegStartFinishEvent(func(srv *server.Server) {
+				close(started)
+			})
+			go func() {
+				_ = srv.RunNone()
+			}()
+			<-started
+			return srv, r
+		}
+		a, ra := run(1)
+		b, rb := run(2)
+		defer a.Shutdown()
+		defer b.Shutdown()
+
+		var requests = make(chan string, 1)
+		router.RegisterCross(ra, "match", func(srv *server.Server, envelope *cross.Envelope, msg *matchRequest) {
+			requests <- msg.Player
+			if err := ra.Reply(srv, envelope, &matchResponse{Room: 7}); err != nil {
+				errs <- err
+			}
+		})
+		var rooms = make(chan int, 1)
+		router.RegisterCross(rb, "match.result", func(srv *server.Server, envelope *cross.Envelope, msg *matchResponse) {
+			rooms <- msg.Room
+			responses <- envelope
+		})
+
+		So(rb.Push(b, 1, "match", &matchRequest{Player: "minotaur"}, cross.WithEnvelopeTraceID("trace"), cross.WithEnvelopeReplyTo("match.result")), ShouldBeNil)
+		select {
+		case envelope := <-responses:
+			So(<-requests, ShouldEqual, "minotaur")
+			So(<-rooms, ShouldEqual, 7)
+			So(envelope.TraceID, ShouldEqual, "trace")
+			So(envelope.From, ShouldEqual, 1)
+		case <-time.After(time.Second):
+			t.Fatal("reply timeout")
+		}
+
+		So(rb.Push(b, 1, "unknown", &matchRequest{}), ShouldBeNil)
+		So(<-errs, ShouldEqual, router.ErrUnknownTopic)
+
+		packet := cross.NewEnvelope(2, "match", nil, cross.WithEnvelopeTTL(time.Millisecond))
+		packet.Timestamp -= 1000
+		data, _ := cross.BinaryEnvelopeCodec.Marshal(packet)
+		ra.Handle(a, 2, data)
+		So(<-errs, ShouldEqual, router.ErrCrossMessageExpired)
+	})
+}
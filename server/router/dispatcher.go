@@ -0,0 +1,118 @@
+package router
+
+import (
+	"fmt"
+	"github.com/kercylan98/minotaur/server"
+	"github.com/kercylan98/minotaur/utils/concurrent"
+	"github.com/kercylan98/minotaur/utils/log"
+)
+
+// NewDispatcher 创建一个基于消息 ID 进行分发的消息分发器
+//   - 默认采用 NewBinaryHeaderCodec(4, 4, 1, nil) 作为消息头编解码器，JSONCodec 作为消息体编解码器
+//   - 通过 Dispatcher.Handle 接入服务器：srv.RegConnectionReceivePacketEvent(dispatcher.Handle)
+func NewDispatcher(options ...DispatcherOption) *Dispatcher {
+	dispatcher := &Dispatcher{
+		router:        NewLevel1Router[uint32, dispatcherHandle](),
+		header:        NewBinaryHeaderCodec(4, 4, 1, nil),
+		codec:         JSONCodec,
+		websocketType: server.WebsocketMessageTypeBinary,
+		handling:      concurrent.NewBalanceMap[string, dispatcherContext](),
+	}
+	for _, option := range options {
+		option(dispatcher)
+	}
+	return dispatcher
+}
+
+type dispatcherHandle func(conn *server.Conn, body []byte) error
+
+// dispatcherContext 连接正在处理的消息上下文
+type dispatcherContext struct {
+	header        MessageHeader
+	websocketType int
+}
+
+// Dispatcher 消息分发器
+//   - 从数据包中解析出消息头，根据消息 ID 匹配已注册的处理函数，并将消息体解码为处理函数所需的类型
+type Dispatcher struct {
+	router        *Level1Router[uint32, dispatcherHandle]
+	header        HeaderCodec
+	codec         PayloadCodec
+	websocketType int
+	errorHandle   func(conn *server.Conn, header MessageHeader, err error)
+	handling      *concurrent.BalanceMap[string, dispatcherContext]
+}
+
+// Register 注册特定消息 ID 的处理函数，消息体将被解码为 T 类型后传入处理函数
+//   - 同一个消息 ID 仅允许注册一次，重复注册将会发生 panic
+//   - 在处理函数中可以通过 Dispatcher.Reply 对该消息进行响应
+func Register[T any](dispatcher *Dispatcher, id uint32, handle func(conn *server.Conn, req *T)) {
+	dispatcher.router.Route(id, func(conn *server.Conn, body []byte) error {
+		var req = new(T)
+		if err := dispatcher.codec.Unmarshal(body, req); err != nil {
+			return err
+		}
+		handle(conn, req)
+		return nil
+	})
+}
+
+// Handle 处理数据包，可直接作为 server.ConnectionReceivePacketEventHandle 使用
+func (slf *Dispatcher) Handle(srv *server.Server, conn *server.Conn, packet server.Packet) {
+	header, body, err := slf.header.Unpack(packet.Data)
+	if err != nil {
+		slf.onError(conn, header, err)
+		return
+	}
+	handle := slf.router.Match(header.ID)
+	if handle == nil {
+		slf.onError(conn, header, ErrUnknownMessage)
+		return
+	}
+	slf.handling.Set(conn.GetID(), dispatcherContext{header: header, websocketType: packet.WebsocketType})
+	defer slf.handling.Delete(conn.GetID())
+	if err = handle(conn, body); err != nil {
+		slf.onError(conn, header, fmt.Errorf("router: decode message %d failed: %w", header.ID, err))
+	}
+}
+
+// Reply 对连接正在处理的消息进行响应，响应消息将携带与请求消息相同的序列号
+//   - 仅允许在处理函数中调用
+func (slf *Dispatcher) Reply(conn *server.Conn, id uint32, resp any) error {
+	ctx, exist := slf.handling.GetExist(conn.GetID())
+	if !exist {
+		return ErrNoReplyContext
+	}
+	return slf.write(conn, MessageHeader{ID: id, Seq: ctx.header.Seq, Flags: ctx.header.Flags}, ctx.websocketType, resp)
+}
+
+// Push 向连接推送消息，推送消息的序列号为 0
+func (slf *Dispatcher) Push(conn *server.Conn, id uint32, msg any) error {
+	return slf.write(conn, MessageHeader{ID: id}, slf.websocketType, msg)
+}
+
+// Pack 将消息打包为数据包
+func (slf *Dispatcher) Pack(header MessageHeader, msg any) ([]byte, error) {
+	body, err := slf.codec.Marshal(msg)
+	if err != nil {
+		return nil, err
+	}
+	return slf.header.Pack(header, body), nil
+}
+
+func (slf *Dispatcher) write(conn *server.Conn, header MessageHeader, websocketType int, msg any) error {
+	data, err := slf.Pack(header, msg)
+	if err != nil {
+		return err
+	}
+	conn.Write(server.NewWSPacket(websocketType, data))
+	return nil
+}
+
+func (slf *Dispatcher) onError(conn *server.Conn, header MessageHeader, err error) {
+	if slf.errorHandle != nil {
+		slf.errorHandle(conn, header, err)
+		return
+	}
+	log.Warn("Dispatcher", log.String("conn", conn.GetID()), log.Uint32("id", header.ID), log.Uint32("seq", header.Seq), log.Err(err))
+}
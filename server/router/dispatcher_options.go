@@ -0,0 +1,41 @@
+package router
+
+import "github.com/kercylan98/minotaur/server"
+
+// DispatcherOption 消息分发器选项
+type DispatcherOption func(dispatcher *Dispatcher)
+
+// WithHeaderCodec 通过特定的消息头编解码器创建消息分发器
+//   - 默认为 NewBinaryHeaderCodec(4, 4, 1, nil)
+func WithHeaderCodec(codec HeaderCodec) DispatcherOption {
+	return func(dispatcher *Dispatcher) {
+		dispatcher.header = codec
+	}
+}
+
+// WithPayloadCodec 通过特定的消息体编解码器创建消息分发器
+//   - 内置 JSONCodec、ProtobufCodec、MsgpackCodec
+//   - 默认为 JSONCodec
+func WithPayloadCodec(codec PayloadCodec) DispatcherOption {
+	return func(dispatcher *Dispatcher) {
+		dispatcher.codec = codec
+	}
+}
+
+// WithPushWebsocketType 设置通过 Dispatcher.Push 推送消息时采用的 websocket 消息类型
+//   - 默认为 server.WebsocketMessageTypeBinary
+//   - 通过 Dispatcher.Reply 响应消息时将采用与请求消息相同的类型
+func WithPushWebsocketType(websocketType int) DispatcherOption {
+	return func(dispatcher *Dispatcher) {
+		dispatcher.websocketType = websocketType
+	}
+}
+
+// WithErrorHandle 设置消息分发过程中发生错误时的处理函数
+//   - 未注册的消息 ID 将会传入 ErrUnknownMessage，消息头或消息体解析失败时将会传入对应的错误
+//   - 默认将会输出 WARN 日志
+func WithErrorHandle(handle func(conn *server.Conn, header MessageHeader, err error)) DispatcherOption {
+	return func(dispatcher *Dispatcher) {
+		dispatcher.errorHandle = handle
+	}
+}
@@ -0,0 +1,59 @@
+package router_test
+
+import (
+	"errors"
+	"github.com/kercylan98/minotaur/server"
+	"github.com/kercylan98/minotaur/server/router"
+	. "github.com/smartystreets/goconvey/convey"
+	"testing"
+)
+
+type loginRequest struct {
+	Account string `json:"account"`
+}
+
+type loginResponse struct {
+	Token string `json:"token"`
+}
+
+func TestDispatcher_Handle(t *testing.T) {
+	Convey("TestDispatcher_Handle", t, func() {
+		var errs []error
+		var written []server.Packet
+		dispatcher := router.NewDispatcher(router.WithErrorHandle(func(conn *server.Conn, header router.MessageHeader, err error) {
+			errs = append(errs, err)
+		}))
+		router.Register(dispatcher, 1, func(conn *server.Conn, req *loginRequest) {
+			So(req.Account, ShouldEqual, "minotaur")
+			So(dispatcher.Reply(conn, 2, &loginResponse{Token: "token"}), ShouldBeNil)
+		})
+
+		srv := server.New(server.NetworkNone)
+		srv.RegConnectionWritePacketBeforeEvent(func(srv *server.Server, conn *server.Conn, packet server.Packet) server.Packet {
+			written = append(written, packet)
+			return packet
+		})
+		conn := server.NewEmptyConn(srv)
+		defer conn.Close()
+
+		data, err := dispatcher.Pack(router.MessageHeader{ID: 1, Seq: 7}, &loginRequest{Account: "minotaur"})
+		So(err, ShouldBeNil)
+		dispatcher.Handle(srv, conn, server.NewWSPacket(server.WebsocketMessageTypeText, data))
+		So(errs, ShouldBeEmpty)
+		So(written, ShouldHaveLength, 1)
+		So(written[0].WebsocketType, ShouldEqual, server.WebsocketMessageTypeText)
+
+		header, body, err := router.NewBinaryHeaderCodec(4, 4, 1, nil).Unpack(written[0].Data)
+		So(err, ShouldBeNil)
+		So(header, ShouldResemble, router.MessageHeader{ID: 2, Seq: 7})
+		So(string(body), ShouldEqual, `{"token":"token"}`)
+
+		data, _ = dispatcher.Pack(router.MessageHeader{ID: 3}, &loginRequest{})
+		dispatcher.Handle(srv, conn, server.NewPacket(data))
+		dispatcher.Handle(srv, conn, server.NewPacket([]byte{0, 0, 0, 1, 0, 0, 0, 0, 0, '{'}))
+		So(errs, ShouldHaveLength, 2)
+		So(errs[0], ShouldEqual, router.ErrUnknownMessage)
+		So(errors.Is(errs[1], router.ErrUnknownMessage), ShouldBeFalse)
+		So(dispatcher.Reply(conn, 2, nil), ShouldEqual, router.ErrNoReplyContext)
+	})
+}
@@ -0,0 +1,22 @@
+package router
+
+import "errors"
+
+var (
+	// ErrHeaderFieldSize 消息头字段长度不合法
+	ErrHeaderFieldSize = errors.New("router: header field size only supports 0, 1, 2 or 4")
+	// ErrHeaderTooShort 数据包长度不足以解析出消息头
+	ErrHeaderTooShort = errors.New("router: packet is too short to unpack header")
+	// ErrUnknownMessage 未注册的消息 ID
+	ErrUnknownMessage = errors.New("router: unknown message id")
+	// ErrNotProtoMessage 消息类型未实现 proto.Message 接口
+	ErrNotProtoMessage = errors.New("router: message does not implement proto.Message")
+	// ErrNoReplyContext 当前连接不存在正在处理的消息，无法进行响应
+	ErrNoReplyContext = errors.New("router: no message is being handled on the conn")
+	// ErrUnknownTopic 未注册的跨服消息主题
+	ErrUnknownTopic = errors.New("router: unknown cross message topic")
+	// ErrCrossMessageExpired 跨服消息已超过存活时间
+	ErrCrossMessageExpired = errors.New("router: cross message expired")
+	// ErrNoReplyTopic 跨服消息未指定响应主题，无法进行响应
+	ErrNoReplyTopic = errors.New("router: cross message has no reply topic")
+)
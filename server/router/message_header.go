@@ -0,0 +1,104 @@
+package router
+
+import (
+	"encoding/binary"
+)
+
+// MessageHeader 消息头
+type MessageHeader struct {
+	ID    uint32 // 消息 ID
+	Seq   uint32 // 序列号，响应消息将携带与请求消息相同的序列号
+	Flags uint32 // 标记位
+}
+
+// HeaderCodec 消息头编解码器
+type HeaderCodec interface {
+	// Unpack 从数据包中解析出消息头及消息体
+	Unpack(data []byte) (header MessageHeader, body []byte, err error)
+	// Pack 将消息头及消息体打包为数据包
+	Pack(header MessageHeader, body []byte) []byte
+}
+
+// NewBinaryHeaderCodec 创建一个二进制消息头编解码器
+//   - 消息头格式：[消息 ID][序列号][标记位][消息体]
+//   - idSize、seqSize、flagsSize 分别为各字段占用的字节数，支持 0、1、2、4，为 0 时表示不包含该字段
+//   - order：字节序，为 nil 时默认采用 binary.BigEndian
+func NewBinaryHeaderCodec(idSize, seqSize, flagsSize int, order binary.ByteOrder) *BinaryHeaderCodec {
+	for _, size := range []int{idSize, seqSize, flagsSize} {
+		switch size {
+		case 0, 1, 2, 4:
+		default:
+			panic(ErrHeaderFieldSize)
+		}
+	}
+	if order == nil {
+		order = binary.BigEndian
+	}
+	return &BinaryHeaderCodec{
+		idSize:    idSize,
+		seqSize:   seqSize,
+		flagsSize: flagsSize,
+		order:     order,
+	}
+}
+
+// BinaryHeaderCodec 二进制消息头编解码器
+type BinaryHeaderCodec struct {
+	idSize    int              // 消息 ID 字段长度
+	seqSize   int              // 序列号字段长度
+	flagsSize int              // 标记位字段长度
+	order     binary.ByteOrder // 字节序
+}
+
+// Size 获取消息头的长度
+func (slf *BinaryHeaderCodec) Size() int {
+	return slf.idSize + slf.seqSize + slf.flagsSize
+}
+
+// Unpack 从数据包中解析出消息头及消息体
+func (slf *BinaryHeaderCodec) Unpack(data []byte) (header MessageHeader, body []byte, err error) {
+	if len(data) < slf.Size() {
+		return header, nil, ErrHeaderTooShort
+	}
+	var offset int
+	header.ID, offset = slf.read(data, offset, slf.idSize)
+	header.Seq, offset = slf.read(data, offset, slf.seqSize)
+	header.Flags, offset = slf.read(data, offset, slf.flagsSize)
+	return header, data[offset:], nil
+}
+
+// Pack 将消息头及消息体打包为数据包
+func (slf *BinaryHeaderCodec) Pack(header MessageHeader, body []byte) []byte {
+	var data = make([]byte, slf.Size()+len(body))
+	var offset int
+	offset = slf.write(data, offset, slf.idSize, header.ID)
+	offset = slf.write(data, offset, slf.seqSize, header.Seq)
+	offset = slf.write(data, offset, slf.flagsSize, header.Flags)
+	copy(data[offset:], body)
+	return data
+}
+
+func (slf *BinaryHeaderCodec) read(data []byte, offset, size int) (uint32, int) {
+	switch size {
+	case 1:
+		return uint32(data[offset]), offset + size
+	case 2:
+		return uint32(slf.order.Uint16(data[offset:])), offset + size
+	case 4:
+		return slf.order.Uint32(data[offset:]), offset + size
+	default:
+		return 0, offset
+	}
+}
+
+func (slf *BinaryHeaderCodec) write(data []byte, offset, size int, value uint32) int {
+	switch size {
+	case 1:
+		data[offset] = byte(value)
+	case 2:
+		slf.order.PutUint16(data[offset:], uint16(value))
+	case 4:
+		slf.order.PutUint32(data[offset:], value)
+	}
+	return offset + size
+}
@@ -0,0 +1,64 @@
+package router
+
+import (
+	jsonIter "github.com/json-iterator/go"
+	"github.com/vmihailenco/msgpack/v5"
+	"google.golang.org/protobuf/proto"
+)
+
+// PayloadCodec 消息体编解码器
+type PayloadCodec interface {
+	// Marshal 将消息编码为消息体
+	Marshal(v any) ([]byte, error)
+	// Unmarshal 将消息体解码至 v
+	Unmarshal(data []byte, v any) error
+}
+
+var (
+	// JSONCodec 基于 JSON 的消息体编解码器
+	JSONCodec PayloadCodec = jsonCodec{}
+	// ProtobufCodec 基于 protobuf 的消息体编解码器，消息类型需实现 proto.Message 接口
+	ProtobufCodec PayloadCodec = protobufCodec{}
+	// MsgpackCodec 基于 msgpack 的消息体编解码器
+	MsgpackCodec PayloadCodec = msgpackCodec{}
+)
+
+var json = jsonIter.ConfigCompatibleWithStandardLibrary
+
+type jsonCodec struct{}
+
+func (jsonCodec) Marshal(v any) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (jsonCodec) Unmarshal(data []byte, v any) error {
+	return json.Unmarshal(data, v)
+}
+
+type protobufCodec struct{}
+
+func (protobufCodec) Marshal(v any) ([]byte, error) {
+	m, ok := v.(proto.Message)
+	if !ok {
+		return nil, ErrNotProtoMessage
+	}
+	return proto.Marshal(m)
+}
+
+func (protobufCodec) Unmarshal(data []byte, v any) error {
+	m, ok := v.(proto.Message)
+	if !ok {
+		return ErrNotProtoMessage
+	}
+	return proto.Unmarshal(data, m)
+}
+
+type msgpackCodec struct{}
+
+func (msgpackCodec) Marshal(v any) ([]byte, error) {
+	return msgpack.Marshal(v)
+}
+
+func (msgpackCodec) Unmarshal(data []byte, v any) error {
+	return msgpack.Unmarshal(data, v)
+}
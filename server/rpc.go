@@ -0,0 +1,150 @@
+package server
+
+import (
+	"context"
+	"encoding/binary"
+	"sync"
+)
+
+const (
+	// RPCFramePush 推送帧：不需要对端响应的数据包
+	RPCFramePush RPCFrameKind = iota
+	// RPCFrameRequest 请求帧：对端需要通过携带相同序列号的 RPCFrameResponse 进行响应
+	RPCFrameRequest
+	// RPCFrameResponse 响应帧：对 RPCFrameRequest 的响应
+	RPCFrameResponse
+)
+
+const rpcFrameHeaderSize = 5
+
+// RPCFrameKind 请求/响应模式下的数据帧类型
+type RPCFrameKind byte
+
+// PackRPCFrame 打包请求/响应模式下的数据帧
+//   - 数据帧格式：[帧类型 1 byte][序列号 4 byte BigEndian][数据]
+func PackRPCFrame(kind RPCFrameKind, seq uint32, data []byte) []byte {
+	var frame = make([]byte, rpcFrameHeaderSize+len(data))
+	frame[0] = byte(kind)
+	binary.BigEndian.PutUint32(frame[1:], seq)
+	copy(frame[rpcFrameHeaderSize:], data)
+	return frame
+}
+
+// UnpackRPCFrame 解包请求/响应模式下的数据帧
+func UnpackRPCFrame(frame []byte) (kind RPCFrameKind, seq uint32, data []byte, err error) {
+	if len(frame) < rpcFrameHeaderSize {
+		return 0, 0, nil, ErrRPCIllegalFrame
+	}
+	kind = RPCFrameKind(frame[0])
+	if kind > RPCFrameResponse {
+		return 0, 0, nil, ErrRPCIllegalFrame
+	}
+	return kind, binary.BigEndian.Uint32(frame[1:]), frame[rpcFrameHeaderSize:], nil
+}
+
+// NewRPCCaller 创建一个请求/响应调用器，用于通过序列号将请求与响应进行关联
+//   - maxInflight：同时等待响应的请求数量上限，达到上限后新的请求将阻塞至有空闲位置或 ctx 结束
+func NewRPCCaller(maxInflight int) *RPCCaller {
+	if maxInflight <= 0 {
+		maxInflight = DefaultRPCMaxInflight
+	}
+	return &RPCCaller{
+		calls: map[uint32]chan Packet{},
+		slots: make(chan struct{}, maxInflight),
+	}
+}
+
+// RPCCaller 请求/响应调用器
+type RPCCaller struct {
+	mutex sync.Mutex
+	seq   uint32                 // 当前序列号
+	calls map[uint32]chan Packet // 等待响应的请求
+	slots chan struct{}          // 请求数量限制
+	err   error                  // 关闭原因
+}
+
+// Call 发起请求并等待响应
+//   - send：用于通过特定序列号发送请求帧的函数
+//   - 当 ctx 结束时将返回 ctx.Err()，当调用器被关闭时将返回关闭时的错误
+func (slf *RPCCaller) Call(ctx context.Context, send func(seq uint32) error) (Packet, error) {
+	select {
+	case slf.slots <- struct{}{}:
+	case <-ctx.Done():
+		return Packet{}, ctx.Err()
+	}
+	defer func() { <-slf.slots }()
+
+	slf.mutex.Lock()
+	if slf.err != nil {
+		slf.mutex.Unlock()
+		return Packet{}, slf.err
+	}
+	slf.seq++
+	if slf.seq == 0 {
+		slf.seq++
+	}
+	seq := slf.seq
+	reply := make(chan Packet, 1)
+	slf.calls[seq] = reply
+	slf.mutex.Unlock()
+
+	if err := send(seq); err != nil {
+		slf.cancel(seq)
+		return Packet{}, err
+	}
+
+	select {
+	case packet, ok := <-reply:
+		if !ok {
+			slf.mutex.Lock()
+			defer slf.mutex.Unlock()
+			return Packet{}, slf.err
+		}
+		return packet, nil
+	case <-ctx.Done():
+		slf.cancel(seq)
+		return Packet{}, ctx.Err()
+	}
+}
+
+// Resolve 将响应交付给对应序列号的请求，当请求不存在（已超时或已取消）时返回 false
+func (slf *RPCCaller) Resolve(seq uint32, packet Packet) bool {
+	slf.mutex.Lock()
+	reply, exist := slf.calls[seq]
+	if exist {
+		delete(slf.calls, seq)
+	}
+	slf.mutex.Unlock()
+	if exist {
+		reply <- packet
+	}
+	return exist
+}
+
+// Inflight 获取正在等待响应的请求数量
+func (slf *RPCCaller) Inflight() int {
+	slf.mutex.Lock()
+	defer slf.mutex.Unlock()
+	return len(slf.calls)
+}
+
+// Close 关闭调用器，所有正在等待响应的请求将返回 err
+//   - 重复关闭将不会产生任何效果
+func (slf *RPCCaller) Close(err error) {
+	slf.mutex.Lock()
+	defer slf.mutex.Unlock()
+	if slf.err != nil {
+		return
+	}
+	slf.err = err
+	for seq, reply := range slf.calls {
+		close(reply)
+		delete(slf.calls, seq)
+	}
+}
+
+func (slf *RPCCaller) cancel(seq uint32) {
+	slf.mutex.Lock()
+	delete(slf.calls, seq)
+	slf.mutex.Unlock()
+}
@@ -0,0 +1,177 @@
+package server_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/kercylan98/minotaur/server"
+	"github.com/kercylan98/minotaur/server/client"
+	. "github.com/smartystreets/goconvey/convey"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRPCCaller(t *testing.T) {
+	Convey("TestRPCCaller", t, func() {
+		caller := server.NewRPCCaller(1)
+
+		packet, err := caller.Call(context.Background(), func(seq uint32) error {
+			frame := server.PackRPCFrame(server.RPCFrameRequest, seq, []byte("ping"))
+			kind, s, data, err := server.UnpackRPCFrame(frame)
+			So(err, ShouldBeNil)
+			So(kind, ShouldEqual, server.RPCFrameRequest)
+			So(string(data), ShouldEqual, "ping")
+			go caller.Resolve(s, server.NewPacketString("pong"))
+			return nil
+		})
+		So(err, ShouldBeNil)
+		So(packet.String(), ShouldEqual, "pong")
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		_, err = caller.Call(ctx, func(seq uint32) error { return nil })
+		So(errors.Is(err, context.DeadlineExceeded), ShouldBeTrue)
+		So(caller.Inflight(), ShouldEqual, 0)
+
+		go func() {
+			time.Sleep(10 * time.Millisecond)
+			caller.Close(server.ErrConnClosed)
+		}()
+		_, err = caller.Call(context.Background(), func(seq uint32) error { return nil })
+		So(err, ShouldEqual, server.ErrConnClosed)
+	})
+}
+
+func TestConn_Call(t *testing.T) {
+	Convey("TestConn_Call", t, func() {
+		var addr = freeAddr()
+		var pattern = "/rpc-" + strings.ReplaceAll(addr, ":", "-")
+		srv := server.New(server.NetworkWebsocket, server.WithRPC(0))
+		var opened = make(chan *server.Conn, 1)
+		var closed = make(chan struct{})
+		srv.RegConnectionOpenedEvent(func(srv *server.Server, conn *server.Conn) {
+			opened <- conn
+		})
+		srv.RegConnectionClosedEvent(func(srv *server.Server, conn *server.Conn, err any) {
+			close(closed)
+		})
+		srv.RegConnectionReceiveRequestEvent(func(srv *server.Server, conn *server.Conn, packet server.Packet, reply func(packet server.Packet)) {
+			if packet.String() != "silent" {
+				reply(server.NewWSPacketString(server.WebsocketMessageTypeText, "server:"+packet.String()))
+			}
+		})
+		stop := runServer(srv, addr+pattern)
+		defer stop()
+		waitListen(addr)
+
+		// 客户端对请求按照与请求顺序相反的顺序进行响应
+		cli := client.NewWebsocket("ws://"+addr+pattern, client.WithWebsocketRPC(0))
+		cli.RegConnectionReceiveRequestEvent(func(conn *client.Websocket, packet server.Packet, reply func(packet server.Packet)) {
+			var delay time.Duration
+			if _, err := fmt.Sscanf(packet.String(), "delay-%d", &delay); err != nil {
+				return
+			}
+			time.AfterFunc(delay*time.Millisecond, func() {
+				reply(server.NewWSPacketString(server.WebsocketMessageTypeText, "client:"+packet.String()))
+			})
+		})
+		var handled = make(chan string, 1)
+		cli.RegConnectionReceivePacketEvent(func(conn *client.Websocket, packet server.Packet) {
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+			response, err := conn.Call(ctx, server.NewWSPacketString(server.WebsocketMessageTypeText, packet.String()))
+			if err != nil {
+				handled <- err.Error()
+				return
+			}
+			handled <- response.String()
+		})
+		So(cli.Run(), ShouldBeNil)
+		var conn *server.Conn
+		select {
+		case conn = <-opened:
+		case <-time.After(time.Second):
+			t.Fatal("connection was not opened")
+		}
+
+		Convey("Correlation", func() {
+			var wait sync.WaitGroup
+			var responses = make([]string, 5)
+			for i := range responses {
+				wait.Add(1)
+				go func(i int) {
+					defer wait.Done()
+					response, err := conn.Call(context.Background(), server.NewWSPacketString(server.WebsocketMessageTypeText, fmt.Sprintf("delay-%d", (len(responses)-i)*20)))
+					if err == nil {
+						responses[i] = response.String()
+					}
+				}(i)
+			}
+			wait.Wait()
+			for i, response := range responses {
+				So(response, ShouldEqual, fmt.Sprintf("client:delay-%d", (len(responses)-i)*20))
+			}
+
+			response, err := cli.Call(context.Background(), server.NewWSPacketString(server.WebsocketMessageTypeText, "ping"))
+			So(err, ShouldBeNil)
+			So(response.String(), ShouldEqual, "server:ping")
+		})
+
+		Convey("CallInHandler", func() {
+			server.PushSystemMessage(srv, func() {
+				conn.Write(server.NewWSPacketString(server.WebsocketMessageTypeText, "push"))
+			})
+			select {
+			case response := <-handled:
+				So(response, ShouldEqual, "server:push")
+			case <-time.After(time.Second * 2):
+				t.Fatal("call in receive handler was not answered")
+			}
+		})
+
+		Convey("Timeout", func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+			defer cancel()
+			_, err := conn.Call(ctx, server.NewWSPacketString(server.WebsocketMessageTypeText, "silent"))
+			So(errors.Is(err, context.DeadlineExceeded), ShouldBeTrue)
+
+			ctx, cancel = context.WithTimeout(context.Background(), 50*time.Millisecond)
+			defer cancel()
+			_, err = cli.Call(ctx, server.NewWSPacketString(server.WebsocketMessageTypeText, "silent"))
+			So(errors.Is(err, context.DeadlineExceeded), ShouldBeTrue)
+
+			response, err := conn.Call(context.Background(), server.NewWSPacketString(server.WebsocketMessageTypeText, "delay-1"))
+			So(err, ShouldBeNil)
+			So(response.String(), ShouldEqual, "client:delay-1")
+		})
+
+		Convey("Close", func() {
+			var serverErr, clientErr = make(chan error, 1), make(chan error, 1)
+			go func() {
+				_, err := conn.Call(context.Background(), server.NewWSPacketString(server.WebsocketMessageTypeText, "silent"))
+				serverErr <- err
+			}()
+			go func() {
+				_, err := cli.Call(context.Background(), server.NewWSPacketString(server.WebsocketMessageTypeText, "silent"))
+				clientErr <- err
+			}()
+			time.Sleep(50 * time.Millisecond)
+			server.PushSystemMessage(srv, conn.Close)
+			for _, ch := range []chan error{serverErr, clientErr} {
+				select {
+				case err := <-ch:
+					So(err, ShouldEqual, server.ErrConnClosed)
+				case <-time.After(time.Second):
+					t.Fatal("pending call was not released after the connection closed")
+				}
+			}
+			select {
+			case <-closed:
+			case <-time.After(time.Second):
+				t.Fatal("connection closed event was not fired")
+			}
+		})
+	})
+}
@@ -30,8 +30,14 @@ import (
 // New 根据特定网络类型创建一个服务器
 func New(network Network, options ...Option) *Server {
 	server := &Server{
-		event:        &event{},
-		runtime:      &runtime{messagePoolSize: DefaultMessageBufferSize, messageChannelSize: DefaultMessageChannelSize},
+		event: &event{},
+		runtime: &runtime{
+			messagePoolSize:      DefaultMessageBufferSize,
+			messageChannelSize:   DefaultMessageChannelSize,
+			crossRetryAttempts:   DefaultCrossRetryAttempts,
+			crossRetryBackoff:    DefaultCrossRetryBackoff,
+			crossRetryMaxBackoff: DefaultCrossRetryMaxBackoff,
+		},
 		option:       &option{},
 		network:      network,
 		online:       concurrent.NewBalanceMap[string, *Conn](),
@@ -50,6 +56,7 @@ func New(network Network, options ...Option) *Server {
 		server.grpcServer = grpc.NewServer()
 	case NetworkWebsocket:
 		server.websocketReadDeadline = DefaultWebsocketReadDeadline
+		server.websocketServer = &http.Server{}
 	}
 
 	for _, option := range options {
@@ -82,21 +89,33 @@ type Server struct {
 	online                   *concurrent.BalanceMap[string, *Conn]             // 在线连接
 	ginServer                *gin.Engine                                       // HTTP模式下的路由器
 	httpServer               *http.Server                                      // HTTP模式下的服务器
+	websocketServer          *http.Server                                      // Websocket模式下的服务器
 	grpcServer               *grpc.Server                                      // GRPC模式下的服务器
 	gServer                  *gNet                                             // TCP或UDP模式下的服务器
-	isRunning                bool                                              // 是否正在运行
+	isRunning                atomic.Bool                                       // 是否正在运行
 	isShutdown               atomic.Bool                                       // 是否已关闭
 	closeChannel             chan struct{}                                     // 关闭信号
 	ants                     *ants.Pool                                        // 协程池
 	messagePool              *concurrent.Pool[*Message]                        // 消息池
 	messageChannel           chan *Message                                     // 消息管道
+	messageLanes             *messageLanes                                     // 消息优先级通道，为 nil 时表示所有消息共享 messageChannel
+	recorder                 *messageRecorder                                  // 消息记录器
+	crashReporter            *crashReporter                                    // 崩溃报告器
+	replaying                atomic.Bool                                       // 是否正在回放消息
 	multiple                 *MultipleServer                                   // 多服务器模式下的服务器
 	multipleRuntimeErrorChan chan error                                        // 多服务器模式下的运行时错误
 	runMode                  RunMode                                           // 运行模式
-	shuntChannels            *concurrent.BalanceMap[int64, chan *Message]      // 分流管道
+	shunts                   *concurrent.BalanceMap[int64, *shunt]             // 分流管道
+	shuntIdleTimeout         time.Duration                                     // 分流管道空闲回收时间，为 0 时表示不回收
 	channelGenerator         func(guid int64) chan *Message                    // 消息管道生成器
 	shuntMatcher             func(conn *Conn) (guid int64, allowToCreate bool) // 分流管道匹配器
 	messageCounter           atomic.Int64                                      // 消息计数器
+	sessions                 *concurrent.BalanceMap[string, *Conn]             // 会话
+	ipConnections            *concurrent.BalanceMap[string, int]               // 每个 IP 的连接数量
+	draining                 atomic.Bool                                       // 是否正在排空连接
+	metrics                  *serverMetrics                                    // 指标
+	adminConsole             *adminConsole                                     // 管理控制台
+	linkClosed               func(conn *Conn, err any)                         // 仅针对虚拟连接触发事件时，代理链路关闭的处理函数
 }
 
 // Run 使用特定地址运行服务器
@@ -112,36 +131,68 @@ type Server struct {
 //		server.NetworkWebsocket (addr:":8888/ws")
 //		server.NetworkKcp (addr:":8888")
 //	 server.NetworkNone (addr:"")
-func (slf *Server) Run(addr string) error {
+func (slf *Server) Run(addr string) (err error) {
 	if slf.network == NetworkNone {
 		addr = "-"
 	}
 	if slf.event == nil {
 		return ErrConstructed
 	}
+	if slf.registry != nil && slf.registry.instance.Address == "" {
+		return ErrRegistryAddress
+	}
 	slf.event.check()
 	slf.addr = addr
 	var protoAddr = fmt.Sprintf("%s://%s", slf.network, slf.addr)
+	if err = slf.serveMetrics(); err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			slf.releaseMetrics()
+			slf.releaseAdminConsole()
+		}
+	}()
+	if err = slf.serveAdminConsole(); err != nil {
+		return err
+	}
+	slf.messagePool = concurrent.NewPool[*Message](slf.messagePoolSize,
+		func() *Message {
+			return &Message{}
+		},
+		func(data *Message) {
+			data.t = 0
+			data.attrs = nil
+			data.shunt = nil
+			data.conn = false
+		},
+	)
+	if slf.messageLaneWeights != nil {
+		slf.messageLanes = newMessageLanes(*slf.messageLaneWeights, slf.messageChannelSize)
+	} else {
+		slf.messageChannel = make(chan *Message, slf.messageChannelSize)
+	}
+	if err = slf.initCross(); err != nil {
+		log.Error("Cross", log.Err(err))
+		return err
+	}
 	var messageInitFinish = make(chan struct{}, 1)
 	var connectionInitHandle = func(callback func()) {
-		slf.messagePool = concurrent.NewPool[*Message](slf.messagePoolSize,
-			func() *Message {
-				return &Message{}
-			},
-			func(data *Message) {
-				data.t = 0
-				data.attrs = nil
-			},
-		)
-		slf.messageChannel = make(chan *Message, slf.messageChannelSize)
 		if slf.network != NetworkHttp && slf.network != NetworkWebsocket && slf.network != NetworkGRPC {
 			slf.gServer = &gNet{Server: slf}
 		}
 		if callback != nil {
 			go callback()
 		}
+		if slf.keepaliveEnabled() && (slf.network == NetworkWebsocket || slf.network == NetworkKcp) {
+			go slf.keepaliveLoop()
+		}
 		go func() {
 			messageInitFinish <- struct{}{}
+			if slf.messageLanes != nil {
+				slf.messageLanes.run(slf.dispatchMessage)
+				return
+			}
 			for message := range slf.messageChannel {
 				slf.dispatchMessage(message)
 			}
@@ -151,7 +202,7 @@ func (slf *Server) Run(addr string) error {
 	switch slf.network {
 	case NetworkNone:
 		go connectionInitHandle(func() {
-			slf.isRunning = true
+			slf.isRunning.Store(true)
 			slf.OnStartBeforeEvent()
 		})
 	case NetworkGRPC:
@@ -161,24 +212,28 @@ func (slf *Server) Run(addr string) error {
 		}
 		go connectionInitHandle(nil)
 		go func() {
-			slf.isRunning = true
+			slf.isRunning.Store(true)
 			slf.OnStartBeforeEvent()
 			if err := slf.grpcServer.Serve(listener); err != nil {
-				slf.isRunning = false
+				slf.isRunning.Store(false)
 				PushErrorMessage(slf, err, MessageErrorActionShutdown)
 			}
 		}()
 	case NetworkTcp, NetworkTcp4, NetworkTcp6, NetworkUdp, NetworkUdp4, NetworkUdp6, NetworkUnix:
+		if slf.hotUpgrade {
+			upgrader.reuse(slf, string(slf.network), slf.addr)
+		}
 		go connectionInitHandle(func() {
-			slf.isRunning = true
+			slf.isRunning.Store(true)
 			slf.OnStartBeforeEvent()
 			if err := gnet.Serve(slf.gServer, protoAddr,
 				gnet.WithLogger(log.GetLogger()),
 				gnet.WithLogLevel(super.If(slf.runMode == RunModeProd, logging.ErrorLevel, logging.DebugLevel)),
 				gnet.WithTicker(true),
 				gnet.WithMulticore(true),
+				gnet.WithReusePort(slf.hotUpgrade),
 			); err != nil {
-				slf.isRunning = false
+				slf.isRunning.Store(false)
 				PushErrorMessage(slf, err, MessageErrorActionShutdown)
 			}
 		})
@@ -188,15 +243,21 @@ func (slf *Server) Run(addr string) error {
 			return err
 		}
 		go connectionInitHandle(func() {
-			slf.isRunning = true
+			slf.isRunning.Store(true)
 			slf.OnStartBeforeEvent()
 			for {
 				session, err := listener.AcceptKCP()
 				if err != nil {
 					continue
 				}
+				ip := addrIP(session.RemoteAddr())
+				if !slf.accepting() || !slf.acquireIP(ip) {
+					_ = session.Close()
+					continue
+				}
 
 				conn := newKcpConn(slf, session)
+				conn.acquiredIP = ip
 				slf.OnConnectionOpenedEvent(conn)
 				slf.OnConnectionOpenedAfterEvent(conn)
 
@@ -213,7 +274,9 @@ func (slf *Server) Run(addr string) error {
 						if err != nil {
 							panic(err)
 						}
-						PushPacketMessage(slf, conn, buf[:n])
+						if err = conn.receive(buf[:n]); err != nil {
+							panic(err)
+						}
 					}
 				}(conn)
 			}
@@ -227,34 +290,38 @@ func (slf *Server) Run(addr string) error {
 		case RunModeProd:
 			gin.SetMode(gin.ReleaseMode)
 		}
+		listener, err := slf.listen("tcp", slf.addr)
+		if err != nil {
+			return err
+		}
+		slf.hotUpgradeDone("tcp", slf.addr)
 		go func() {
-			slf.isRunning = true
+			slf.isRunning.Store(true)
 			slf.OnStartBeforeEvent()
 			slf.httpServer.Addr = slf.addr
 			go connectionInitHandle(nil)
 			if len(slf.certFile)+len(slf.keyFile) > 0 {
-				if err := slf.httpServer.ListenAndServeTLS(slf.certFile, slf.keyFile); err != nil {
-					slf.isRunning = false
+				if err := slf.httpServer.ServeTLS(listener, slf.certFile, slf.keyFile); err != nil && !isListenerClosed(err) {
+					slf.isRunning.Store(false)
 					PushErrorMessage(slf, err, MessageErrorActionShutdown)
 				}
 			} else {
-				if err := slf.httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-					slf.isRunning = false
+				if err := slf.httpServer.Serve(listener); err != nil && !isListenerClosed(err) {
+					slf.isRunning.Store(false)
 					PushErrorMessage(slf, err, MessageErrorActionShutdown)
 				}
 			}
 
 		}()
 	case NetworkWebsocket:
+		var pattern string
+		if index := strings.Index(addr, "/"); index == -1 {
+			pattern = "/"
+		} else {
+			pattern = addr[index:]
+			slf.addr = slf.addr[:index]
+		}
 		go connectionInitHandle(func() {
-			var pattern string
-			var index = strings.Index(addr, "/")
-			if index == -1 {
-				pattern = "/"
-			} else {
-				pattern = addr[index:]
-				slf.addr = slf.addr[:index]
-			}
 			var upgrade = websocket.Upgrader{
 				ReadBufferSize:  4096,
 				WriteBufferSize: 4096,
@@ -264,21 +331,37 @@ func (slf *Server) Run(addr string) error {
 			}
 			http.HandleFunc(pattern, func(writer http.ResponseWriter, request *http.Request) {
 				ip := request.Header.Get("X-Real-IP")
+				if len(ip) == 0 {
+					ip = request.RemoteAddr
+					if index := strings.LastIndex(ip, ":"); index != -1 {
+						ip = ip[0:index]
+					}
+				}
+				if !slf.accepting() {
+					http.Error(writer, ErrServerShutdown.Error(), http.StatusServiceUnavailable)
+					return
+				}
+				limitIP := slf.limitIP(request)
+				if !slf.acquireIP(limitIP) {
+					http.Error(writer, ErrConnectionLimitExceeded.Error(), http.StatusTooManyRequests)
+					return
+				}
 				ws, err := upgrade.Upgrade(writer, request, nil)
 				if err != nil {
+					slf.releaseIP(limitIP)
 					return
 				}
-				if len(ip) == 0 {
-					addr := ws.RemoteAddr().String()
-					if index := strings.LastIndex(addr, ":"); index != -1 {
-						ip = addr[0:index]
-					}
-				}
 				if slf.websocketCompression > 0 {
 					_ = ws.SetCompressionLevel(slf.websocketCompression)
 				}
 				ws.EnableWriteCompression(slf.websocketWriteCompression)
 				conn := newWebsocketConn(slf, ws, ip)
+				conn.acquiredIP = limitIP
+				conn.header = request.Header.Clone()
+				ws.SetPongHandler(func(string) error {
+					conn.touch()
+					return nil
+				})
 				for k, v := range request.URL.Query() {
 					if len(v) == 1 {
 						conn.SetData(k, v[0])
@@ -286,7 +369,11 @@ func (slf *Server) Run(addr string) error {
 						conn.SetData(k, v)
 					}
 				}
-				slf.OnConnectionOpenedEvent(conn)
+				if token := request.URL.Query().Get(slf.sessionQueryKey); len(slf.sessionQueryKey) > 0 && len(token) > 0 {
+					slf.OnConnectionResumeOrOpenedEvent(conn, token)
+				} else {
+					slf.OnConnectionOpenedEvent(conn)
+				}
 
 				defer func() {
 					if err := recover(); err != nil {
@@ -304,20 +391,27 @@ func (slf *Server) Run(addr string) error {
 					if len(slf.supportMessageTypes) > 0 && !slf.supportMessageTypes[messageType] {
 						panic(ErrWebsocketIllegalMessageType)
 					}
-					PushPacketMessage(slf, conn, append(packet, byte(messageType)))
+					conn.push(packet, messageType)
 				}
 			})
 			go func() {
-				slf.isRunning = true
+				slf.isRunning.Store(true)
 				slf.OnStartBeforeEvent()
+				listener, err := slf.listen("tcp", slf.addr)
+				if err != nil {
+					slf.isRunning.Store(false)
+					PushErrorMessage(slf, err, MessageErrorActionShutdown)
+					return
+				}
+				slf.hotUpgradeDone("tcp", slf.addr)
 				if len(slf.certFile)+len(slf.keyFile) > 0 {
-					if err := http.ListenAndServeTLS(slf.addr, slf.certFile, slf.keyFile, nil); err != nil {
-						slf.isRunning = false
+					if err := slf.websocketServer.ServeTLS(listener, slf.certFile, slf.keyFile); err != nil && !isListenerClosed(err) {
+						slf.isRunning.Store(false)
 						PushErrorMessage(slf, err, MessageErrorActionShutdown)
 					}
 				} else {
-					if err := http.ListenAndServe(slf.addr, nil); err != nil {
-						slf.isRunning = false
+					if err := slf.websocketServer.Serve(listener); err != nil && !isListenerClosed(err) {
+						slf.isRunning.Store(false)
 						PushErrorMessage(slf, err, MessageErrorActionShutdown)
 					}
 				}
@@ -331,6 +425,10 @@ func (slf *Server) Run(addr string) error {
 	<-messageInitFinish
 	close(messageInitFinish)
 	messageInitFinish = nil
+	slf.startRegistry()
+	if slf.hotUpgrade {
+		upgrader.register(slf)
+	}
 	if slf.multiple == nil {
 		log.Info("Server", log.String(serverMark, "===================================================================="))
 		log.Info("Server", log.String(serverMark, "RunningInfo"),
@@ -357,6 +455,15 @@ func (slf *Server) Run(addr string) error {
 	return nil
 }
 
+// addrIP 获取网络地址中的 IP 部分
+func addrIP(addr net.Addr) string {
+	ip := addr.String()
+	if index := strings.LastIndex(ip, ":"); index != -1 {
+		ip = ip[0:index]
+	}
+	return ip
+}
+
 // RunNone 是 Run("") 的简写，仅适用于运行 NetworkNone 服务器
 func (slf *Server) RunNone() error {
 	return slf.Run(str.None)
@@ -406,12 +513,32 @@ func (slf *Server) Ticker() *timer.Ticker {
 }
 
 // Shutdown 主动停止运行服务器
+//   - 多服务器模式下将通过 MultipleServer.Shutdown 停止运行所有服务器
 func (slf *Server) Shutdown() {
+	if slf.multiple != nil {
+		slf.multiple.Shutdown()
+		return
+	}
 	slf.systemSignal <- syscall.SIGQUIT
 }
 
+// shutdownFromMessage 在消息处理过程中停止运行服务器
+//   - 关闭流程需要等待消息处理完毕及连接排空，而连接关闭事件同样需要在消息处理协程中执行，因此关闭流程不能在当前协程中执行
+func (slf *Server) shutdownFromMessage(err error) {
+	if err == nil {
+		slf.Shutdown()
+		return
+	}
+	go slf.shutdown(err)
+}
+
 // shutdown 停止运行服务器
 func (slf *Server) shutdown(err error) {
+	if slf.hotUpgrade {
+		upgrader.deregister(slf)
+	}
+	slf.releaseRegistry()
+	slf.drain()
 	slf.isShutdown.Store(true)
 	for slf.messageCounter.Load() > 0 {
 		log.Info("Server", log.Any("network", slf.network), log.String("listen", slf.addr),
@@ -426,6 +553,11 @@ func (slf *Server) shutdown(err error) {
 			slf.multipleRuntimeErrorChan <- err
 		}
 	}()
+	slf.releaseMetrics()
+	slf.releaseAdminConsole()
+	if slf.recorder != nil {
+		slf.recorder.close()
+	}
 	if slf.ticker != nil {
 		slf.ticker.Release()
 	}
@@ -441,25 +573,34 @@ func (slf *Server) shutdown(err error) {
 		slf.messagePool.Close()
 		slf.messageChannel = nil
 	}
-	if slf.shuntChannels != nil {
-		slf.shuntChannels.Range(func(key int64, c chan *Message) bool {
-			close(c)
-			return false
+	if slf.messageLanes != nil {
+		// 通道在运行后不再被替换，关闭前先关闭消息池，使后续的消息不再写入通道
+		slf.messagePool.Close()
+		slf.messageLanes.close()
+	}
+	if slf.shunts != nil {
+		slf.shunts.ClearHandle(func(key int64, s *shunt) {
+			s.close()
 		})
-		slf.shuntChannels.Clear()
-		slf.shuntChannels = nil
 	}
-	if slf.grpcServer != nil && slf.isRunning {
+	if slf.grpcServer != nil && slf.isRunning.Load() {
 		slf.grpcServer.GracefulStop()
 	}
-	if slf.httpServer != nil && slf.isRunning {
+	if slf.httpServer != nil && slf.isRunning.Load() {
 		ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 		defer cancel()
 		if shutdownErr := slf.httpServer.Shutdown(ctx); shutdownErr != nil {
 			log.Error("Server", log.Err(shutdownErr))
 		}
 	}
-	if slf.gServer != nil && slf.isRunning {
+	if slf.websocketServer != nil && slf.isRunning.Load() {
+		ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+		defer cancel()
+		if shutdownErr := slf.websocketServer.Shutdown(ctx); shutdownErr != nil {
+			log.Error("Server", log.Err(shutdownErr))
+		}
+	}
+	if slf.gServer != nil && slf.isRunning.Load() {
 		if shutdownErr := gnet.Stop(context.Background(), fmt.Sprintf("%s://%s", slf.network, slf.addr)); err != nil {
 			log.Error("Server", log.Err(shutdownErr))
 		}
@@ -524,19 +665,6 @@ func (slf *Server) GetMessageCount() int64 {
 	return slf.messageCounter.Load()
 }
 
-// ShuntChannelFreed 释放分流通道
-func (slf *Server) ShuntChannelFreed(channelGuid int64) {
-	if slf.shuntChannels == nil {
-		return
-	}
-	channel, exist := slf.shuntChannels.GetExist(channelGuid)
-	if exist {
-		close(channel)
-		slf.shuntChannels.Delete(channelGuid)
-		slf.OnShuntChannelClosedEvent(channelGuid)
-	}
-}
-
 // pushMessage 向服务器中写入特定类型的消息，需严格遵守消息属性要求
 func (slf *Server) pushMessage(message *Message) {
 	if slf.messagePool.IsClose() {
@@ -546,28 +674,39 @@ func (slf *Server) pushMessage(message *Message) {
 	if slf.isShutdown.Load() {
 		return
 	}
-	if slf.shuntChannels != nil && (message.t == MessageTypePacket) {
-		conn := message.attrs[0].(*Conn)
-		channelGuid, allowToCreate := slf.shuntMatcher(conn)
-		channel, exist := slf.shuntChannels.GetExist(channelGuid)
-		if !exist && allowToCreate {
-			channel = slf.channelGenerator(channelGuid)
-			slf.shuntChannels.Set(channelGuid, channel)
-			go func(channel chan *Message) {
-				for message := range channel {
-					slf.dispatchMessage(message)
-				}
-			}(channel)
-			defer slf.OnShuntChannelCreatedEvent(channelGuid)
-		}
-		if channel != nil {
-			channel <- message
+	if slf.shunts != nil && message.t == MessageTypePacket {
+		channelGuid, allowToCreate := slf.shuntMatcher(message.attrs[0].(*Conn))
+		if slf.pushShuntMessage(channelGuid, message, allowToCreate) {
 			return
 		}
 	}
+	slf.pushSystemChannel(message)
+}
+
+// pushSystemChannel 向系统通道中写入消息，开启消息优先级通道时将根据消息类型写入对应的通道
+func (slf *Server) pushSystemChannel(message *Message) {
+	if slf.messageLanes != nil {
+		slf.messageLanes.push(message)
+		return
+	}
 	slf.messageChannel <- message
 }
 
+// pushMessageTo 向特定的分流通道中写入消息，分流通道不存在时将自动创建，未开启分流时将写入系统通道
+func (slf *Server) pushMessageTo(guid int64, message *Message) {
+	if slf.messagePool.IsClose() {
+		slf.messagePool.Release(message)
+		return
+	}
+	if slf.isShutdown.Load() {
+		return
+	}
+	if slf.shunts != nil && slf.pushShuntMessage(guid, message, true) {
+		return
+	}
+	slf.pushSystemChannel(message)
+}
+
 func (slf *Server) low(message *Message, present time.Time, expect time.Duration, messageReplace ...string) {
 	cost := time.Since(present)
 	if cost > expect {
@@ -602,21 +741,21 @@ func (slf *Server) dispatchMessage(msg *Message) {
 	}
 
 	present := time.Now()
+	var messageType = msg.t // 异步消息可能在其他协程中被回收，需提前获取消息类型
+	if slf.recorder != nil {
+		slf.recorder.recordMessage(msg)
+	}
 	defer func() {
 		if err := recover(); err != nil {
-			stack := string(debug.Stack())
-			log.Error("Server", log.String("MessageType", messageNames[msg.t]), log.Any("MessageAttrs", msg.attrs), log.Any("error", err), log.String("stack", stack))
-			fmt.Println(stack)
-			if e, ok := err.(error); ok {
-				slf.OnMessageErrorEvent(msg, e)
-			}
+			slf.recoverMessage(msg, msg.attrs, err, string(debug.Stack()))
 		}
 
-		if msg.t == MessageTypeAsync {
+		if messageType == MessageTypeAsync {
 			return
 		}
 
 		super.Handle(cancel)
+		slf.metrics.recordMessage(msg.t, time.Since(present))
 		slf.low(msg, present, time.Millisecond*100)
 		slf.messageCounter.Add(-1)
 
@@ -631,8 +770,22 @@ func (slf *Server) dispatchMessage(msg *Message) {
 		var conn = attrs[0].(*Conn)
 		var packet = attrs[1].([]byte)
 		var wst = int(packet[len(packet)-1])
+		var kind, seq = RPCFramePush, uint32(0)
+		if conn.rpc != nil {
+			var data []byte
+			var err error
+			if kind, seq, data, err = UnpackRPCFrame(packet[:len(packet)-1]); err != nil {
+				log.Warn("Server", log.String("conn", conn.GetID()), log.Err(err))
+				break
+			}
+			packet = append(data, byte(wst))
+		}
 		if !slf.OnConnectionPacketPreprocessEvent(conn, packet, func(newPacket []byte) { packet = newPacket }) {
-			slf.OnConnectionReceivePacketEvent(conn, Packet{Data: packet[:len(packet)-1], WebsocketType: wst})
+			if kind == RPCFrameRequest {
+				slf.OnConnectionReceiveRequestEvent(conn, seq, Packet{Data: packet[:len(packet)-1], WebsocketType: wst})
+			} else {
+				slf.OnConnectionReceivePacketEvent(conn, Packet{Data: packet[:len(packet)-1], WebsocketType: wst})
+			}
 		}
 	case MessageTypeError:
 		err, action := attrs[0].(error), attrs[1].(MessageErrorAction)
@@ -640,28 +793,31 @@ func (slf *Server) dispatchMessage(msg *Message) {
 		case MessageErrorActionNone:
 			log.Panic("Server", log.Err(err))
 		case MessageErrorActionShutdown:
-			slf.shutdown(err)
+			slf.shutdownFromMessage(err)
 		default:
 			log.Warn("Server", log.String("not support message error action", action.String()))
 		}
 	case MessageTypeCross:
+		if len(attrs) > 2 {
+			if reply, ok := attrs[2].(func(packet []byte)); ok {
+				slf.OnReceiveCrossRequestEvent(attrs[0].(int64), attrs[1].([]byte), reply)
+				break
+			}
+		}
 		slf.OnReceiveCrossPacketEvent(attrs[0].(int64), attrs[1].([]byte))
 	case MessageTypeTicker:
 		attrs[0].(func())()
 	case MessageTypeAsync:
 		handle := attrs[0].(func() error)
 		callback, cb := attrs[1].(func(err error))
+		var shunt = msg.shunt
 		if err := slf.ants.Submit(func() {
 			defer func() {
 				if err := recover(); err != nil {
-					stack := string(debug.Stack())
-					log.Error("Server", log.String("MessageType", messageNames[msg.t]), log.Any("error", err), log.String("stack", stack))
-					fmt.Println(stack)
-					if e, ok := err.(error); ok {
-						slf.OnMessageErrorEvent(msg, e)
-					}
+					slf.recoverMessage(msg, attrs, err, string(debug.Stack()))
 				}
 				super.Handle(cancel)
+				slf.metrics.recordMessage(msg.t, time.Since(present))
 				slf.low(msg, present, time.Second)
 				slf.messageCounter.Add(-1)
 
@@ -671,9 +827,14 @@ func (slf *Server) dispatchMessage(msg *Message) {
 			}()
 			err := handle()
 			if cb && callback != nil {
-				PushSystemMessage(slf, func() {
+				var caller = func() {
 					callback(err)
-				}, "AsyncCallback")
+				}
+				if shunt != nil {
+					PushSystemMessageTo(slf, shunt.guid, caller, "AsyncCallback")
+				} else {
+					PushSystemMessage(slf, caller, "AsyncCallback")
+				}
 			} else if err != nil {
 				log.Error("Server", log.String("MessageType", messageNames[msg.t]), log.Any("error", err), log.String("stack", string(debug.Stack())))
 			}
@@ -0,0 +1,147 @@
+package server
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"github.com/kercylan98/minotaur/utils/log"
+	"time"
+)
+
+// newSessionToken 为连接生成会话令牌，当服务器未开启会话保持时返回空字符串
+func newSessionToken(server *Server) string {
+	if server.sessionGrace <= 0 {
+		return ""
+	}
+	var buf = make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(buf)
+}
+
+// bindSession 将连接绑定到会话中
+func (slf *Server) bindSession(conn *Conn) {
+	if conn.token == "" {
+		return
+	}
+	slf.sessions.Set(conn.token, conn)
+}
+
+// resolveSession 获取连接关闭事件真正作用的连接
+//   - 当连接已通过会话恢复转交至其他连接时，返回接管该连接传输层的连接
+//   - 当连接的传输层已经被替换时，该连接的关闭事件已经过期，将返回 false
+func (slf *Server) resolveSession(conn *Conn) (*Conn, bool) {
+	target := conn.resumedTo.Load()
+	if target == nil {
+		return conn, conn.carrier.Load() == nil
+	}
+	return target, target.carrier.Load() == conn
+}
+
+// ResumeSession 通过会话令牌将新的连接恢复至断开前的会话中
+//   - 恢复成功后，原有连接将接管 conn 的传输层，通过 SetData 设置的数据及未发送的数据包都将被保留，并在恢复后继续发送
+//   - 恢复成功后 conn 将被废弃，返回值为恢复后应当使用的连接，同时将触发 SessionResumedEvent
+//   - 当原有连接尚未断开时（例如移动网络切换后原有传输层处于半开状态），原有传输层将被关闭并由 conn 接管
+//   - 当会话不存在或已过期时将返回 false
+//   - 该函数应当在服务器消息中调用，例如 ConnectionReceivePacketEvent
+//   - 在 NetworkWebsocket 模式下，可通过 WithSession 的 queryKey 参数在连接建立时自动进行恢复
+func (slf *Server) ResumeSession(conn *Conn, token string) (*Conn, bool) {
+	if slf.sessions == nil || token == "" {
+		return nil, false
+	}
+	old, exist := slf.sessions.GetExist(token)
+	if !exist || old == conn {
+		return nil, false
+	}
+	id := conn.GetID()
+	if !old.isDetached() {
+		log.Info("Server", log.String("conn", old.GetID()), log.String("takeover", id), log.Err(ErrSessionTakenOver))
+		slf.online.Delete(old.GetID())
+		old.detach()
+	}
+	old.attach(conn)
+	if conn.token != "" {
+		slf.sessions.Delete(conn.token)
+	}
+	slf.online.Delete(id)
+	slf.online.Set(old.GetID(), old)
+	slf.OnSessionResumedEvent(old)
+	return old, true
+}
+
+// expireSession 在会话保持时间结束后释放会话
+func (slf *Server) expireSession(conn *Conn) {
+	conn.mutex.Lock()
+	defer conn.mutex.Unlock()
+	if conn.expire != nil {
+		conn.expire.Stop()
+	}
+	conn.expire = time.AfterFunc(slf.sessionGrace, func() {
+		PushSystemMessage(slf, func() {
+			if !conn.isDetached() {
+				return
+			}
+			slf.sessions.Delete(conn.token)
+			conn.Close()
+			slf.OnSessionExpiredEvent(conn)
+		}, "SessionExpired")
+	})
+}
+
+// GetSessionToken 获取连接的会话令牌
+//   - 仅在通过 WithSession 开启会话保持时有效，否则返回空字符串
+//   - 客户端需要在重新连接后通过该令牌恢复会话，通常应当在登录成功后下发至客户端
+func (slf *Conn) GetSessionToken() string {
+	return slf.token
+}
+
+// isDetached 连接的传输层是否已断开且处于会话保持期间
+func (slf *Conn) isDetached() bool {
+	slf.mutex.Lock()
+	defer slf.mutex.Unlock()
+	return slf.detached
+}
+
+// detach 断开连接的传输层，但保留连接数据及未发送的数据包
+func (slf *Conn) detach() {
+	slf.mutex.Lock()
+	slf.detached = true
+	slf.mutex.Unlock()
+	slf.closeTransport()
+}
+
+// attach 接管 conn 的传输层，conn 将被废弃
+//   - 因 conn 写入队列已满而等待的写入方将被唤醒，并将数据包写入当前连接
+func (slf *Conn) attach(conn *Conn) {
+	slf.mutex.Lock()
+	conn.mutex.Lock()
+	if slf.expire != nil {
+		slf.expire.Stop()
+		slf.expire = nil
+	}
+	slf.setAddr(conn.RemoteAddr(), conn.GetIP())
+	slf.ws = conn.ws
+	slf.gn = conn.gn
+	slf.kcp = conn.kcp
+	slf.carrier.Store(conn)
+	conn.resumedTo.Store(slf)
+	slf.packets = append(slf.packets, conn.packets...)
+	slf.queuedPackets += conn.queuedPackets
+	slf.queuedBytes += conn.queuedBytes
+	slf.detached = false
+	conn.packets = nil
+	var pool = conn.packetPool
+	conn.packetPool = nil
+	conn.notifyWritable()
+	conn.mutex.Unlock()
+	slf.mutex.Unlock()
+	if pool != nil {
+		pool.Close()
+	}
+	if conn.rpc != nil {
+		conn.rpc.Close(ErrConnClosed)
+	}
+	slf.touch()
+	conn.notifyWrite()
+	slf.notifyWrite()
+}
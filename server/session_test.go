@@ -0,0 +1,189 @@
+package server_test
+
+import (
+	"github.com/gorilla/websocket"
+	"github.com/kercylan98/minotaur/server"
+	. "github.com/smartystreets/goconvey/convey"
+	"strings"
+	"testing"
+	"time"
+)
+
+// sessionServer 开启会话保持的 WebSocket 服务器
+type sessionServer struct {
+	url      string
+	opened   chan *server.Conn
+	closed   chan *server.Conn
+	resumed  chan *server.Conn
+	expired  chan *server.Conn
+	stop     func()
+	onClosed func(conn *server.Conn)
+}
+
+func newSessionServer(name string, grace time.Duration) *sessionServer {
+	var addr = freeAddr()
+	var pattern = "/" + name + "-" + strings.ReplaceAll(addr, ":", "-")
+	var s = &sessionServer{
+		url:     "ws://" + addr + pattern,
+		opened:  make(chan *server.Conn, 4),
+		closed:  make(chan *server.Conn, 4),
+		resumed: make(chan *server.Conn, 4),
+		expired: make(chan *server.Conn, 4),
+	}
+	srv := server.New(server.NetworkWebsocket, server.WithSession(grace, "token"))
+	srv.RegConnectionOpenedEvent(func(srv *server.Server, conn *server.Conn) {
+		conn.SetData("name", conn.GetID())
+		conn.Write(server.Packet{WebsocketType: server.WebsocketMessageTypeText, Data: []byte(conn.GetSessionToken())})
+		s.opened <- conn
+	})
+	srv.RegConnectionClosedEvent(func(srv *server.Server, conn *server.Conn, err any) {
+		if s.onClosed != nil {
+			s.onClosed(conn)
+		}
+		s.closed <- conn
+	})
+	srv.RegSessionResumedEvent(func(srv *server.Server, conn *server.Conn) {
+		s.resumed <- conn
+	})
+	srv.RegSessionExpiredEvent(func(srv *server.Server, conn *server.Conn) {
+		s.expired <- conn
+	})
+	s.stop = runServer(srv, addr+pattern)
+	waitListen(addr)
+	return s
+}
+
+// dial 携带会话令牌连接服务器
+func (slf *sessionServer) dial(token string) *websocket.Conn {
+	var url = slf.url
+	if len(token) > 0 {
+		url += "?token=" + token
+	}
+	ws, _, err := websocket.DefaultDialer.Dial(url, nil)
+	So(err, ShouldBeNil)
+	return ws
+}
+
+// read 读取客户端收到的数据包
+func read(ws *websocket.Conn) string {
+	_ = ws.SetReadDeadline(time.Now().Add(time.Second))
+	_, data, err := ws.ReadMessage()
+	So(err, ShouldBeNil)
+	return string(data)
+}
+
+// disconnect 客户端主动断开连接，服务器在回应关闭帧后写入数据包将失败
+func disconnect(ws *websocket.Conn) {
+	_ = ws.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+	_ = ws.Close()
+}
+
+// waitConn 等待事件中的连接，超时返回 nil
+func waitConn(ch chan *server.Conn, timeout time.Duration) *server.Conn {
+	select {
+	case conn := <-ch:
+		return conn
+	case <-time.After(timeout):
+		return nil
+	}
+}
+
+func TestWithSession(t *testing.T) {
+	Convey("TestWithSession", t, func() {
+		Convey("Resume", func() {
+			s := newSessionServer("session-resume", time.Minute)
+			defer s.stop()
+			s.onClosed = func(conn *server.Conn) {
+				// 写入时传输层已回应关闭帧，写入将失败，数据包需要被保留
+				var flushes = conn.GetWriteQueueMetrics().Flushes
+				conn.Write(server.Packet{WebsocketType: server.WebsocketMessageTypeText, Data: []byte("failed")})
+				for deadline := time.Now().Add(time.Second); time.Now().Before(deadline) && conn.GetWriteQueueMetrics().Flushes == flushes; {
+					time.Sleep(time.Millisecond)
+				}
+			}
+
+			ws := s.dial("")
+			token := read(ws)
+			origin := waitConn(s.opened, time.Second)
+			So(origin != nil, ShouldBeTrue)
+			disconnect(ws)
+			So(waitConn(s.closed, time.Second) == origin, ShouldBeTrue)
+			So(origin.GetWriteQueueMetrics().QueuedPackets, ShouldEqual, 1)
+			origin.Write(server.Packet{WebsocketType: server.WebsocketMessageTypeText, Data: []byte("detached")})
+
+			ws = s.dial(token)
+			defer ws.Close()
+			So(read(ws), ShouldEqual, "failed")
+			So(read(ws), ShouldEqual, "detached")
+			resumed := waitConn(s.resumed, time.Second)
+			So(resumed == origin, ShouldBeTrue)
+			So(resumed.GetSessionToken(), ShouldEqual, token)
+			So(resumed.GetData("name"), ShouldNotEqual, resumed.GetID())
+			So(waitConn(s.opened, 100*time.Millisecond) == nil, ShouldBeTrue)
+		})
+
+		Convey("Takeover", func() {
+			s := newSessionServer("session-takeover", time.Minute)
+			defer s.stop()
+
+			// 原有传输层处于半开状态，服务器尚未感知到断开
+			stale := s.dial("")
+			defer stale.Close()
+			token := read(stale)
+			origin := waitConn(s.opened, time.Second)
+			So(origin != nil, ShouldBeTrue)
+
+			ws := s.dial(token)
+			defer ws.Close()
+			So(waitConn(s.resumed, time.Second) == origin, ShouldBeTrue)
+			So(origin.GetID(), ShouldEqual, ws.LocalAddr().String())
+			origin.Write(server.Packet{WebsocketType: server.WebsocketMessageTypeText, Data: []byte("resumed")})
+			So(read(ws), ShouldEqual, "resumed")
+
+			_ = stale.SetReadDeadline(time.Now().Add(time.Second))
+			_, _, err := stale.ReadMessage()
+			So(err, ShouldNotBeNil)
+			So(waitConn(s.closed, 100*time.Millisecond) == nil, ShouldBeTrue)
+			So(waitConn(s.opened, 100*time.Millisecond) == nil, ShouldBeTrue)
+		})
+
+		Convey("Expire", func() {
+			s := newSessionServer("session-expire", 100*time.Millisecond)
+			defer s.stop()
+
+			ws := s.dial("")
+			token := read(ws)
+			origin := waitConn(s.opened, time.Second)
+			disconnect(ws)
+			So(waitConn(s.closed, time.Second) == origin, ShouldBeTrue)
+			So(waitConn(s.expired, time.Second) == origin, ShouldBeTrue)
+
+			ws = s.dial(token)
+			defer ws.Close()
+			So(read(ws), ShouldNotEqual, token)
+			So(waitConn(s.opened, time.Second) != origin, ShouldBeTrue)
+			So(waitConn(s.resumed, 100*time.Millisecond) == nil, ShouldBeTrue)
+		})
+
+		Convey("TokenMismatch", func() {
+			s := newSessionServer("session-mismatch", time.Minute)
+			defer s.stop()
+
+			ws := s.dial("")
+			token := read(ws)
+			origin := waitConn(s.opened, time.Second)
+			disconnect(ws)
+			So(waitConn(s.closed, time.Second) == origin, ShouldBeTrue)
+
+			other := s.dial(strings.Repeat("0", len(token)))
+			defer other.Close()
+			So(read(other), ShouldNotEqual, token)
+			So(waitConn(s.opened, time.Second) != origin, ShouldBeTrue)
+			So(waitConn(s.resumed, 100*time.Millisecond) == nil, ShouldBeTrue)
+
+			ws = s.dial(token)
+			defer ws.Close()
+			So(waitConn(s.resumed, time.Second) == origin, ShouldBeTrue)
+		})
+	})
+}
@@ -0,0 +1,205 @@
+package server
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// shunt 分流通道，同一分流通道内的消息将被串行处理
+type shunt struct {
+	guid       int64
+	channel    chan *Message
+	depth      atomic.Int64 // 尚未处理完成的消息数量
+	processed  atomic.Int64 // 已处理完成的消息数量
+	latency    atomic.Int64 // 已处理完成的消息从推送到处理完成的总耗时
+	maxLatency atomic.Int64 // 已处理完成的消息从推送到处理完成的最大耗时
+
+	rw        sync.RWMutex  // 写入消息时持有读锁，关闭通道时持有写锁，确保不会向已关闭的通道写入消息
+	done      chan struct{} // 分流通道关闭信号，关闭后等待写入的消息将放弃写入
+	closeOnce sync.Once
+}
+
+func newShunt(guid int64, channel chan *Message) *shunt {
+	return &shunt{guid: guid, channel: channel, done: make(chan struct{})}
+}
+
+// push 将消息写入分流通道，分流通道已关闭时返回 false
+//   - 分流通道已满时将阻塞等待，阻塞期间分流通道被关闭时将放弃写入
+func (slf *shunt) push(message *Message) bool {
+	slf.rw.RLock()
+	defer slf.rw.RUnlock()
+	select {
+	case <-slf.done:
+		return false
+	default:
+	}
+	select {
+	case slf.channel <- message:
+		return true
+	case <-slf.done:
+		return false
+	}
+}
+
+// close 关闭分流通道，已写入的消息仍将被处理
+func (slf *shunt) close() {
+	slf.closeOnce.Do(func() {
+		close(slf.done)
+		slf.rw.Lock()
+		close(slf.channel)
+		slf.rw.Unlock()
+	})
+}
+
+// ShuntStats 分流通道统计信息
+type ShuntStats struct {
+	Depth      int64         // 尚未处理完成的消息数量
+	Processed  int64         // 已处理完成的消息数量
+	AvgLatency time.Duration // 消息从推送到处理完成的平均耗时
+	MaxLatency time.Duration // 消息从推送到处理完成的最大耗时
+}
+
+func (slf *shunt) stats() ShuntStats {
+	var stats = ShuntStats{
+		Depth:      slf.depth.Load(),
+		Processed:  slf.processed.Load(),
+		MaxLatency: time.Duration(slf.maxLatency.Load()),
+	}
+	if stats.Processed > 0 {
+		stats.AvgLatency = time.Duration(slf.latency.Load() / stats.Processed)
+	}
+	return stats
+}
+
+func (slf *shunt) record(latency time.Duration) {
+	slf.depth.Add(-1)
+	slf.processed.Add(1)
+	slf.latency.Add(int64(latency))
+	for {
+		var max = slf.maxLatency.Load()
+		if int64(latency) <= max || slf.maxLatency.CompareAndSwap(max, int64(latency)) {
+			return
+		}
+	}
+}
+
+// GetShuntStats 获取所有分流通道的统计信息
+func (slf *Server) GetShuntStats() map[int64]ShuntStats {
+	var stats = map[int64]ShuntStats{}
+	if slf.shunts == nil {
+		return stats
+	}
+	slf.shunts.Range(func(guid int64, s *shunt) bool {
+		stats[guid] = s.stats()
+		return false
+	})
+	return stats
+}
+
+// GetShuntCount 获取当前分流通道的数量
+func (slf *Server) GetShuntCount() int {
+	if slf.shunts == nil {
+		return 0
+	}
+	return slf.shunts.Size()
+}
+
+// ShuntChannelFreed 释放分流通道
+//   - 通过 WithManagedShunt 创建的分流通道将在空闲超时后自动释放，无需调用该函数
+func (slf *Server) ShuntChannelFreed(channelGuid int64) {
+	if slf.shunts == nil {
+		return
+	}
+	s, exist := slf.shunts.DeleteGetExist(channelGuid)
+	if exist {
+		s.close()
+		slf.OnShuntChannelClosedEvent(channelGuid)
+	}
+}
+
+// pushShuntMessage 将消息推送至特定的分流通道，分流通道不存在时将根据 create 决定是否创建
+//   - 返回 false 表示消息未被推送至分流通道
+func (slf *Server) pushShuntMessage(guid int64, message *Message, create bool) bool {
+	var s *shunt
+	var created bool
+	slf.shunts.Atom(func(m map[int64]*shunt) {
+		var exist bool
+		if s, exist = m[guid]; !exist {
+			if !create {
+				return
+			}
+			s = newShunt(guid, slf.channelGenerator(guid))
+			m[guid] = s
+			created = true
+		}
+		// 在锁内增加计数，确保分流通道在消息处理完成前不会被回收
+		s.depth.Add(1)
+	})
+	if s == nil {
+		return false
+	}
+	if created {
+		go slf.runShunt(s)
+		slf.OnShuntChannelCreatedEvent(guid)
+	}
+	message.shunt = s
+	message.pushAt = time.Now()
+	if !s.push(message) {
+		s.depth.Add(-1)
+		message.shunt = nil
+		return false
+	}
+	return true
+}
+
+// runShunt 串行处理分流通道中的消息，开启空闲回收时，分流通道将在空闲超时后被回收
+func (slf *Server) runShunt(s *shunt) {
+	var idle <-chan time.Time
+	var timer *time.Timer
+	if slf.shuntIdleTimeout > 0 {
+		timer = time.NewTimer(slf.shuntIdleTimeout)
+		defer timer.Stop()
+		idle = timer.C
+	}
+	for {
+		select {
+		case message, ok := <-s.channel:
+			if !ok {
+				return
+			}
+			var pushAt = message.pushAt
+			slf.dispatchMessage(message)
+			s.record(time.Since(pushAt))
+			if timer != nil {
+				if !timer.Stop() {
+					select {
+					case <-timer.C:
+					default:
+					}
+				}
+				timer.Reset(slf.shuntIdleTimeout)
+			}
+		case <-idle:
+			if slf.reclaimShunt(s) {
+				return
+			}
+			timer.Reset(slf.shuntIdleTimeout)
+		}
+	}
+}
+
+// reclaimShunt 回收空闲的分流通道，分流通道中仍存在消息时将不会被回收
+func (slf *Server) reclaimShunt(s *shunt) (reclaimed bool) {
+	slf.shunts.Atom(func(m map[int64]*shunt) {
+		if m[s.guid] != s || s.depth.Load() > 0 {
+			return
+		}
+		delete(m, s.guid)
+		reclaimed = true
+	})
+	if reclaimed {
+		slf.OnShuntChannelClosedEvent(s.guid)
+	}
+	return
+}
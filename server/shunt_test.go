@@ -0,0 +1,89 @@
+package server_test
+
+import (
+	"errors"
+	"github.com/kercylan98/minotaur/server"
+	. "github.com/smartystreets/goconvey/convey"
+	"testing"
+	"time"
+)
+
+func TestWithManagedShunt(t *testing.T) {
+	Convey("TestWithManagedShunt", t, func() {
+		srv := server.New(server.NetworkNone, server.WithManagedShunt(func(conn *server.Conn) (guid int64, ok bool) {
+			return 0, false
+		}, 50*time.Millisecond, 0))
+		var closed = make(chan int64, 1)
+		srv.RegShuntChannelCloseEvent(func(srv *server.Server, guid int64) {
+			closed <- guid
+		})
+		stop := runServer(srv)
+		defer stop()
+
+		var order []int
+		var done = make(chan struct{})
+		for i := 0; i < 3; i++ {
+			i := i
+			server.PushSystemMessageTo(srv, 1, func() {
+				order = append(order, i)
+			})
+		}
+		server.PushAsyncMessageTo(srv, 1, func() error {
+			return errors.New("async")
+		}, func(err error) {
+			order = append(order, 3)
+			close(done)
+		})
+		<-done
+		So(order, ShouldResemble, []int{0, 1, 2, 3})
+		So(srv.GetShuntCount(), ShouldEqual, 1)
+
+		stats := srv.GetShuntStats()[1]
+		So(stats.Processed, ShouldBeGreaterThanOrEqualTo, 4)
+		So(stats.MaxLatency, ShouldBeGreaterThan, 0)
+
+		select {
+		case guid := <-closed:
+			So(guid, ShouldEqual, 1)
+		case <-time.After(time.Second):
+			t.Fatal("shunt channel was not reclaimed")
+		}
+		So(srv.GetShuntCount(), ShouldEqual, 0)
+	})
+}
+
+func TestServer_ShuntChannelFreed(t *testing.T) {
+	srv := server.New(server.NetworkNone, server.WithShunt(func(guid int64) chan *server.Message {
+		return make(chan *server.Message, 1)
+	}, func(conn *server.Conn) (guid int64, allowToCreate bool) {
+		return 0, false
+	}))
+	stop := runServer(srv)
+	defer stop()
+
+	var handled = make(chan struct{}, 4000)
+	var pushed = make(chan struct{})
+	go func() {
+		defer close(pushed)
+		for i := 0; i < cap(handled); i++ {
+			server.PushSystemMessageTo(srv, 1, func() {
+				handled <- struct{}{}
+			})
+		}
+	}()
+	for freeing := true; freeing; {
+		select {
+		case <-pushed:
+			freeing = false
+		default:
+			srv.ShuntChannelFreed(1)
+		}
+	}
+	for i := 0; i < cap(handled); i++ {
+		select {
+		case <-handled:
+		case <-time.After(time.Second * 5):
+			t.Fatalf("handled %d messages, expected %d", i, cap(handled))
+		}
+	}
+}
@@ -0,0 +1,318 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kercylan98/minotaur/utils/log"
+)
+
+const (
+	hotUpgradeEnvListeners = "MINOTAUR_HOT_UPGRADE_LISTENERS" // 子进程继承的侦听器，格式为 key=fd,key=fd，fd 为 -1 时表示通过 SO_REUSEPORT 重新侦听
+	hotUpgradeEnvReady     = "MINOTAUR_HOT_UPGRADE_READY"     // 子进程用于通知父进程已就绪的管道
+)
+
+var upgrader = new(hotUpgrader)
+
+// hotUpgrader 进程级别的热更新管理器
+//   - 同一进程中所有开启了 WithHotUpgrade 的服务器将在一次热更新中交由同一个子进程接管
+type hotUpgrader struct {
+	mutex     sync.Mutex
+	once      sync.Once
+	watchOnce sync.Once
+	servers   []*Server                   // 开启了热更新的服务器
+	listeners map[string]*upgradeListener // 当前进程的侦听器，为 nil 时表示通过 SO_REUSEPORT 侦听
+	owners    map[string]*Server          // 侦听器所属的服务器
+	inherited map[string]int              // 从父进程继承的侦听器
+	pending   map[string]struct{}         // 尚未就绪的继承侦听器
+	ready     *os.File                    // 通知父进程已就绪的管道
+	upgrading bool                        // 是否正在进行热更新
+	timeout   time.Duration               // 等待子进程就绪的超时时间
+}
+
+// init 解析从父进程继承的侦听器
+func (slf *hotUpgrader) init() {
+	slf.once.Do(func() {
+		slf.listeners = map[string]*upgradeListener{}
+		slf.owners = map[string]*Server{}
+		slf.inherited = map[string]int{}
+		slf.pending = map[string]struct{}{}
+		if env := os.Getenv(hotUpgradeEnvListeners); len(env) > 0 {
+			for _, item := range strings.Split(env, ",") {
+				var index = strings.LastIndex(item, "=")
+				if index == -1 {
+					continue
+				}
+				fd, err := strconv.Atoi(item[index+1:])
+				if err != nil {
+					continue
+				}
+				slf.inherited[item[:index]] = fd
+				slf.pending[item[:index]] = struct{}{}
+			}
+		}
+		if fd, err := strconv.Atoi(os.Getenv(hotUpgradeEnvReady)); err == nil {
+			slf.ready = os.NewFile(uintptr(fd), hotUpgradeEnvReady)
+		}
+		_ = os.Unsetenv(hotUpgradeEnvListeners)
+		_ = os.Unsetenv(hotUpgradeEnvReady)
+	})
+}
+
+// register 注册开启了热更新的服务器
+func (slf *hotUpgrader) register(srv *Server) {
+	slf.init()
+	slf.mutex.Lock()
+	slf.servers = append(slf.servers, srv)
+	if srv.hotUpgradeTimeout > slf.timeout {
+		slf.timeout = srv.hotUpgradeTimeout
+	}
+	slf.mutex.Unlock()
+	slf.watch()
+}
+
+// deregister 注销已关闭的服务器，其侦听器将不再传递给子进程
+func (slf *hotUpgrader) deregister(srv *Server) {
+	slf.init()
+	slf.mutex.Lock()
+	defer slf.mutex.Unlock()
+	for i, server := range slf.servers {
+		if server == srv {
+			slf.servers = append(slf.servers[:i], slf.servers[i+1:]...)
+			break
+		}
+	}
+	if len(slf.servers) == 0 {
+		// 所有服务器均已关闭，热更新已完成，此后可再次开启热更新
+		slf.upgrading = false
+	}
+	for key, owner := range slf.owners {
+		if owner != srv {
+			continue
+		}
+		if listener := slf.listeners[key]; listener != nil {
+			_ = listener.Close()
+		}
+		delete(slf.listeners, key)
+		delete(slf.owners, key)
+	}
+}
+
+// listen 侦听特定地址，当该地址的侦听器由父进程传递时将直接接管
+func (slf *hotUpgrader) listen(srv *Server, network, addr string) (net.Listener, error) {
+	slf.init()
+	var key = fmt.Sprintf("%s://%s", network, addr)
+	var listener net.Listener
+	var err error
+	slf.mutex.Lock()
+	defer slf.mutex.Unlock()
+	if fd, exist := slf.inherited[key]; exist && fd >= 0 {
+		delete(slf.inherited, key)
+		file := os.NewFile(uintptr(fd), key)
+		listener, err = net.FileListener(file)
+		_ = file.Close()
+		log.Info("Server", log.String("action", "hot-upgrade"), log.String("listen", key), log.String("state", "inherited"))
+	} else {
+		listener, err = net.Listen(network, addr)
+	}
+	if err != nil {
+		return nil, err
+	}
+	var l = &upgradeListener{Listener: listener}
+	slf.listeners[key] = l
+	slf.owners[key] = srv
+	return l, nil
+}
+
+// reuse 记录通过 SO_REUSEPORT 侦听的地址，子进程将重新侦听该地址
+func (slf *hotUpgrader) reuse(srv *Server, network, addr string) {
+	slf.init()
+	var key = fmt.Sprintf("%s://%s", network, addr)
+	slf.mutex.Lock()
+	slf.listeners[key] = nil
+	slf.owners[key] = srv
+	slf.mutex.Unlock()
+}
+
+// done 标记特定地址已完成侦听，当所有继承的侦听器均已就绪时通知父进程
+func (slf *hotUpgrader) done(network, addr string) {
+	slf.init()
+	slf.mutex.Lock()
+	defer slf.mutex.Unlock()
+	delete(slf.pending, fmt.Sprintf("%s://%s", network, addr))
+	if len(slf.pending) > 0 || slf.ready == nil {
+		return
+	}
+	_, _ = slf.ready.Write([]byte{1})
+	_ = slf.ready.Close()
+	slf.ready = nil
+	log.Info("Server", log.String("action", "hot-upgrade"), log.String("state", "ready"))
+}
+
+// upgrade 启动子进程并传递所有侦听器，子进程就绪后当前进程停止接受新的连接并通过关闭流程排空连接
+func (slf *hotUpgrader) upgrade() error {
+	slf.init()
+	slf.mutex.Lock()
+	if slf.upgrading {
+		slf.mutex.Unlock()
+		return ErrHotUpgradeInProgress
+	}
+	if len(slf.servers) == 0 {
+		slf.mutex.Unlock()
+		return ErrHotUpgradeNotEnabled
+	}
+	slf.upgrading = true
+	var timeout, servers = slf.timeout, slf.servers
+	var files []*os.File
+	var items []string
+	for key, listener := range slf.listeners {
+		if listener == nil {
+			items = append(items, fmt.Sprintf("%s=-1", key))
+			continue
+		}
+		filer, ok := listener.Listener.(interface{ File() (*os.File, error) })
+		if !ok {
+			continue
+		}
+		file, err := filer.File()
+		if err != nil {
+			closeFiles(files)
+			slf.upgrading = false
+			slf.mutex.Unlock()
+			return fmt.Errorf("%w: %w", ErrHotUpgrade, err)
+		}
+		items = append(items, fmt.Sprintf("%s=%d", key, 3+len(files)))
+		files = append(files, file)
+	}
+	slf.mutex.Unlock()
+
+	pid, err := slf.start(files, items, timeout)
+	closeFiles(files)
+	if err != nil {
+		slf.mutex.Lock()
+		slf.upgrading = false
+		slf.mutex.Unlock()
+		log.Error("Server", log.String("action", "hot-upgrade"), log.Err(err))
+		return err
+	}
+	log.Info("Server", log.String("action", "hot-upgrade"), log.Int("pid", pid), log.String("state", "handoff"))
+
+	slf.mutex.Lock()
+	for _, listener := range slf.listeners {
+		if listener != nil {
+			_ = listener.Close()
+		}
+	}
+	slf.mutex.Unlock()
+	for _, srv := range servers {
+		srv.Shutdown()
+	}
+	return nil
+}
+
+// start 启动子进程并等待其就绪
+func (slf *hotUpgrader) start(files []*os.File, items []string, timeout time.Duration) (int, error) {
+	executable, err := os.Executable()
+	if err != nil {
+		return 0, fmt.Errorf("%w: %w", ErrHotUpgrade, err)
+	}
+	reader, writer, err := os.Pipe()
+	if err != nil {
+		return 0, fmt.Errorf("%w: %w", ErrHotUpgrade, err)
+	}
+	defer func() {
+		_ = reader.Close()
+	}()
+
+	cmd := exec.Command(executable, os.Args[1:]...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	cmd.ExtraFiles = append(files, writer)
+	cmd.Env = append(os.Environ(),
+		fmt.Sprintf("%s=%s", hotUpgradeEnvListeners, strings.Join(items, ",")),
+		fmt.Sprintf("%s=%d", hotUpgradeEnvReady, 3+len(files)),
+	)
+	err = cmd.Start()
+	_ = writer.Close()
+	if err != nil {
+		return 0, fmt.Errorf("%w: %w", ErrHotUpgrade, err)
+	}
+	go func() {
+		_ = cmd.Wait()
+	}()
+
+	var ready = make(chan error, 1)
+	go func() {
+		var buf = make([]byte, 1)
+		_, err := reader.Read(buf)
+		ready <- err
+	}()
+	select {
+	case err = <-ready:
+		if err != nil {
+			_ = cmd.Process.Kill()
+			return 0, fmt.Errorf("%w: child exited before ready: %w", ErrHotUpgrade, err)
+		}
+	case <-time.After(timeout):
+		_ = cmd.Process.Kill()
+		return 0, fmt.Errorf("%w: child not ready after %s", ErrHotUpgrade, timeout)
+	}
+	return cmd.Process.Pid, nil
+}
+
+func closeFiles(files []*os.File) {
+	for _, file := range files {
+		_ = file.Close()
+	}
+}
+
+// upgradeListener 可重复关闭的侦听器
+//   - 热更新时侦听器将先于服务器关闭，避免 http.Server 再次关闭时产生错误
+type upgradeListener struct {
+	net.Listener
+	once sync.Once
+	err  error
+}
+
+func (slf *upgradeListener) Close() error {
+	slf.once.Do(func() {
+		slf.err = slf.Listener.Close()
+	})
+	return slf.err
+}
+
+// HotUpgrade 对当前进程中所有通过 WithHotUpgrade 创建的服务器进行热更新
+//   - 将以相同的参数启动当前可执行文件，并将所有侦听器传递给子进程
+//   - 子进程中的服务器完成侦听后，当前进程将停止接受新的连接，并通过 Server.Shutdown 排空已有连接后关闭
+//   - 当子进程启动失败或超时未就绪时将返回错误，当前进程将继续正常运行
+//   - 在 Linux 等类 Unix 系统中，可以通过向进程发送 SIGUSR2 信号触发
+func HotUpgrade() error {
+	return upgrader.upgrade()
+}
+
+// listen 侦听服务器地址，开启热更新时将优先接管父进程传递的侦听器
+func (slf *Server) listen(network, addr string) (net.Listener, error) {
+	if !slf.hotUpgrade {
+		return net.Listen(network, addr)
+	}
+	return upgrader.listen(slf, network, addr)
+}
+
+// hotUpgradeDone 标记服务器已完成侦听
+func (slf *Server) hotUpgradeDone(network, addr string) {
+	if slf.hotUpgrade {
+		upgrader.done(network, addr)
+	}
+}
+
+// isListenerClosed 侦听器是否因热更新或关闭服务器而被关闭
+func isListenerClosed(err error) bool {
+	return errors.Is(err, net.ErrClosed) || errors.Is(err, http.ErrServerClosed)
+}
@@ -0,0 +1,103 @@
+//go:build linux
+
+package server_test
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/kercylan98/minotaur/server"
+	. "github.com/smartystreets/goconvey/convey"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"syscall"
+	"testing"
+	"time"
+)
+
+const hotUpgradeTestAddr = "MINOTAUR_HOT_UPGRADE_TEST_ADDR"
+
+func TestHotUpgrade_Shutdown(t *testing.T) {
+	Convey("TestHotUpgrade_Shutdown", t, func() {
+		srv := server.New(server.NetworkHttp, server.WithHotUpgrade(time.Second))
+		stop := runServer(srv, freeAddr())
+		stop()
+		So(server.HotUpgrade(), ShouldEqual, server.ErrHotUpgradeNotEnabled)
+	})
+}
+
+func TestHotUpgrade(t *testing.T) {
+	var addr, child = os.LookupEnv(hotUpgradeTestAddr)
+	srv := server.New(server.NetworkHttp, server.WithHotUpgrade(10*time.Second))
+	srv.HttpRouter().GET("/pid", func(ctx *gin.Context) {
+		ctx.String(http.StatusOK, strconv.Itoa(os.Getpid()))
+	})
+	if child {
+		// 子进程将持续运行，直到父进程通过 SIGTERM 终止
+		_ = srv.Run(addr)
+		return
+	}
+
+	Convey("TestHotUpgrade", t, func() {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		So(err, ShouldBeNil)
+		addr = listener.Addr().String()
+		_ = listener.Close()
+
+		var started, stopped = make(chan struct{}), make(chan struct{})
+		srv.RegStartFinishEvent(func(srv *server.Server) {
+			close(started)
+		})
+		go func() {
+			_ = srv.Run(addr)
+			close(stopped)
+		}()
+		<-started
+
+		pid, err := getHotUpgradePid(addr)
+		So(err, ShouldBeNil)
+		So(pid, ShouldEqual, os.Getpid())
+
+		t.Setenv(hotUpgradeTestAddr, addr)
+		var args = os.Args
+		os.Args = []string{args[0], "-test.run=^TestHotUpgrade$"}
+		err = server.HotUpgrade()
+		os.Args = args
+		So(err, ShouldBeNil)
+
+		select {
+		case <-stopped:
+		case <-time.After(5 * time.Second):
+			t.Fatal("old server was not shutdown")
+		}
+
+		pid, err = getHotUpgradePid(addr)
+		So(err, ShouldBeNil)
+		So(pid, ShouldNotEqual, os.Getpid())
+
+		So(syscall.Kill(pid, syscall.SIGTERM), ShouldBeNil)
+		var deadline = time.Now().Add(5 * time.Second)
+		for time.Now().Before(deadline) {
+			if _, err = getHotUpgradePid(addr); err != nil {
+				break
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		So(err, ShouldNotBeNil)
+	})
+}
+
+func getHotUpgradePid(addr string) (int, error) {
+	var client = &http.Client{Transport: &http.Transport{DisableKeepAlives: true}, Timeout: time.Second}
+	resp, err := client.Get("http://" + addr + "/pid")
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(string(data))
+}
@@ -0,0 +1,22 @@
+//go:build !windows
+
+package server
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// watch 监听 SIGUSR2 信号以触发热更新
+func (slf *hotUpgrader) watch() {
+	slf.watchOnce.Do(func() {
+		var ch = make(chan os.Signal, 1)
+		signal.Notify(ch, syscall.SIGUSR2)
+		go func() {
+			for range ch {
+				_ = slf.upgrade()
+			}
+		}()
+	})
+}
@@ -0,0 +1,6 @@
+//go:build windows
+
+package server
+
+// watch Windows 下不支持通过信号触发热更新，仅可通过 HotUpgrade 触发
+func (slf *hotUpgrader) watch() {}
@@ -0,0 +1,70 @@
+package server
+
+import (
+	"sync"
+)
+
+// NewVirtualConn 创建一个虚拟连接
+//   - 虚拟连接不持有任何传输层，适用于由网关等其他连接代理的客户端
+//   - id 为连接 ID，ip 为客户端 IP
+//   - 通过 Write 写入的数据包将在写循环中交由 writer 发送，writer 返回错误时将与普通连接一样视为写入失败
+//   - 通过 Close 关闭连接时将调用 closer，可用于通知代理方断开客户端，为 nil 时不进行任何处理
+//   - 代理转发的数据包需要通过 Conn.ReceiveVirtualPacket 交由服务器处理
+//   - 虚拟连接的打开及关闭需要由调用方通过 Server.OnConnectionOpenedEvent 和 Server.OnConnectionClosedEvent 触发
+func NewVirtualConn(server *Server, id, ip string, writer func(packet Packet) error, closer func()) *Conn {
+	c := &Conn{
+		server:        server,
+		remoteAddr:    virtualAddr(id),
+		ip:            ip,
+		virtual:       writer,
+		virtualCloser: closer,
+		limiter:       newConnRateLimiter(server),
+		rpc:           newConnRPC(server),
+		data:          map[any]any{},
+	}
+	c.touch()
+	var wait = new(sync.WaitGroup)
+	wait.Add(1)
+	go c.writeLoop(wait)
+	wait.Wait()
+	return c
+}
+
+// virtualAddr 虚拟连接的地址
+type virtualAddr string
+
+func (slf virtualAddr) Network() string {
+	return "virtual"
+}
+
+func (slf virtualAddr) String() string {
+	return string(slf)
+}
+
+// IsVirtual 是否是虚拟连接
+func (slf *Conn) IsVirtual() bool {
+	return slf.virtual != nil
+}
+
+// ReceiveVirtualPacket 虚拟连接接收到代理转发的数据包
+//   - 数据包将与普通连接一样经过限流、预处理等流程后交由 ConnectionReceivePacketEvent 处理
+//   - 非虚拟连接调用该函数将不会产生任何效果
+func (slf *Conn) ReceiveVirtualPacket(packet Packet) {
+	if slf.virtual == nil {
+		return
+	}
+	slf.push(packet.Data, packet.WebsocketType)
+}
+
+// UseVirtualConnEvents 设置服务器仅针对虚拟连接触发连接相关的事件
+//   - 适用于所有客户端均通过代理接入的服务器，例如网关后的端点服务器，此时非虚拟连接仅作为与代理之间的链路
+//   - 非虚拟连接将不会触发 ConnectionOpenedEvent、ConnectionClosedEvent，也不会被计入在线连接，其关闭将交由 closed 处理
+//   - 非虚拟连接接收到的数据包仍将经过 ConnectionPacketPreprocessEvent，需要在其中进行拦截
+func (slf *Server) UseVirtualConnEvents(closed func(conn *Conn, err any)) {
+	slf.linkClosed = closed
+}
+
+// isLink 是否是仅作为代理链路的连接
+func (slf *Server) isLink(conn *Conn) bool {
+	return slf.linkClosed != nil && conn.virtual == nil
+}
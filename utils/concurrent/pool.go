@@ -51,8 +51,15 @@ func (slf *Pool[T]) IsClose() bool {
 	return slf.generator == nil
 }
 
+// Release 将对象放回缓冲区，缓冲池关闭后将直接放弃该对象
 func (slf *Pool[T]) Release(data T) {
-	slf.releaser(data)
+	slf.mutex.Lock()
+	releaser := slf.releaser
+	slf.mutex.Unlock()
+	if releaser == nil {
+		return
+	}
+	releaser(data)
 	slf.put(data)
 }
 
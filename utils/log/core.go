@@ -3,3 +3,23 @@ package log
 import "go.uber.org/zap/zapcore"
 
 type Core = zapcore.Core
+
+// levelCore 受全局日志级别控制的 Core
+type levelCore struct {
+	Core
+}
+
+func (slf *levelCore) Enabled(lvl Level) bool {
+	return level.Enabled(lvl) && slf.Core.Enabled(lvl)
+}
+
+func (slf *levelCore) With(fields []Field) Core {
+	return &levelCore{Core: slf.Core.With(fields)}
+}
+
+func (slf *levelCore) Check(entry zapcore.Entry, checked *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if !level.Enabled(entry.Level) {
+		return checked
+	}
+	return slf.Core.Check(entry, checked)
+}
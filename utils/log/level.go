@@ -27,6 +27,7 @@ const (
 )
 
 var (
+	level                 = zap.NewAtomicLevelAt(DebugLevel)
 	levels                = []Level{DebugLevel, InfoLevel, WarnLevel, ErrorLevel, DPanicLevel, PanicLevel, FatalLevel}
 	defaultLevelPartition = map[Level]func() LevelEnablerFunc{
 		DebugLevel:  DebugLevelPartition,
@@ -39,6 +40,17 @@ var (
 	}
 )
 
+// SetLevel 设置日志的最低输出级别，低于该级别的日志将不会被输出
+//   - 该级别对所有通过 NewLog 创建的日志记录器生效，可在运行时动态调整
+func SetLevel(lvl Level) {
+	level.SetLevel(lvl)
+}
+
+// GetLevel 获取日志的最低输出级别
+func GetLevel() Level {
+	return level.Level()
+}
+
 // Levels 返回所有日志级别
 func Levels() []Level {
 	return levels
@@ -23,6 +23,11 @@ func (slf *Time) SetOffset(offset time.Duration) {
 	slf.offset = offset
 }
 
+// GetOffset 获取时间偏移
+func (slf *Time) GetOffset() time.Duration {
+	return slf.offset
+}
+
 // Now 获取当前时间偏移后的时间
 func (slf *Time) Now() time.Time {
 	return time.Now().Add(slf.offset)
@@ -72,6 +72,19 @@ func (slf *Ticker) GetSchedulers() []string {
 	return names
 }
 
+// Trigger 立即执行特定名称的调度器一次，返回调度器是否存在
+//   - 该函数不会影响调度器原有的调度计划，通常用于消息回放等需要手动驱动定时器的场景
+func (slf *Ticker) Trigger(name string) bool {
+	slf.lock.RLock()
+	s, ok := slf.timers[name]
+	slf.lock.RUnlock()
+	if !ok {
+		return false
+	}
+	s.Caller()
+	return true
+}
+
 // After 设置一个在特定时间后运行一次的调度器
 func (slf *Ticker) After(name string, after time.Duration, handleFunc interface{}, args ...interface{}) {
 	slf.Loop(name, after, timingWheelTick, 1, handleFunc, args...)